@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/omgitsads/mcp-go-session-example/middleware"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+)
+
+// startAdminServer serves the session admin API on addr in the background.
+// Every request must carry token as a bearer token.
+func startAdminServer(group *serverGroup, addr, token string, store storage.SessionStore) {
+	svr := &http.Server{
+		Addr:    addr,
+		Handler: middleware.BearerToken(token, adminHandler(store)),
+	}
+
+	log.Printf("Serving admin API on %s/admin/sessions", addr)
+	group.start("Admin", svr)
+}
+
+// adminSessionList is the response body for GET /admin/sessions
+type adminSessionList struct {
+	Sessions []string `json:"sessions"`
+}
+
+// adminReadOnly is the request and response body for /admin/read-only
+type adminReadOnly struct {
+	ReadOnly bool `json:"readOnly"`
+}
+
+// adminHandler routes the session admin API. Listing and inspecting sessions
+// need a store that implements storage.SessionLister and
+// storage.SessionInspector, and switching read-only mode one that implements
+// storage.ReadOnlySwitch; other stores get 501 Not Implemented.
+func adminHandler(store storage.SessionStore) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /admin/sessions", func(w http.ResponseWriter, r *http.Request) {
+		lister, ok := store.(storage.SessionLister)
+		if !ok {
+			writeAdminError(w, http.StatusNotImplemented, errors.New("session store doesn't support listing sessions"))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		sessionIDs, err := lister.ListSessions(ctx)
+		if err != nil {
+			writeAdminError(w, storage.HTTPStatus(err), err)
+			return
+		}
+
+		if sessionIDs == nil {
+			sessionIDs = []string{}
+		}
+		sort.Strings(sessionIDs)
+		writeAdminJSON(w, http.StatusOK, adminSessionList{Sessions: sessionIDs})
+	})
+
+	mux.HandleFunc("GET /admin/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		data, ok := inspectSession(w, r, store)
+		if !ok {
+			return
+		}
+
+		var session storage.SessionData
+		if err := json.Unmarshal(data, &session); err != nil {
+			writeAdminError(w, http.StatusInternalServerError, fmt.Errorf("failed to decode session data: %w", err))
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, session)
+	})
+
+	mux.HandleFunc("DELETE /admin/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// Check the session exists first, since deleting a missing key isn't
+		// an error for the backends
+		if _, ok := inspectSession(w, r, store); !ok {
+			return
+		}
+
+		if err := store.Delete(r.PathValue("id")); err != nil {
+			writeAdminError(w, storage.HTTPStatus(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /admin/read-only", func(w http.ResponseWriter, r *http.Request) {
+		switcher, ok := store.(storage.ReadOnlySwitch)
+		if !ok {
+			writeAdminError(w, http.StatusNotImplemented, errors.New("session store doesn't support read-only mode"))
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, adminReadOnly{ReadOnly: switcher.ReadOnly()})
+	})
+
+	mux.HandleFunc("PUT /admin/read-only", func(w http.ResponseWriter, r *http.Request) {
+		switcher, ok := store.(storage.ReadOnlySwitch)
+		if !ok {
+			writeAdminError(w, http.StatusNotImplemented, errors.New("session store doesn't support read-only mode"))
+			return
+		}
+
+		var body adminReadOnly
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&body); err != nil {
+			writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+		switcher.SetReadOnly(body.ReadOnly)
+		writeAdminJSON(w, http.StatusOK, adminReadOnly{ReadOnly: switcher.ReadOnly()})
+	})
+
+	return mux
+}
+
+// inspectSession reads the stored record for the session named in the request
+// path. It writes an error response and returns false if that fails.
+func inspectSession(w http.ResponseWriter, r *http.Request, store storage.SessionStore) ([]byte, bool) {
+	inspector, ok := store.(storage.SessionInspector)
+	if !ok {
+		writeAdminError(w, http.StatusNotImplemented, errors.New("session store doesn't support inspecting sessions"))
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	data, err := inspector.Inspect(ctx, r.PathValue("id"))
+	if err != nil {
+		writeAdminError(w, storage.HTTPStatus(err), err)
+		return nil, false
+	}
+	return data, true
+}
+
+// writeAdminJSON writes v as a JSON response with the given status
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write admin response: %v", err)
+	}
+}
+
+// writeAdminError writes err as a JSON error response with the given status
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	writeAdminJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	mcpserver "github.com/omgitsads/mcp-go-session-example/mcp"
+	"github.com/omgitsads/mcp-go-session-example/middleware"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+)
+
+const testAdminToken = "admin-secret"
+
+// newTestAdminServer serves the admin API for a Redis store backed by
+// miniredis, holding the given sessions
+func newTestAdminServer(t *testing.T, sessionIDs ...string) (*httptest.Server, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	store, err := storage.NewRedisSessionStore(storage.RedisSessionStoreConfig{
+		Addr:   mr.Addr(),
+		Server: mcpserver.NewSessionServer(nil).MCPServer,
+	})
+	if err != nil {
+		t.Fatalf("NewRedisSessionStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	for _, sessionID := range sessionIDs {
+		if err := store.Set(sessionID, mcp.NewStreamableServerTransport(sessionID, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := httptest.NewServer(middleware.BearerToken(testAdminToken, adminHandler(store)))
+	t.Cleanup(server.Close)
+	return server, mr
+}
+
+// adminRequest sends an admin API request with token, decoding a JSON
+// response body into v if it's not nil
+func adminRequest(t *testing.T, server *httptest.Server, method, path, token string, v any) int {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), method, server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s returned an invalid body: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAdminAPIRequiresToken(t *testing.T) {
+	server, _ := newTestAdminServer(t, "session-1")
+	for _, token := range []string{"", "wrong-secret"} {
+		if status := adminRequest(t, server, http.MethodGet, "/admin/sessions", token, nil); status != http.StatusUnauthorized {
+			t.Errorf("GET /admin/sessions with token %q = %d, want 401", token, status)
+		}
+		if status := adminRequest(t, server, http.MethodDelete, "/admin/sessions/session-1", token, nil); status != http.StatusUnauthorized {
+			t.Errorf("DELETE /admin/sessions/session-1 with token %q = %d, want 401", token, status)
+		}
+	}
+
+	// Nothing was deleted by the rejected requests
+	var list adminSessionList
+	adminRequest(t, server, http.MethodGet, "/admin/sessions", testAdminToken, &list)
+	if !slices.Equal(list.Sessions, []string{"session-1"}) {
+		t.Errorf("sessions = %v, want [session-1]", list.Sessions)
+	}
+}
+
+func TestAdminAPISessions(t *testing.T) {
+	server, mr := newTestAdminServer(t, "session-2", "session-1")
+
+	var list adminSessionList
+	if status := adminRequest(t, server, http.MethodGet, "/admin/sessions", testAdminToken, &list); status != http.StatusOK {
+		t.Fatalf("GET /admin/sessions = %d, want 200", status)
+	}
+	if !slices.Equal(list.Sessions, []string{"session-1", "session-2"}) {
+		t.Errorf("sessions = %v, want them sorted", list.Sessions)
+	}
+
+	var session storage.SessionData
+	if status := adminRequest(t, server, http.MethodGet, "/admin/sessions/session-1", testAdminToken, &session); status != http.StatusOK {
+		t.Fatalf("GET /admin/sessions/session-1 = %d, want 200", status)
+	}
+	if session.SessionID != "session-1" || session.UpdatedAt.IsZero() {
+		t.Errorf("session = %+v, want session-1's record", session)
+	}
+	if status := adminRequest(t, server, http.MethodGet, "/admin/sessions/missing", testAdminToken, nil); status != http.StatusNotFound {
+		t.Errorf("GET /admin/sessions/missing = %d, want 404", status)
+	}
+	if status := adminRequest(t, server, http.MethodGet, "/admin/sessions/bad%20id", testAdminToken, nil); status != http.StatusBadRequest {
+		t.Errorf("GET /admin/sessions/bad%%20id = %d, want 400", status)
+	}
+
+	if status := adminRequest(t, server, http.MethodDelete, "/admin/sessions/session-1", testAdminToken, nil); status != http.StatusNoContent {
+		t.Fatalf("DELETE /admin/sessions/session-1 = %d, want 204", status)
+	}
+	if mr.Exists("mcp:session:session-1") {
+		t.Error("evicted session is still stored")
+	}
+	if status := adminRequest(t, server, http.MethodDelete, "/admin/sessions/session-1", testAdminToken, nil); status != http.StatusNotFound {
+		t.Errorf("second DELETE /admin/sessions/session-1 = %d, want 404", status)
+	}
+	adminRequest(t, server, http.MethodGet, "/admin/sessions", testAdminToken, &list)
+	if !slices.Equal(list.Sessions, []string{"session-2"}) {
+		t.Errorf("sessions after evicting session-1 = %v, want [session-2]", list.Sessions)
+	}
+}
+
+func TestAdminAPIReadOnly(t *testing.T) {
+	server, _ := newTestAdminServer(t, "session-1")
+
+	var mode adminReadOnly
+	if status := adminRequest(t, server, http.MethodGet, "/admin/read-only", testAdminToken, &mode); status != http.StatusOK || mode.ReadOnly {
+		t.Fatalf("GET /admin/read-only = %d, %+v, want 200 and writable", status, mode)
+	}
+
+	setReadOnly := func(body string) int {
+		t.Helper()
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPut, server.URL+"/admin/read-only", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := setReadOnly(`{"readOnly":true}`); status != http.StatusOK {
+		t.Fatalf("PUT /admin/read-only = %d, want 200", status)
+	}
+	adminRequest(t, server, http.MethodGet, "/admin/read-only", testAdminToken, &mode)
+	if !mode.ReadOnly {
+		t.Error("store isn't read-only after PUT /admin/read-only")
+	}
+	// Evicting is a write, so it's refused
+	if status := adminRequest(t, server, http.MethodDelete, "/admin/sessions/session-1", testAdminToken, nil); status == http.StatusNoContent {
+		t.Error("DELETE /admin/sessions/session-1 succeeded in read-only mode")
+	}
+	if status := setReadOnly(`not json`); status != http.StatusBadRequest {
+		t.Errorf("PUT /admin/read-only with an invalid body = %d, want 400", status)
+	}
+	if status := setReadOnly(`{"readOnly":false}`); status != http.StatusOK {
+		t.Fatalf("PUT /admin/read-only = %d, want 200", status)
+	}
+	if status := adminRequest(t, server, http.MethodDelete, "/admin/sessions/session-1", testAdminToken, nil); status != http.StatusNoContent {
+		t.Errorf("DELETE /admin/sessions/session-1 after leaving read-only mode = %d, want 204", status)
+	}
+}
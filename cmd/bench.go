@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	mcpserver "github.com/omgitsads/mcp-go-session-example/mcp"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+	"github.com/spf13/cobra"
+)
+
+// benchPrefix is added to the store's key prefix for the sessions a benchmark
+// writes. Session IDs can't contain ':', so listings of the real sessions
+// never include them.
+const benchPrefix = "bench:"
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure session store latency and throughput",
+	Long: `Run stores, loads and deletes of synthetic sessions against the session
+store and print their latency percentiles and the overall throughput, to
+check the store's capacity before going live. The session store is
+configured the same way as for the server command and is used through the
+same code path, with the local cache disabled so every load reads the
+backend. Each session is stored, loaded and then deleted, so --ops is split
+evenly between the three. Sessions are written under a "bench:" addition
+to the key prefix, and any that are left when the run ends, e.g. because it
+was interrupted, are deleted.`,
+	Args: cobra.NoArgs,
+	Run:  runBench,
+}
+
+func init() {
+	benchCmd.Flags().Int("ops", 10000, "Total store operations to run, split evenly between stores, loads and deletes")
+	benchCmd.Flags().Int("concurrency", 50, "Operations run at once")
+	addStoreFlags(benchCmd)
+}
+
+func runBench(cmd *cobra.Command, args []string) {
+	cfg, err := parseConfig(cmd)
+	if err != nil {
+		log.Fatalf("Failed to parse configuration: %v", err)
+	}
+
+	ops, _ := cmd.Flags().GetInt("ops")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	if ops < 3 {
+		log.Fatalf("--ops must be at least 3, to store, load and delete one session, got %d", ops)
+	}
+	if concurrency < 1 {
+		log.Fatalf("--concurrency must be at least 1, got %d", concurrency)
+	}
+
+	// Every load should reach the backend, and the sessions are kept apart
+	// from real ones
+	cfg.DisableLocalCache = true
+	cfg.RedisPrefix += benchPrefix
+	cfg.MemcachedPrefix += benchPrefix
+	cfg.EtcdPrefix += benchPrefix
+	cfg.ConsulPrefix += benchPrefix
+
+	// Loading a session connects it to a server
+	sessionServer := mcpserver.NewSessionServer(nil)
+
+	sessionStore, err := newSessionStore(cfg, sessionServer.MCPServer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sessionStore.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sessions := ops / 3
+	fmt.Printf("Running %d operations on %d sessions against the %s store, %d at a time\n", sessions*3, sessions, cfg.Store, concurrency)
+	result := benchStore(ctx, sessionStore, sessions, concurrency)
+	printBenchResult(result)
+}
+
+// benchOp is a kind of operation the benchmark measures
+type benchOp string
+
+const (
+	benchStoreOp  benchOp = "store"
+	benchLoadOp   benchOp = "load"
+	benchDeleteOp benchOp = "delete"
+)
+
+// benchOps lists the measured operations in the order they're printed
+var benchOps = []benchOp{benchStoreOp, benchLoadOp, benchDeleteOp}
+
+// benchResult holds what a benchmark measured
+type benchResult struct {
+	latencies map[benchOp][]time.Duration // Latency of every operation that succeeded
+	errors    map[benchOp]int             // Operations that failed
+	elapsed   time.Duration               // Wall time for every operation to finish
+	leftover  int                         // Sessions that couldn't be deleted afterwards either
+}
+
+// benchStore stores, loads and deletes synthetic sessions, with
+// concurrency operations in flight at once, until they've all been handled or
+// ctx is done. Sessions left behind by failed or skipped deletes are deleted
+// once the run ends.
+func benchStore(ctx context.Context, store storage.SessionStore, sessions, concurrency int) *benchResult {
+	result := &benchResult{
+		latencies: make(map[benchOp][]time.Duration),
+		errors:    make(map[benchOp]int),
+	}
+	var (
+		mu        sync.Mutex
+		remaining []string // Stored sessions that weren't deleted
+	)
+
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := 0; i < sessions; i++ {
+			select {
+			case next <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			latencies := make(map[benchOp][]time.Duration)
+			failures := make(map[benchOp]int)
+			var left []string
+
+			for range next {
+				sessionID, ok := benchSession(ctx, store, latencies, failures)
+				if !ok && sessionID != "" {
+					left = append(left, sessionID)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, op := range benchOps {
+				result.latencies[op] = append(result.latencies[op], latencies[op]...)
+				result.errors[op] += failures[op]
+			}
+			remaining = append(remaining, left...)
+		}()
+	}
+	wg.Wait()
+	result.elapsed = time.Since(start)
+
+	for _, sessionID := range remaining {
+		if err := store.Delete(sessionID); err != nil {
+			log.Printf("Failed to delete benchmark session %s: %v", sessionID, err)
+			result.leftover++
+		}
+	}
+	return result
+}
+
+// benchSession stores, loads and deletes one synthetic session, recording
+// each operation's latency or failure. It returns the session's ID if it was
+// stored, and whether it was deleted again.
+func benchSession(ctx context.Context, store storage.SessionStore, latencies map[benchOp][]time.Duration, failures map[benchOp]int) (string, bool) {
+	sessionID := "bench-" + rand.Text()
+	transport := mcp.NewStreamableServerTransport(sessionID, nil)
+
+	measure := func(op benchOp, fn func() error) bool {
+		start := time.Now()
+		if err := fn(); err != nil {
+			failures[op]++
+			return false
+		}
+		latencies[op] = append(latencies[op], time.Since(start))
+		return true
+	}
+
+	if !measure(benchStoreOp, func() error { return store.Set(sessionID, transport) }) {
+		return "", false
+	}
+	if ctx.Err() != nil {
+		return sessionID, false
+	}
+	measure(benchLoadOp, func() error {
+		session, err := store.Get(ctx, sessionID)
+		if err == nil && session == nil {
+			err = storage.ErrNotFound
+		}
+		return err
+	})
+	return sessionID, measure(benchDeleteOp, func() error { return store.Delete(sessionID) })
+}
+
+// printBenchResult prints each operation's latency percentiles and the
+// overall throughput
+func printBenchResult(result *benchResult) {
+	fmt.Printf("%-8s %8s %8s %10s %10s %10s\n", "op", "count", "errors", "p50", "p95", "p99")
+	total := 0
+	for _, op := range benchOps {
+		latencies := result.latencies[op]
+		slices.Sort(latencies)
+		total += len(latencies) + result.errors[op]
+		fmt.Printf("%-8s %8d %8d %10s %10s %10s\n", op, len(latencies), result.errors[op],
+			formatLatency(percentile(latencies, 0.50)),
+			formatLatency(percentile(latencies, 0.95)),
+			formatLatency(percentile(latencies, 0.99)))
+	}
+
+	throughput := 0.0
+	if result.elapsed > 0 {
+		throughput = float64(total) / result.elapsed.Seconds()
+	}
+	fmt.Printf("%d operations in %s, %.0f ops/s\n", total, result.elapsed.Round(time.Millisecond), throughput)
+	if result.leftover > 0 {
+		fmt.Printf("%d benchmark sessions couldn't be deleted and will be left until they expire\n", result.leftover)
+	}
+}
+
+// percentile returns the latency at fraction p of sorted latencies, using the
+// nearest rank, or 0 if there are none
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+// formatLatency rounds a latency for display to the microsecond, unless it's
+// shorter than that, printing "-" for none
+func formatLatency(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	if d < time.Microsecond {
+		return d.String()
+	}
+	return d.Round(time.Microsecond).String()
+}
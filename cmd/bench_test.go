@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	mcpserver "github.com/omgitsads/mcp-go-session-example/mcp"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+)
+
+// newBenchStore returns a Redis store configured the way runBench configures
+// one, backed by miniredis
+func newBenchStore(t *testing.T) (storage.SessionStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	cfg, err := parseConfig(newTestCommand(t, map[string]string{"redis-addr": mr.Addr()}))
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+	cfg.DisableLocalCache = true
+	cfg.RedisPrefix += benchPrefix
+
+	store, err := newSessionStore(cfg, mcpserver.NewSessionServer(nil).MCPServer)
+	if err != nil {
+		t.Fatalf("newSessionStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, mr
+}
+
+func TestBenchStore(t *testing.T) {
+	store, mr := newBenchStore(t)
+
+	result := benchStore(t.Context(), store, 20, 4)
+	for _, op := range benchOps {
+		if got := len(result.latencies[op]); got != 20 {
+			t.Errorf("%s latencies recorded = %d, want 20", op, got)
+		}
+		if result.errors[op] != 0 {
+			t.Errorf("%s errors = %d, want 0", op, result.errors[op])
+		}
+	}
+	if result.elapsed <= 0 || result.leftover != 0 {
+		t.Errorf("elapsed = %s, leftover = %d", result.elapsed, result.leftover)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("benchmark left keys %v", keys)
+	}
+
+	out := captureStdout(t, func() { printBenchResult(result) })
+	if !strings.Contains(out, "60 operations in") {
+		t.Errorf("printBenchResult() printed %q, want the operation total", out)
+	}
+	for _, op := range benchOps {
+		if !strings.Contains(out, string(op)+" ") {
+			t.Errorf("printBenchResult() printed %q, want a %s row", out, op)
+		}
+	}
+}
+
+func TestBenchStoreUnavailable(t *testing.T) {
+	store, mr := newBenchStore(t)
+	mr.Close()
+
+	result := benchStore(t.Context(), store, 5, 2)
+	if result.errors[benchStoreOp] != 5 {
+		t.Errorf("store errors = %d, want 5", result.errors[benchStoreOp])
+	}
+	for _, op := range benchOps {
+		if len(result.latencies[op]) != 0 {
+			t.Errorf("%s latencies recorded for failed operations", op)
+		}
+	}
+	// Sessions that were never stored aren't left over
+	if result.leftover != 0 {
+		t.Errorf("leftover = %d, want 0", result.leftover)
+	}
+}
+
+func TestBenchStoreCancelled(t *testing.T) {
+	store, mr := newBenchStore(t)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	result := benchStore(ctx, store, 100, 4)
+	if got := len(result.latencies[benchStoreOp]); got == 100 {
+		t.Error("cancelled benchmark ran every session")
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("cancelled benchmark left keys %v", keys)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{name: "none", sorted: nil, p: 0.5, want: 0},
+		{name: "one", sorted: latencies[:1], p: 0.99, want: time.Millisecond},
+		{name: "p0", sorted: latencies, p: 0, want: time.Millisecond},
+		{name: "p50", sorted: latencies, p: 0.50, want: 50 * time.Millisecond},
+		{name: "p95", sorted: latencies, p: 0.95, want: 95 * time.Millisecond},
+		{name: "p99", sorted: latencies, p: 0.99, want: 99 * time.Millisecond},
+		{name: "p100", sorted: latencies, p: 1, want: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(%v) = %s, want %s", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatLatency(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "-"},
+		{d: 500 * time.Nanosecond, want: "500ns"},
+		{d: 1234567 * time.Nanosecond, want: "1.235ms"},
+		{d: 2 * time.Second, want: "2s"},
+	}
+	for _, tt := range tests {
+		if got := formatLatency(tt.d); got != tt.want {
+			t.Errorf("formatLatency(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omgitsads/mcp-go-session-example/storage"
+)
+
+func TestParseConfigRedisNoExpiry(t *testing.T) {
+	tests := []struct {
+		name        string
+		flags       map[string]string
+		wantTTL     time.Duration
+		wantSession time.Duration // TTL reported by sessionTTL, 0 for none
+	}{
+		{name: "default", wantTTL: time.Hour, wantSession: time.Hour},
+		{name: "ttl", flags: map[string]string{"redis-ttl": "5m"}, wantTTL: 5 * time.Minute, wantSession: 5 * time.Minute},
+		{name: "no expiry", flags: map[string]string{"redis-no-expiry": "true"}, wantTTL: storage.NoExpiry, wantSession: 0},
+		{name: "no expiry overrides ttl", flags: map[string]string{"redis-ttl": "5m", "redis-no-expiry": "true"}, wantTTL: storage.NoExpiry, wantSession: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig(newTestCommand(t, tt.flags))
+			if err != nil {
+				t.Fatalf("parseConfig() error = %v", err)
+			}
+			if cfg.RedisTTL != tt.wantTTL {
+				t.Errorf("RedisTTL = %s, want %s", cfg.RedisTTL, tt.wantTTL)
+			}
+			if ttl := sessionTTL(cfg); ttl != tt.wantSession {
+				t.Errorf("sessionTTL() = %s, want %s", ttl, tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestParseConfigRedisEnvDBs(t *testing.T) {
+	dbs := "staging=1,production=2"
+	tests := []struct {
+		name    string
+		flags   map[string]string
+		wantDB  int
+		wantErr string
+	}{
+		{name: "no mapping", flags: map[string]string{"redis-db": "4"}, wantDB: 4},
+		{name: "environment", flags: map[string]string{"redis-env-dbs": dbs, "environment": "production"}, wantDB: 2},
+		{name: "overrides redis-db", flags: map[string]string{"redis-env-dbs": dbs, "environment": "staging", "redis-db": "4"}, wantDB: 1},
+		{name: "missing environment", flags: map[string]string{"redis-env-dbs": dbs}, wantErr: "no environment was given"},
+		{name: "unmapped environment", flags: map[string]string{"redis-env-dbs": dbs, "environment": "dev"}, wantErr: `no Redis DB configured for environment "dev"`},
+		{name: "with URL", flags: map[string]string{"redis-env-dbs": dbs, "environment": "staging", "redis-url": "redis://localhost:6379/0"}, wantErr: "REDIS_ENV_DBS can't be combined with REDIS_URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MCP_ENVIRONMENT", "")
+			t.Setenv("REDIS_ENV_DBS", "")
+			cfg, err := parseConfig(newTestCommand(t, tt.flags))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error = %v", err)
+			}
+			if cfg.RedisDB != tt.wantDB {
+				t.Errorf("RedisDB = %d, want %d", cfg.RedisDB, tt.wantDB)
+			}
+		})
+	}
+}
+
+func TestParseConfigSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("file overrides inline", func(t *testing.T) {
+		t.Setenv("REDIS_PASSWORD", "inline-password")
+		t.Setenv("REDIS_PASSWORD_FILE", writeSecret("password", "file-password\r\n\n"))
+		cfg, err := parseConfig(newTestCommand(t, nil))
+		if err != nil {
+			t.Fatalf("parseConfig() error = %v", err)
+		}
+		if cfg.RedisPassword != "file-password" {
+			t.Errorf("RedisPassword = %q, want the file's contents without trailing newlines", cfg.RedisPassword)
+		}
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		cfg, err := parseConfig(newTestCommand(t, map[string]string{"redis-url-file": writeSecret("url", "redis://localhost:6379/1\n")}))
+		if err != nil {
+			t.Fatalf("parseConfig() error = %v", err)
+		}
+		if cfg.RedisURL != "redis://localhost:6379/1" {
+			t.Errorf("RedisURL = %q, want the file's contents", cfg.RedisURL)
+		}
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		t.Setenv("REDIS_PASSWORD_FILE", writeSecret("empty", "\n"))
+		if _, err := parseConfig(newTestCommand(t, nil)); err == nil || !strings.Contains(err.Error(), "REDIS_PASSWORD_FILE") {
+			t.Errorf("parseConfig() error = %v, want an error naming REDIS_PASSWORD_FILE", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Setenv("REDIS_URL_FILE", filepath.Join(dir, "missing"))
+		if _, err := parseConfig(newTestCommand(t, nil)); err == nil || !strings.Contains(err.Error(), "REDIS_URL_FILE") {
+			t.Errorf("parseConfig() error = %v, want an error naming REDIS_URL_FILE", err)
+		}
+	})
+}
+
+func TestParseConfigEnvPrefix(t *testing.T) {
+	t.Setenv("REDIS_ADDR", "unprefixed:6379")
+	t.Setenv("MCP_PORT", "9000")
+	t.Setenv("FOO_REDIS_ADDR", "prefixed:6379")
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("MCP_ENV_PREFIX", "FOO_")
+		cfg, err := parseConfig(newTestCommand(t, nil))
+		if err != nil {
+			t.Fatalf("parseConfig() error = %v", err)
+		}
+		if cfg.RedisAddr != "prefixed:6379" {
+			t.Errorf("RedisAddr = %q, want the prefixed variable's value", cfg.RedisAddr)
+		}
+		if cfg.Port == 9000 {
+			t.Error("Port was read from the unprefixed MCP_PORT")
+		}
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		t.Setenv("MCP_ENV_PREFIX", "BAR_")
+		cmd := newTestCommand(t, nil)
+		cmd.Flags().String("env-prefix", "", "")
+		if err := cmd.Flags().Set("env-prefix", "FOO_"); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := parseConfig(cmd)
+		if err != nil {
+			t.Fatalf("parseConfig() error = %v", err)
+		}
+		if cfg.RedisAddr != "prefixed:6379" {
+			t.Errorf("RedisAddr = %q, want --env-prefix to take precedence over MCP_ENV_PREFIX", cfg.RedisAddr)
+		}
+	})
+
+	t.Run("no prefix", func(t *testing.T) {
+		t.Setenv("MCP_ENV_PREFIX", "")
+		cfg, err := parseConfig(newTestCommand(t, nil))
+		if err != nil {
+			t.Fatalf("parseConfig() error = %v", err)
+		}
+		if cfg.RedisAddr != "unprefixed:6379" || cfg.Port != 9000 {
+			t.Errorf("RedisAddr, Port = %q, %d, want the unprefixed variables' values", cfg.RedisAddr, cfg.Port)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+)
+
+// watchStatsSignal logs a snapshot of the store's statistics each time the
+// process receives one of statsSignals (SIGUSR1 on Unix), until ctx is done.
+// It's a no-op on platforms without a suitable signal.
+func watchStatsSignal(ctx context.Context, store storage.SessionStore) {
+	if len(statsSignals) == 0 {
+		return
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, statsSignals...)
+	defer signal.Stop(sigChan)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigChan:
+			// Counting sessions can be slow on a large store, so don't hold
+			// up the next signal
+			go dumpStats(store)
+		}
+	}
+}
+
+// dumpStats logs the store's cache, session and connection pool statistics
+// along with the goroutine count. Statistics the store doesn't provide are
+// left out.
+func dumpStats(store storage.SessionStore) {
+	cached := 0
+	store.Range(func(string, *mcp.StreamableServerTransport) {
+		cached++
+	})
+	log.Printf("Stats: %d cached sessions, %d goroutines", cached, runtime.NumGoroutine())
+
+	if lister, ok := store.(storage.SessionLister); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		total, err := storage.CountSessions(ctx, lister)
+		cancel()
+		if err != nil {
+			log.Printf("Stats: failed to count sessions: %v", err)
+		} else {
+			log.Printf("Stats: %d sessions in the store", total)
+		}
+	}
+	if stats, ok := store.(storage.CacheStatsProvider); ok {
+		hits, misses := stats.CacheStats()
+		log.Printf("Stats: session cache %d hits, %d misses, %.2f hit ratio", hits, misses, stats.CacheHitRatio())
+	}
+	if pool, ok := store.(storage.PoolStatsProvider); ok {
+		stats := pool.PoolStats()
+		log.Printf("Stats: connection pool %d total, %d idle, %d stale, %d hits, %d misses, %d timeouts",
+			stats.TotalConns, stats.IdleConns, stats.StaleConns, stats.Hits, stats.Misses, stats.Timeouts)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// statsSignals is empty where there's no SIGUSR1, disabling the dump
+var statsSignals []os.Signal
+
+// reloadSignals is empty where there's no SIGHUP, so plugin tools are only
+// loaded at startup
+var reloadSignals []os.Signal
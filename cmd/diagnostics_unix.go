@@ -0,0 +1,14 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// statsSignals trigger a statistics dump
+var statsSignals = []os.Signal{syscall.SIGUSR1}
+
+// reloadSignals trigger a reload of the plugin tools
+var reloadSignals = []os.Signal{syscall.SIGHUP}
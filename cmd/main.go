@@ -1,5 +1,9 @@
 package main
 
+// version is set at build time with -ldflags "-X main.version=...", as the
+// Makefile does. It's empty for go run and plain go build.
+var version string
+
 func main() {
 	Execute()
 }
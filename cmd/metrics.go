@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/omgitsads/mcp-go-session-example/health"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// healthCheckTimeout bounds how long /healthz waits for its checks
+const healthCheckTimeout = 2 * time.Second
+
+// startMetricsServer serves Prometheus metrics, the readiness check and the
+// health report on addr in the background, as part of group
+func startMetricsServer(group *serverGroup, addr string, store storage.SessionStore, checks *health.Registry) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/readyz", readinessHandler(store))
+	mux.Handle("/healthz", health.Handler(checks, healthCheckTimeout))
+
+	svr := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	log.Printf("Serving metrics on %s/metrics", addr)
+	group.start("Metrics", svr)
+}
+
+// readinessHandler reports 200 when the session store is reachable and 503
+// otherwise. Stores that monitor their connection in the background answer
+// from that state; others are pinged on each request.
+func readinessHandler(store storage.SessionStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		if monitored, ok := store.(storage.ConnectionStateProvider); ok {
+			if !monitored.IsConnected() {
+				err = errors.New("session store disconnected")
+			}
+		} else {
+			ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+			defer cancel()
+			err = store.Health(ctx)
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// newHealthChecks creates the health registry served on /healthz. Stores that
+// register their own checks do so; any other store is checked with Health.
+func newHealthChecks(store storage.SessionStore) *health.Registry {
+	registry := health.NewRegistry()
+	if registrar, ok := store.(storage.HealthCheckRegistrar); ok {
+		registrar.RegisterHealthChecks(registry)
+	} else {
+		registry.Register("session_store", store.Health)
+	}
+	return registry
+}
+
+// registerStoreMetrics exposes the store's cache statistics, if it has any
+func registerStoreMetrics(store storage.SessionStore) {
+	stats, ok := store.(storage.CacheStatsProvider)
+	if !ok {
+		return
+	}
+
+	prometheus.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "mcp_session_cache_hits_total",
+			Help: "Session lookups served from the active sessions cache.",
+		}, func() float64 {
+			hits, _ := stats.CacheStats()
+			return float64(hits)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "mcp_session_cache_misses_total",
+			Help: "Session lookups that had to read from the session store backend.",
+		}, func() float64 {
+			_, misses := stats.CacheStats()
+			return float64(misses)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "mcp_session_cache_hit_ratio",
+			Help: "Fraction of session lookups served from the active sessions cache.",
+		}, stats.CacheHitRatio),
+	)
+}
+
+// logCacheStats periodically logs the store's cache statistics until ctx is done
+func logCacheStats(ctx context.Context, store storage.SessionStore, interval time.Duration) {
+	stats, ok := store.(storage.CacheStatsProvider)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			hits, misses := stats.CacheStats()
+			log.Printf("Session cache: %d hits, %d misses, %.2f hit ratio", hits, misses, stats.CacheHitRatio())
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	mcpserver "github.com/omgitsads/mcp-go-session-example/mcp"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+)
+
+// newTestMigrateStore returns a Redis store backed by miniredis, holding the
+// given sessions
+func newTestMigrateStore(t *testing.T, sessionIDs ...string) (storage.SessionStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	store, err := storage.NewRedisSessionStore(storage.RedisSessionStoreConfig{
+		Addr:   mr.Addr(),
+		Server: mcpserver.NewSessionServer(nil).MCPServer,
+	})
+	if err != nil {
+		t.Fatalf("NewRedisSessionStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	for _, sessionID := range sessionIDs {
+		if err := store.Set(sessionID, mcp.NewStreamableServerTransport(sessionID, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store, mr
+}
+
+// migrate runs migrateSessions, returning what it printed
+func migrate(t *testing.T, source, dest storage.SessionStore, conflict migrateConflictPolicy, deleteSource bool) string {
+	t.Helper()
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	var err error
+	out := captureStdout(t, func() {
+		err = migrateSessions(t.Context(), source, dest, conflict, deleteSource)
+	})
+	if err != nil {
+		t.Fatalf("migrateSessions() error = %v", err)
+	}
+	return out
+}
+
+func TestMigrateSessions(t *testing.T) {
+	source, sourceRedis := newTestMigrateStore(t, "session-1", "session-2")
+	dest, destRedis := newTestMigrateStore(t)
+
+	out := migrate(t, source, dest, conflictOverwrite, false)
+	if !strings.Contains(out, "Copied 2 new sessions, overwrote 0 and kept 0 existing") {
+		t.Errorf("migrateSessions() printed %q, want 2 sessions copied", out)
+	}
+	for _, key := range []string{"mcp:session:session-1", "mcp:session:session-2"} {
+		if !destRedis.Exists(key) {
+			t.Errorf("%s wasn't copied to the destination", key)
+		}
+		if !sourceRedis.Exists(key) {
+			t.Errorf("%s was deleted from the source without --delete-source", key)
+		}
+	}
+}
+
+func TestMigrateSessionsDeleteSource(t *testing.T) {
+	source, sourceRedis := newTestMigrateStore(t, "session-1")
+	dest, destRedis := newTestMigrateStore(t)
+
+	migrate(t, source, dest, conflictOverwrite, true)
+	if !destRedis.Exists("mcp:session:session-1") {
+		t.Error("session wasn't copied to the destination")
+	}
+	if sourceRedis.Exists("mcp:session:session-1") {
+		t.Error("session wasn't deleted from the source")
+	}
+}
+
+func TestMigrateSessionsConflict(t *testing.T) {
+	tests := []struct {
+		name       string
+		conflict   migrateConflictPolicy
+		destNewer  bool
+		wantCopied bool
+		wantCounts string
+	}{
+		{name: "skip", conflict: conflictSkip, wantCounts: "Copied 1 new sessions, overwrote 0 and kept 1 existing"},
+		{name: "overwrite", conflict: conflictOverwrite, wantCopied: true, wantCounts: "Copied 1 new sessions, overwrote 1 and kept 0 existing"},
+		{name: "newer source", conflict: conflictNewer, wantCopied: true, wantCounts: "Copied 1 new sessions, overwrote 1 and kept 0 existing"},
+		{name: "newer destination", conflict: conflictNewer, destNewer: true, wantCounts: "Copied 1 new sessions, overwrote 0 and kept 1 existing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Records are stamped with when they were written, so the newer
+			// copy is the one written second
+			var source, dest storage.SessionStore
+			var destRedis *miniredis.Miniredis
+			if tt.destNewer {
+				source, _ = newTestMigrateStore(t, "session-1", "session-2")
+				time.Sleep(time.Millisecond)
+				dest, destRedis = newTestMigrateStore(t, "session-1")
+			} else {
+				dest, destRedis = newTestMigrateStore(t, "session-1")
+				time.Sleep(time.Millisecond)
+				source, _ = newTestMigrateStore(t, "session-1", "session-2")
+			}
+			existing, _ := destRedis.Get("mcp:session:session-1")
+
+			out := migrate(t, source, dest, tt.conflict, false)
+			if !strings.Contains(out, tt.wantCounts) {
+				t.Errorf("migrateSessions() printed %q, want %q", out, tt.wantCounts)
+			}
+			if got, _ := destRedis.Get("mcp:session:session-1"); (got != existing) != tt.wantCopied {
+				t.Errorf("destination copy replaced = %v, want %v", got != existing, tt.wantCopied)
+			}
+			if !destRedis.Exists("mcp:session:session-2") {
+				t.Error("session the destination didn't have wasn't copied")
+			}
+		})
+	}
+}
+
+func TestMigrateSessionsConflictNeedsInspector(t *testing.T) {
+	source, _ := newTestMigrateStore(t, "session-1")
+	dest := nonInspectingStore{}
+	for _, conflict := range []migrateConflictPolicy{conflictSkip, conflictNewer} {
+		err := migrateSessions(t.Context(), source, dest, conflict, false)
+		if err == nil || !strings.Contains(err.Error(), "--conflict="+string(conflict)) {
+			t.Errorf("migrateSessions(%s) error = %v, want an error naming the policy", conflict, err)
+		}
+	}
+}
+
+// nonInspectingStore is a SessionStore that can't read stored sessions
+type nonInspectingStore struct {
+	storage.SessionStore
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+
+	mcpserver "github.com/omgitsads/mcp-go-session-example/mcp"
+)
+
+// loadPlugins loads the plugin tools in dir, logging the outcome
+func loadPlugins(sessionServer *mcpserver.SessionServer, dir string) {
+	loaded, err := sessionServer.LoadPlugins(dir)
+	if err != nil {
+		log.Printf("Failed to load plugin tools: %v", err)
+		return
+	}
+	log.Printf("Loaded %d plugin tools from %s", loaded, dir)
+}
+
+// watchReloadSignal reloads the plugin tools in dir each time the process
+// receives one of reloadSignals (SIGHUP on Unix), until ctx is done. It's a
+// no-op on platforms without a suitable signal.
+func watchReloadSignal(ctx context.Context, sessionServer *mcpserver.SessionServer, dir string) {
+	if len(reloadSignals) == 0 {
+		return
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, reloadSignals...)
+	defer signal.Stop(sigChan)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigChan:
+			log.Printf("Reloading plugin tools from %s", dir)
+			loadPlugins(sessionServer, dir)
+		}
+	}
+}
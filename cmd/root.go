@@ -22,5 +22,9 @@ func Execute() {
 }
 
 func init() {
+	rootCmd.PersistentFlags().String("env-prefix", "", "Prefix for configuration environment variables, e.g. FOO_ to read FOO_REDIS_ADDR (default from MCP_ENV_PREFIX env)")
+
 	rootCmd.AddCommand(serverCmd)
+	rootCmd.AddCommand(sessionsCmd)
+	rootCmd.AddCommand(benchCmd)
 }
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,33 +12,21 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/caarlos0/env/v10"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	mcpserver "github.com/omgitsads/mcp-go-session-example/mcp"
+	"github.com/omgitsads/mcp-go-session-example/middleware"
 	"github.com/omgitsads/mcp-go-session-example/storage"
 	"github.com/spf13/cobra"
 )
 
-// Config holds all configuration for the server
-type Config struct {
-	// HTTP Server configuration
-	Host string `env:"MCP_HOST" envDefault:"localhost"`
-	Port int    `env:"MCP_PORT" envDefault:"8080"`
-
-	// Redis configuration
-	RedisAddr     string        `env:"REDIS_ADDR"`
-	RedisPassword string        `env:"REDIS_PASSWORD"`
-	RedisDB       int           `env:"REDIS_DB" envDefault:"0"`
-	RedisPrefix   string        `env:"REDIS_PREFIX" envDefault:"mcp:session:"`
-	RedisTTL      time.Duration `env:"REDIS_TTL" envDefault:"1h"`
-}
-
 var serverCmd = &cobra.Command{
 	Use:   "server",
 	Short: "Start the MCP HTTP server with Redis session storage",
 	Long: `Start an HTTP server that implements the Model Context Protocol (MCP).
 The server uses Redis for session storage to support multi-instance deployments and session persistence.
-Redis connection is required - configure via REDIS_ADDR environment variable or --redis-addr flag.`,
+Redis connection is required - configure via REDIS_ADDR environment variable or --redis-addr flag.
+Memcached can be used instead with --store=memcached and --memcached-addrs.
+Use --check to validate the configuration and store connectivity without serving traffic.`,
 	Run: runServer,
 }
 
@@ -44,46 +34,62 @@ func init() {
 	// HTTP server flags
 	serverCmd.Flags().StringP("host", "H", "", "Host to bind to (default from MCP_HOST env or 'localhost')")
 	serverCmd.Flags().IntP("port", "p", 0, "Port to listen on (default from MCP_PORT env or 8080)")
+	serverCmd.Flags().String("unix-socket", "", "Unix domain socket path to listen on instead of host and port (default from MCP_UNIX_SOCKET env)")
+	serverCmd.Flags().Int64("max-body-bytes", -1, "Maximum request body size in bytes, 0 for unlimited (default from MCP_MAX_BODY_BYTES env or 4MiB)")
+	serverCmd.Flags().StringSlice("allowed-cidrs", nil, "Comma-separated client networks allowed to connect, e.g. 10.0.0.0/8; all if empty (default from MCP_ALLOWED_CIDRS env)")
+	serverCmd.Flags().StringSlice("trusted-proxies", nil, "Comma-separated proxy networks whose X-Forwarded-For header is trusted for --allowed-cidrs (default from MCP_TRUSTED_PROXIES env)")
+	serverCmd.Flags().Bool("gzip", false, "Compress responses for clients that accept gzip, except event streams (default from MCP_GZIP env)")
+	serverCmd.Flags().Float64("body-log-sample-rate", -1, "Fraction of requests whose bodies are logged for debugging, from 0 to 1 (default from MCP_BODY_LOG_SAMPLE_RATE env or 0)")
+	serverCmd.Flags().String("body-log-header", "", "Log the bodies of requests that set this header, e.g. X-Debug-Bodies (default from MCP_BODY_LOG_HEADER env, disabled if empty)")
+	serverCmd.Flags().StringSlice("body-log-redact-fields", nil, "Comma-separated JSON fields redacted from logged bodies (default from MCP_BODY_LOG_REDACT_FIELDS env or common credential fields)")
+	serverCmd.Flags().Int("body-log-max-bytes", 0, "Largest request or response body logged, larger ones are left out (default from MCP_BODY_LOG_MAX_BYTES env or 64KiB)")
+	serverCmd.Flags().String("access-log-format", "", "Write an access log line for every request to stdout: 'json', 'clf' (Common Log Format) or 'combined' (default from MCP_ACCESS_LOG_FORMAT env, disabled if empty)")
+	serverCmd.Flags().Duration("shutdown-timeout", 0, "How long to wait for in-flight requests when shutting down (default from MCP_SHUTDOWN_TIMEOUT env or 30s)")
+	serverCmd.Flags().Bool("read-only", false, "Start with session writes rejected with 503 while existing sessions are still served, for backend maintenance (default from MCP_READ_ONLY env)")
+	serverCmd.Flags().Bool("self-test", false, "Store, load and delete a synthetic session before serving, failing startup if it doesn't round-trip (default from MCP_SELF_TEST env)")
+	serverCmd.Flags().Bool("check", false, "Validate configuration and connect to the session store, then exit without serving")
 
-	// Redis session storage flags (required)
-	serverCmd.Flags().String("redis-addr", "", "Redis address (REQUIRED - default from REDIS_ADDR env)")
-	serverCmd.Flags().String("redis-password", "", "Redis password (default from REDIS_PASSWORD env)")
-	serverCmd.Flags().Int("redis-db", -1, "Redis database number (default from REDIS_DB env or 0)")
-	serverCmd.Flags().String("redis-prefix", "", "Redis key prefix for sessions (default from REDIS_PREFIX env or 'mcp:session:')")
-	serverCmd.Flags().Duration("redis-ttl", 0, "Redis session TTL (default from REDIS_TTL env or 1h)")
-}
+	// Server identity flags
+	serverCmd.Flags().String("server-name", "", "Server name reported to clients (default from MCP_SERVER_NAME env or 'mcp-go-session-example')")
+	serverCmd.Flags().String("server-title", "", "Human-readable server title reported to clients (default from MCP_SERVER_TITLE env)")
+	serverCmd.Flags().String("server-version", "", "Server version reported to clients (default from MCP_SERVER_VERSION env or the build version)")
 
-func parseConfig(cmd *cobra.Command) (*Config, error) {
-	// Load configuration from environment variables
-	cfg := Config{}
-	if err := env.Parse(&cfg); err != nil {
-		return nil, err
-	}
+	// Load shedding flags
+	serverCmd.Flags().Duration("shed-latency-threshold", 0, "Reject new sessions with 503 while session store latency is above this (default from MCP_SHED_LATENCY_THRESHOLD env, disabled if 0)")
+	serverCmd.Flags().Bool("shed-protect-existing", true, "Keep serving existing sessions while shedding; false sheds every request (default from MCP_SHED_PROTECT_EXISTING env or true)")
+	serverCmd.Flags().Int("max-concurrent-requests", 0, "Requests handled at once across all sessions, more get 503 (default from MCP_MAX_CONCURRENT_REQUESTS env, unlimited if 0)")
 
-	// Override with command line flags if provided
-	if host, _ := cmd.Flags().GetString("host"); host != "" {
-		cfg.Host = host
-	}
-	if port, _ := cmd.Flags().GetInt("port"); port != 0 {
-		cfg.Port = port
-	}
-	if addr, _ := cmd.Flags().GetString("redis-addr"); addr != "" {
-		cfg.RedisAddr = addr
-	}
-	if password, _ := cmd.Flags().GetString("redis-password"); password != "" {
-		cfg.RedisPassword = password
-	}
-	if db, _ := cmd.Flags().GetInt("redis-db"); db >= 0 {
-		cfg.RedisDB = db
-	}
-	if prefix, _ := cmd.Flags().GetString("redis-prefix"); prefix != "" {
-		cfg.RedisPrefix = prefix
-	}
-	if ttl, _ := cmd.Flags().GetDuration("redis-ttl"); ttl != 0 {
-		cfg.RedisTTL = ttl
-	}
+	// Tool flags
+	serverCmd.Flags().Duration("tool-timeout", -1, "Default timeout for tool calls, 0 for no timeout (default from MCP_TOOL_TIMEOUT env or 60s)")
+	serverCmd.Flags().Int("max-tool-result-bytes", -1, "Largest tool result in bytes, larger ones are truncated; 0 for unlimited (default from MCP_MAX_TOOL_RESULT_BYTES env or 1MiB)")
+	serverCmd.Flags().Int("max-concurrent-tool-calls", 0, "Tool calls allowed to run at once on each session (default from MCP_MAX_CONCURRENT_TOOL_CALLS env, unlimited if 0)")
+	serverCmd.Flags().String("tool-concurrency-policy", "", "What happens to tool calls over --max-concurrent-tool-calls: 'queue' or 'reject' (default from MCP_TOOL_CONCURRENCY_POLICY env or 'queue')")
+	serverCmd.Flags().String("session-not-found", "", "What a tool does when its session is missing from the store: 'reconnect' returns an error asking the client to reconnect, 'recreate' starts the session afresh (default from MCP_SESSION_NOT_FOUND env or 'reconnect')")
+	serverCmd.Flags().StringSlice("enabled-tools", nil, "Comma-separated tools to register, all if empty (default from MCP_ENABLED_TOOLS env)")
+	serverCmd.Flags().StringSlice("disabled-tools", nil, "Comma-separated tools not to register (default from MCP_DISABLED_TOOLS env)")
+	serverCmd.Flags().Bool("strict-args", false, "Reject tool calls with unknown or missing arguments with a tool error naming them (default from MCP_STRICT_ARGS env)")
+	serverCmd.Flags().String("plugins-dir", "", "Directory of JSON tool descriptors to load, reloaded on SIGHUP (default from MCP_PLUGINS_DIR env, disabled if empty)")
+	serverCmd.Flags().String("audit-log", "", "Write a JSON audit record of every tool call to this file, or 'stdout' (default from MCP_AUDIT_LOG env, disabled if empty)")
+
+	// Metrics flags
+	serverCmd.Flags().String("metrics-addr", "", "Address to serve Prometheus metrics on, e.g. :9090 (default from MCP_METRICS_ADDR env, disabled if empty)")
+	serverCmd.Flags().Duration("cache-stats-interval", 0, "Interval to log session cache statistics (default from MCP_CACHE_STATS_INTERVAL env, disabled if 0)")
+
+	// Startup flags
+	serverCmd.Flags().Int("warm-cache", 0, "Load this many of the most recently written sessions into the cache at startup (default from MCP_WARM_CACHE env, disabled if 0)")
+
+	// Analytics flags
+	serverCmd.Flags().String("write-behind-file", "", "Append a JSON event for every session write to this file, for analytics (default from MCP_WRITE_BEHIND_FILE env, disabled if empty)")
+
+	// Backup flags
+	serverCmd.Flags().String("snapshot-dir", "", "Directory to periodically write a compressed snapshot of every session to (default from MCP_SNAPSHOT_DIR env, disabled if empty)")
+	serverCmd.Flags().Duration("snapshot-interval", 0, "Interval between session snapshots (default from MCP_SNAPSHOT_INTERVAL env or 1h)")
 
-	return &cfg, nil
+	// Admin API flags
+	serverCmd.Flags().String("admin-addr", "", "Address to serve the session admin API on, e.g. 127.0.0.1:9091 (default from MCP_ADMIN_ADDR env, disabled if empty)")
+	serverCmd.Flags().String("admin-token-file", "", "File to read the admin API bearer token from (default from MCP_ADMIN_TOKEN_FILE env)")
+
+	addStoreFlags(serverCmd)
 }
 
 func runServer(cmd *cobra.Command, args []string) {
@@ -93,56 +99,384 @@ func runServer(cmd *cobra.Command, args []string) {
 		log.Fatalf("Failed to parse configuration: %v", err)
 	}
 
-	// Validate that Redis is configured
-	if cfg.RedisAddr == "" {
-		log.Fatal("Redis address is required. Set REDIS_ADDR environment variable or use --redis-addr flag")
+	// A preflight stops once the store is reachable, so it mustn't open the
+	// audit log or write-behind file, load plugins or write to the store
+	check, _ := cmd.Flags().GetBool("check")
+
+	var audit mcpserver.AuditSink
+	if cfg.AuditLog != "" && !check {
+		auditSink, closeAudit, err := openAuditLog(cfg.AuditLog)
+		if err != nil {
+			log.Fatalf("Failed to open audit log: %v", err)
+		}
+		defer closeAudit()
+		audit = auditSink
 	}
 
 	// Create the MCP server instance that will be shared
-	sessionServer := mcpserver.NewSessionServer()
-
-	// Configure Redis session storage
-	log.Printf("Configuring Redis session storage at %s", cfg.RedisAddr)
-	redisStore, err := storage.NewRedisSessionStore(storage.RedisSessionStoreConfig{
-		Addr:     cfg.RedisAddr,
-		Password: cfg.RedisPassword,
-		DB:       cfg.RedisDB,
-		Prefix:   cfg.RedisPrefix,
-		TTL:      cfg.RedisTTL,
-		Server:   sessionServer.MCPServer,
+	sessionServer := mcpserver.NewSessionServer(&mcpserver.SessionServerOptions{
+		ToolTimeout:    cfg.ToolTimeout,
+		MaxResultBytes: cfg.MaxToolResultBytes,
+		Audit:          audit,
+		EnabledTools:   cfg.EnabledTools,
+		DisabledTools:  cfg.DisabledTools,
+		StrictArgs:     cfg.StrictArgs,
+
+		SessionNotFound: mcpserver.SessionNotFoundPolicy(cfg.SessionNotFound),
+
+		MaxConcurrentToolCalls: cfg.MaxConcurrentToolCalls,
+		ConcurrencyPolicy:      mcpserver.ConcurrencyPolicy(cfg.ToolConcurrencyPolicy),
+
+		Implementation: &mcp.Implementation{
+			Name:    cfg.ServerName,
+			Title:   cfg.ServerTitle,
+			Version: cfg.ServerVersion,
+		},
 	})
+
+	// Opened before the store, so it's closed after the store has delivered
+	// its queued events
+	if cfg.WriteBehindFile != "" && !check {
+		writeBehind, err := storage.NewFileWriteBehindSink(cfg.WriteBehindFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer writeBehind.Close()
+		cfg.writeBehind = writeBehind
+	}
+
+	sessionStore, err := newSessionStore(cfg, sessionServer.MCPServer)
 	if err != nil {
-		log.Fatalf("Failed to initialize Redis session store: %v", err)
+		log.Fatal(err)
+	}
+	defer sessionStore.Close()
+
+	if check {
+		if err := runCheck(cmd.Context(), cfg, sessionStore); err != nil {
+			sessionStore.Close()
+			log.Fatalf("Configuration check failed: %v", err)
+		}
+		return
+	}
+
+	if cfg.ReadOnly {
+		log.Printf("Session store is read-only, writes are rejected until it's switched off through the admin API")
+	}
+
+	if toucher, ok := sessionStore.(storage.SessionToucher); ok {
+		sessionServer.EnableKeepalive(toucher)
+	}
+	if mcpserver.SessionNotFoundPolicy(cfg.SessionNotFound) == mcpserver.SessionNotFoundRecreate {
+		if importer, ok := sessionStore.(storage.SessionImporter); ok {
+			sessionServer.EnableSessionRecreation(importer)
+		} else {
+			log.Printf("Recreating missing sessions is disabled, the %s session store can't write session records; tools will ask clients to reconnect", cfg.Store)
+		}
+	}
+	sessionServer.EnableCapabilities(serverLimits(cfg))
+
+	// Loaded after the built-in tools, so a plugin can't replace one
+	if cfg.PluginsDir != "" {
+		loadPlugins(sessionServer, cfg.PluginsDir)
+	}
+
+	if cfg.SelfTest {
+		if err := selfTestStore(cmd.Context(), sessionStore); err != nil {
+			sessionStore.Close()
+			log.Fatalf("Session store self-test failed: %v", err)
+		}
+	}
+
+	if cfg.WarmCache > 0 {
+		warmCache(sessionStore, cfg.WarmCache)
+	}
+
+	registerStoreMetrics(sessionStore)
+	initCtx, cancelInit := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := storage.InitSessionMetrics(initCtx, sessionStore); err != nil {
+		log.Printf("Failed to initialize session metrics: %v", err)
+	}
+	cancelInit()
+	auxServers := newServerGroup()
+	if cfg.MetricsAddr != "" {
+		startMetricsServer(auxServers, cfg.MetricsAddr, sessionStore, newHealthChecks(sessionStore))
+	}
+	if cfg.AdminAddr != "" {
+		startAdminServer(auxServers, cfg.AdminAddr, cfg.AdminToken, sessionStore)
+	}
+
+	statsCtx, stopStats := context.WithCancel(context.Background())
+	defer stopStats()
+	if cfg.CacheStatsInterval > 0 {
+		go logCacheStats(statsCtx, sessionStore, cfg.CacheStatsInterval)
+	}
+	go watchStatsSignal(statsCtx, sessionStore)
+	if cfg.PluginsDir != "" {
+		go watchReloadSignal(statsCtx, sessionServer, cfg.PluginsDir)
+	}
+
+	if cfg.SnapshotDir != "" {
+		exporter, err := storage.NewSnapshotExporter(storage.SnapshotExporterConfig{
+			Store:    sessionStore,
+			Sink:     storage.DirSnapshotSink{Dir: cfg.SnapshotDir},
+			Interval: cfg.SnapshotInterval,
+		})
+		if err != nil {
+			sessionStore.Close()
+			log.Fatalf("Failed to set up session snapshots: %v", err)
+		}
+		log.Printf("Writing session snapshots to %s every %s", cfg.SnapshotDir, cfg.SnapshotInterval)
+		go exporter.Run(statsCtx)
 	}
 
 	handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
 		return sessionServer.MCPServer
 	}, &mcp.StreamableHTTPOptions{
-		SessionStore: redisStore,
+		SessionStore: sessionStore,
 	})
 
+	var mcpHandler http.Handler = logBodies(cfg, handler)
+	if cfg.Gzip {
+		mcpHandler = middleware.Gzip(mcpHandler)
+	}
+
+	listener, err := listen(cfg)
+	if err != nil {
+		shutdownAuxServers(auxServers, cfg.ShutdownTimeout)
+		log.Fatalf("Server failed to start: %v", err)
+	}
+
+	shutdownGuard := &middleware.ShutdownGuard{}
 	svr := http.Server{
-		Addr:    cfg.Host + ":" + strconv.Itoa(cfg.Port),
-		Handler: handler,
+		Addr:    listener.Addr().String(),
+		Handler: middleware.LogAccess(middleware.AccessLogFormat(cfg.AccessLogFormat), os.Stdout, middleware.Recover(shutdownGuard.Handler(allowIPs(cfg, middleware.LimitInFlight(cfg.MaxConcurrentRequests, shedLoad(cfg, sessionStore, middleware.MaxBodyBytes(cfg.MaxBodyBytes, mcpHandler))))))),
 	}
 
-	// Handle graceful shutdown
+	// Handle graceful shutdown. Requests arriving after it begins are turned
+	// away while in-flight ones finish. Once the main server has drained, the
+	// store finishes its in-flight work and then the auxiliary servers stop,
+	// all within the same timeout, so metrics stay available while it does.
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		log.Printf("Shutting down, waiting up to %s for in-flight requests", cfg.ShutdownTimeout)
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 		defer shutdownCancel()
 
+		shutdownGuard.Begin()
 		if err := svr.Shutdown(shutdownCtx); err != nil {
 			log.Printf("Server shutdown error: %v", err)
 		}
+		drainStore(shutdownCtx, sessionStore)
+		if err := auxServers.shutdown(shutdownCtx); err != nil {
+			log.Printf("Server shutdown error: %v", err)
+		}
 	}()
 
-	if err := svr.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	log.Printf("Listening on %s (graceful shutdown timeout %s)", listener.Addr(), cfg.ShutdownTimeout)
+	if err := svr.Serve(listener); err != http.ErrServerClosed {
+		shutdownAuxServers(auxServers, cfg.ShutdownTimeout)
 		log.Fatalf("Server failed to start: %v", err)
 	}
 
+	// Serve returns as soon as shutdown starts, so wait for it to finish
+	// before the store is closed
+	<-shutdownDone
 	log.Println("Server stopped")
 }
+
+// serverLimits collects the limits server_capabilities reports from cfg
+func serverLimits(cfg *Config) mcpserver.ServerLimits {
+	return mcpserver.ServerLimits{
+		MaxRequestBytes:      cfg.MaxBodyBytes,
+		MaxSessionStateBytes: cfg.MaxStateBytes,
+		SessionTTL:           sessionTTL(cfg).Seconds(),
+	}
+}
+
+// selfTestStore runs the store's self-test, if it has one
+func selfTestStore(ctx context.Context, store storage.SessionStore) error {
+	tester, ok := store.(storage.SelfTester)
+	if !ok {
+		log.Printf("Session store doesn't support a self-test, skipping it")
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := tester.SelfTest(ctx); err != nil {
+		return err
+	}
+	log.Printf("Session store self-test passed")
+	return nil
+}
+
+// drainStore has the store refuse new sessions and finish its in-flight work
+// before it's closed, logging whatever didn't complete within ctx
+func drainStore(ctx context.Context, store storage.SessionStore) {
+	drainer, ok := store.(storage.SessionDrainer)
+	if !ok {
+		return
+	}
+	if err := drainer.Drain(ctx); err != nil {
+		log.Printf("Session store didn't finish draining: %v", err)
+	}
+}
+
+// shutdownAuxServers stops the auxiliary servers when the main server fails,
+// so their ports are released before the process exits
+func shutdownAuxServers(group *serverGroup, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := group.shutdown(ctx); err != nil {
+		log.Printf("Server shutdown error: %v", err)
+	}
+}
+
+// openAuditLog starts an audit sink writing to stdout or to the file at path,
+// which is appended to. The returned function flushes queued records and
+// closes the file.
+func openAuditLog(path string) (*mcpserver.JSONAuditSink, func(), error) {
+	if path == "stdout" {
+		sink := mcpserver.NewJSONAuditSink(os.Stdout, 0)
+		return sink, func() { sink.Close() }, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+	sink := mcpserver.NewJSONAuditSink(file, 0)
+	return sink, func() {
+		sink.Close()
+		file.Close()
+	}, nil
+}
+
+// warmCache preloads up to limit recent sessions into the store's cache. Failures
+// are logged rather than fatal, since the cache fills on demand anyway.
+func warmCache(store storage.SessionStore, limit int) {
+	warmer, ok := store.(storage.CacheWarmer)
+	if !ok {
+		log.Printf("Cache warm-up is disabled, the session store doesn't support it")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	start := time.Now()
+	loaded, err := warmer.WarmCache(ctx, limit)
+	if err != nil {
+		log.Printf("Failed to warm session cache after loading %d sessions: %v", loaded, err)
+		return
+	}
+	log.Printf("Warmed session cache with %d sessions in %s", loaded, time.Since(start).Round(time.Millisecond))
+}
+
+// shedLoad wraps next with load shedding when it's configured and the session
+// store measures its latency
+func shedLoad(cfg *Config, store storage.SessionStore, next http.Handler) http.Handler {
+	if cfg.ShedLatencyThreshold <= 0 {
+		return next
+	}
+	latency, ok := store.(storage.LatencyProvider)
+	if !ok {
+		log.Printf("Load shedding is disabled, the %s session store doesn't measure latency", cfg.Store)
+		return next
+	}
+	return middleware.ShedLoad(middleware.LoadShedOptions{
+		Threshold:       cfg.ShedLatencyThreshold,
+		Latency:         latency.Latency,
+		ProtectExisting: cfg.ShedProtectExisting,
+	}, next)
+}
+
+// logBodies wraps next in debug logging of request and response bodies, if
+// it's enabled
+func logBodies(cfg *Config, next http.Handler) http.Handler {
+	if cfg.BodyLogSampleRate <= 0 && cfg.BodyLogHeader == "" {
+		return next
+	}
+	if cfg.BodyLogHeader != "" {
+		log.Printf("Logging request and response bodies for %.2f%% of requests and requests with a %s header", cfg.BodyLogSampleRate*100, cfg.BodyLogHeader)
+	} else {
+		log.Printf("Logging request and response bodies for %.2f%% of requests", cfg.BodyLogSampleRate*100)
+	}
+	return middleware.LogBodies(middleware.BodyLogOptions{
+		SampleRate:   cfg.BodyLogSampleRate,
+		DebugHeader:  cfg.BodyLogHeader,
+		RedactFields: cfg.BodyLogRedactFields,
+		MaxBytes:     cfg.BodyLogMaxBytes,
+	}, next)
+}
+
+// allowIPs wraps next in the client IP allow-list, if one is configured
+func allowIPs(cfg *Config, next http.Handler) http.Handler {
+	if len(cfg.allowedPrefixes) == 0 {
+		return next
+	}
+	if len(cfg.trustedPrefixes) == 0 {
+		log.Printf("Allowing clients from %d networks; X-Forwarded-For is ignored, no proxies are trusted", len(cfg.allowedPrefixes))
+	} else {
+		log.Printf("Allowing clients from %d networks, behind %d trusted proxy networks", len(cfg.allowedPrefixes), len(cfg.trustedPrefixes))
+	}
+	return middleware.AllowIPs(middleware.IPAllowOptions{
+		Allowed:        cfg.allowedPrefixes,
+		TrustedProxies: cfg.trustedPrefixes,
+	}, next)
+}
+
+// listen opens the listener for the MCP server, either on the configured Unix
+// socket or on host:port
+func listen(cfg *Config) (net.Listener, error) {
+	if cfg.UnixSocket == "" {
+		return net.Listen("tcp", cfg.Host+":"+strconv.Itoa(cfg.Port))
+	}
+
+	// Remove a stale socket left behind by a previous run, but never anything
+	// that isn't a socket.
+	if info, err := os.Lstat(cfg.UnixSocket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", cfg.UnixSocket)
+		}
+		if err := os.Remove(cfg.UnixSocket); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	// The socket file is unlinked when the listener is closed on shutdown.
+	return net.Listen("unix", cfg.UnixSocket)
+}
+
+// runCheck verifies that the session store is healthy and prints a summary of
+// the effective configuration. It's used by --check for preflight validation.
+func runCheck(ctx context.Context, cfg *Config, store storage.SessionStore) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := store.Health(ctx); err != nil {
+		return fmt.Errorf("session store health check failed: %w", err)
+	}
+
+	fmt.Println("Configuration OK")
+	if cfg.UnixSocket != "" {
+		fmt.Printf("  Listen address: unix:%s\n", cfg.UnixSocket)
+	} else {
+		fmt.Printf("  Listen address: %s:%d\n", cfg.Host, cfg.Port)
+	}
+	fmt.Printf("  Session store:  %s\n", cfg.Store)
+	if redisStore, ok := store.(*storage.RedisSessionStore); ok {
+		fmt.Printf("  Redis DB:       %d\n", redisStore.DB())
+	}
+	return nil
+}
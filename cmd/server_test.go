@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	mcpserver "github.com/omgitsads/mcp-go-session-example/mcp"
+	"github.com/spf13/cobra"
+)
+
+// newTestCommand returns a command with the store flags registered and
+// flags set, as parseConfig would see them from the command line
+func newTestCommand(t *testing.T, flags map[string]string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{Use: "test"}
+	addStoreFlags(cmd)
+	for name, value := range flags {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			t.Fatalf("failed to set --%s: %v", name, err)
+		}
+	}
+	return cmd
+}
+
+// captureStdout returns what fn prints to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan []byte)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		done <- buf.Bytes()
+	}()
+	fn()
+	w.Close()
+	return string(<-done)
+}
+
+func TestRunCheck(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cmd := newTestCommand(t, map[string]string{"redis-addr": mr.Addr()})
+
+	cfg, err := parseConfig(cmd)
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+	store, err := newSessionStore(cfg, mcpserver.NewSessionServer(nil).MCPServer)
+	if err != nil {
+		t.Fatalf("newSessionStore() error = %v", err)
+	}
+	defer store.Close()
+
+	var checkErr error
+	out := captureStdout(t, func() {
+		checkErr = runCheck(t.Context(), cfg, store)
+	})
+	if checkErr != nil {
+		t.Fatalf("runCheck() error = %v", checkErr)
+	}
+	if !strings.Contains(out, "Configuration OK") || !strings.Contains(out, "Session store:  redis") {
+		t.Errorf("runCheck() printed %q, want the configuration summary", out)
+	}
+
+	// A store that stops responding fails the check
+	mr.Close()
+	if err := runCheck(t.Context(), cfg, store); err == nil {
+		t.Error("runCheck() with Redis down succeeded, want an error")
+	}
+}
+
+func TestRunCheckRedisDB(t *testing.T) {
+	mr := miniredis.RunT(t)
+	tests := []struct {
+		name  string
+		flags map[string]string
+		want  string
+	}{
+		{name: "address", flags: map[string]string{"redis-addr": mr.Addr(), "redis-db": "2"}, want: "Redis DB:       2\n"},
+		{name: "URL", flags: map[string]string{"redis-url": "redis://" + mr.Addr() + "/3"}, want: "Redis DB:       3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig(newTestCommand(t, tt.flags))
+			if err != nil {
+				t.Fatalf("parseConfig() error = %v", err)
+			}
+			store, err := newSessionStore(cfg, mcpserver.NewSessionServer(nil).MCPServer)
+			if err != nil {
+				t.Fatalf("newSessionStore() error = %v", err)
+			}
+			defer store.Close()
+
+			out := captureStdout(t, func() {
+				if err := runCheck(t.Context(), cfg, store); err != nil {
+					t.Errorf("runCheck() error = %v", err)
+				}
+			})
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("runCheck() printed %q, want it to include %q", out, tt.want)
+			}
+		})
+	}
+}
+
+// setServerFlags sets flags on serverCmd, restoring their defaults when the
+// test ends
+func setServerFlags(t *testing.T, flags map[string]string) {
+	t.Helper()
+	for name, value := range flags {
+		flag := serverCmd.Flags().Lookup(name)
+		if flag == nil {
+			t.Fatalf("unknown flag --%s", name)
+		}
+		if err := flag.Value.Set(value); err != nil {
+			t.Fatalf("failed to set --%s: %v", name, err)
+		}
+		flag.Changed = true
+		t.Cleanup(func() {
+			flag.Value.Set(flag.DefValue)
+			flag.Changed = false
+		})
+	}
+}
+
+func TestServerCheckHasNoSideEffects(t *testing.T) {
+	mr := miniredis.RunT(t)
+	dir := t.TempDir()
+	pluginsDir := filepath.Join(dir, "plugins")
+	if err := os.Mkdir(pluginsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	descriptor := `{"name": "plugin_echo", "command": {"path": "cat"}}`
+	if err := os.WriteFile(filepath.Join(pluginsDir, "echo.json"), []byte(descriptor), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	setServerFlags(t, map[string]string{
+		"check":             "true",
+		"redis-addr":        mr.Addr(),
+		"audit-log":         filepath.Join(dir, "audit.log"),
+		"write-behind-file": filepath.Join(dir, "writes.log"),
+		"plugins-dir":       pluginsDir,
+		"self-test":         "true",
+	})
+
+	var logs bytes.Buffer
+	logWriter := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(logWriter) })
+
+	out := captureStdout(t, func() { runServer(serverCmd, nil) })
+	if !strings.Contains(out, "Configuration OK") {
+		t.Errorf("runServer() printed %q, want the check summary", out)
+	}
+	for _, name := range []string{"audit.log", "writes.log"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("--check created %s", name)
+		}
+	}
+	if strings.Contains(logs.String(), "plugin tools") || strings.Contains(logs.String(), "self-test") {
+		t.Errorf("--check loaded plugins or ran the self-test: %s", logs.String())
+	}
+}
+
+func TestCheckBadRedisAddress(t *testing.T) {
+	// Nothing listens on port 1
+	cmd := newTestCommand(t, map[string]string{"redis-addr": "127.0.0.1:1"})
+
+	cfg, err := parseConfig(cmd)
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+	store, err := newSessionStore(cfg, mcpserver.NewSessionServer(nil).MCPServer)
+	if err == nil {
+		store.Close()
+		t.Fatal("newSessionStore() succeeded, want a connection error")
+	}
+	if !strings.Contains(err.Error(), "failed to connect to Redis") {
+		t.Errorf("newSessionStore() error = %v, want a connection error", err)
+	}
+}
+
+func TestCheckMissingRedisAddress(t *testing.T) {
+	t.Setenv("REDIS_ADDR", "")
+	t.Setenv("REDIS_URL", "")
+	cmd := newTestCommand(t, nil)
+
+	cfg, err := parseConfig(cmd)
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+	if _, err := newSessionStore(cfg, mcpserver.NewSessionServer(nil).MCPServer); err == nil || !strings.Contains(err.Error(), "Redis address is required") {
+		t.Errorf("newSessionStore() error = %v, want the missing address error", err)
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	cfg := &Config{
+		Store:         "redis",
+		RedisTTL:      2 * time.Hour,
+		MaxBodyBytes:  4 << 20,
+		MaxStateBytes: 64 << 10,
+		RedisPassword: "redis-secret",
+		AdminToken:    "admin-secret",
+		ConsulToken:   "consul-secret",
+	}
+	limits := serverLimits(cfg)
+	if limits.MaxRequestBytes != 4<<20 || limits.MaxSessionStateBytes != 64<<10 || limits.SessionTTL != 7200 {
+		t.Errorf("serverLimits() = %+v, want the configured limits", limits)
+	}
+
+	data, err := json.Marshal(limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("serverLimits() = %s, want no secrets", data)
+	}
+}
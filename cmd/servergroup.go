@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// serverGroup tracks the auxiliary HTTP servers, such as the metrics and
+// admin servers, that run alongside the main server, so they're all stopped
+// together when it stops
+type serverGroup struct {
+	mu      sync.Mutex
+	servers map[string]*http.Server // Keyed by name, for log messages
+	wg      sync.WaitGroup
+}
+
+// newServerGroup creates an empty serverGroup
+func newServerGroup() *serverGroup {
+	return &serverGroup{
+		servers: make(map[string]*http.Server),
+	}
+}
+
+// start serves svr on its address in the background. name is the server's
+// name in log messages, e.g. "Metrics".
+func (g *serverGroup) start(name string, svr *http.Server) {
+	g.mu.Lock()
+	g.servers[name] = svr
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := svr.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("%s server failed: %v", name, err)
+		}
+	}()
+}
+
+// shutdown gracefully stops every server in the group at once. Servers still
+// running when ctx is done are closed, dropping their open connections. It's
+// safe to call more than once.
+func (g *serverGroup) shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	servers := g.servers
+	g.servers = make(map[string]*http.Server)
+	g.mu.Unlock()
+
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for name, svr := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := svr.Shutdown(ctx); err != nil {
+				svr.Close()
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s server: %w", name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Wait for the serving goroutines, so no listener outlives the group
+	g.wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	mcpserver "github.com/omgitsads/mcp-go-session-example/mcp"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+	"github.com/spf13/cobra"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Inspect sessions in the session store",
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print the IDs of the sessions in the session store",
+	Long: `Print the ID of every session in the session store, one per line.
+The session store is configured the same way as for the server command.
+Stores that support it (Redis and etcd) are read a page at a time and each
+page is printed as it arrives, so very large stores can be listed. Redis may
+print an ID more than once if sessions change during the listing.`,
+	Args: cobra.NoArgs,
+	Run:  runSessionsList,
+}
+
+var sessionsGetCmd = &cobra.Command{
+	Use:   "get <session-id>",
+	Short: "Print the stored record for a session",
+	Long: `Print the record stored for a session as indented JSON.
+The session store is configured the same way as for the server command.
+Reading a session doesn't refresh its TTL or load it into any cache.
+Use --raw to print the stored bytes as-is when debugging serialization.
+Stored bytes that aren't UTF-8 text, such as compressed records, are
+printed base64-encoded.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSessionsGet,
+}
+
+var sessionsMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copy sessions from one session store to another",
+	Long: `Copy every session from one session store backend to another, e.g. when
+switching from Redis to etcd. Both stores are configured with the usual
+flags and environment variables; --from and --to select the backends.
+The source store must support listing sessions. Copied sessions get the
+destination store's TTL. Sessions that expire during the migration are skipped.
+--conflict decides what happens to sessions the destination already has:
+skip leaves them as they are, overwrite replaces them, and newer keeps
+whichever copy was written most recently.`,
+	Args: cobra.NoArgs,
+	Run:  runSessionsMigrate,
+}
+
+var sessionsRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore sessions from a snapshot archive",
+	Long: `Write every session in a snapshot archive, as produced by the server's
+periodic snapshots, to the session store. The session store is configured
+the same way as for the server command. Sessions get the time they had left
+when the snapshot was taken, or the store's TTL if the snapshot didn't
+record one, and sessions that have expired since are skipped. Existing
+sessions are overwritten unless --skip-existing is set.`,
+	Args: cobra.NoArgs,
+	Run:  runSessionsRestore,
+}
+
+var sessionsWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Print session events as they happen",
+	Long: `Print a line for each session write, TTL reset, delete and expiry in the
+session store, made by any instance, until interrupted. Each line has the
+time the event was received, its type and the session ID. The session store
+is configured the same way as for the server command. Only Redis supports
+watching, and it needs keyspace notifications enabled, e.g.
+CONFIG SET notify-keyspace-events Kg$x. Creates and updates are both
+reported as writes.`,
+	Args: cobra.NoArgs,
+	Run:  runSessionsWatch,
+}
+
+func init() {
+	sessionsListCmd.Flags().Int("page-size", storage.DefaultPageSize, "Number of sessions to fetch from the store at a time")
+	addStoreFlags(sessionsListCmd)
+
+	sessionsGetCmd.Flags().Bool("raw", false, "Print the raw stored bytes instead of the decoded record")
+	addStoreFlags(sessionsGetCmd)
+
+	sessionsMigrateCmd.Flags().String("from", "", "Session store backend to copy sessions from (required)")
+	sessionsMigrateCmd.Flags().String("to", "", "Session store backend to copy sessions to (required)")
+	sessionsMigrateCmd.Flags().Bool("delete-source", false, "Delete each session from the source store once its copy has been verified")
+	sessionsMigrateCmd.Flags().String("conflict", string(conflictOverwrite), "What to do with sessions the destination already has: 'skip', 'overwrite' or 'newer'")
+	sessionsMigrateCmd.MarkFlagRequired("from")
+	sessionsMigrateCmd.MarkFlagRequired("to")
+	addStoreFlags(sessionsMigrateCmd)
+
+	sessionsRestoreCmd.Flags().String("archive", "", "Snapshot archive to restore sessions from (required)")
+	sessionsRestoreCmd.Flags().Bool("skip-existing", false, "Leave sessions that are already in the store as they are")
+	sessionsRestoreCmd.MarkFlagRequired("archive")
+	addStoreFlags(sessionsRestoreCmd)
+
+	addStoreFlags(sessionsWatchCmd)
+
+	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsGetCmd)
+	sessionsCmd.AddCommand(sessionsMigrateCmd)
+	sessionsCmd.AddCommand(sessionsRestoreCmd)
+	sessionsCmd.AddCommand(sessionsWatchCmd)
+}
+
+func runSessionsList(cmd *cobra.Command, args []string) {
+	cfg, err := parseConfig(cmd)
+	if err != nil {
+		log.Fatalf("Failed to parse configuration: %v", err)
+	}
+
+	// Stores need a server to connect loaded sessions to, even though
+	// listing sessions never loads them
+	sessionServer := mcpserver.NewSessionServer(nil)
+
+	sessionStore, err := newSessionStore(cfg, sessionServer.MCPServer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sessionStore.Close()
+
+	pageSize, _ := cmd.Flags().GetInt("page-size")
+	if err := listSessions(cmd.Context(), sessionStore, pageSize); err != nil {
+		sessionStore.Close()
+		log.Fatal(err)
+	}
+}
+
+// listSessions prints every session ID in store, a page at a time when the
+// store supports it
+func listSessions(ctx context.Context, store storage.SessionStore, pageSize int) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if pager, ok := store.(storage.SessionPager); ok {
+		cursor := ""
+		for {
+			pageCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			sessionIDs, next, err := pager.ListSessionsPage(pageCtx, cursor, pageSize)
+			cancel()
+			if err != nil {
+				return fmt.Errorf("failed to list sessions: %w", err)
+			}
+			for _, sessionID := range sessionIDs {
+				fmt.Println(sessionID)
+			}
+			if next == "" {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	lister, ok := store.(storage.SessionLister)
+	if !ok {
+		return fmt.Errorf("the session store doesn't support listing sessions")
+	}
+	listCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	sessionIDs, err := lister.ListSessions(listCtx)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for _, sessionID := range sessionIDs {
+		fmt.Println(sessionID)
+	}
+	return nil
+}
+
+func runSessionsGet(cmd *cobra.Command, args []string) {
+	cfg, err := parseConfig(cmd)
+	if err != nil {
+		log.Fatalf("Failed to parse configuration: %v", err)
+	}
+
+	// Stores need a server to connect loaded sessions to, even though
+	// inspecting a session never does
+	sessionServer := mcpserver.NewSessionServer(nil)
+
+	sessionStore, err := newSessionStore(cfg, sessionServer.MCPServer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sessionStore.Close()
+
+	inspector, ok := sessionStore.(storage.SessionInspector)
+	if !ok {
+		sessionStore.Close()
+		log.Fatalf("The %s session store doesn't support inspecting sessions", cfg.Store)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sessionID := args[0]
+	raw, _ := cmd.Flags().GetBool("raw")
+	read := inspector.Inspect
+	if raw {
+		rawInspector, ok := sessionStore.(storage.RawSessionInspector)
+		if !ok {
+			sessionStore.Close()
+			log.Fatalf("The %s session store doesn't support reading raw session records", cfg.Store)
+		}
+		read = rawInspector.InspectRaw
+	}
+	data, err := read(ctx, sessionID)
+	if err != nil {
+		sessionStore.Close()
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Fatalf("Session %s not found", sessionID)
+		}
+		log.Fatalf("Failed to read session %s: %v", sessionID, err)
+	}
+
+	if err := printSession(data, raw); err != nil {
+		sessionStore.Close()
+		log.Fatal(err)
+	}
+}
+
+// printSession writes a stored session record to stdout, either as-is or
+// decoded and indented. Raw bytes that aren't UTF-8 text are base64-encoded
+// so they survive the terminal, with a note on stderr saying so.
+func printSession(data []byte, raw bool) error {
+	if raw {
+		if !utf8.Valid(data) {
+			fmt.Fprintln(os.Stderr, "Stored record isn't UTF-8 text, printing it base64-encoded")
+			data = []byte(base64.StdEncoding.EncodeToString(data))
+		}
+		if _, err := os.Stdout.Write(data); err != nil {
+			return err
+		}
+		fmt.Println()
+		return nil
+	}
+
+	var session storage.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return fmt.Errorf("failed to decode session data (try --raw): %w", err)
+	}
+
+	out, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session data: %w", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+func runSessionsMigrate(cmd *cobra.Command, args []string) {
+	cfg, err := parseConfig(cmd)
+	if err != nil {
+		log.Fatalf("Failed to parse configuration: %v", err)
+	}
+
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	if from == to {
+		log.Fatalf("--from and --to must be different session stores")
+	}
+	deleteSource, _ := cmd.Flags().GetBool("delete-source")
+	conflict, _ := cmd.Flags().GetString("conflict")
+	switch migrateConflictPolicy(conflict) {
+	case conflictSkip, conflictOverwrite, conflictNewer:
+	default:
+		log.Fatalf("Invalid --conflict policy %q, must be \"skip\", \"overwrite\" or \"newer\"", conflict)
+	}
+
+	// Loading a session connects it to a server, so both stores need one
+	sessionServer := mcpserver.NewSessionServer(nil)
+
+	source, err := newNamedSessionStore(cfg, from, sessionServer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer source.Close()
+
+	dest, err := newNamedSessionStore(cfg, to, sessionServer)
+	if err != nil {
+		source.Close()
+		log.Fatal(err)
+	}
+	defer dest.Close()
+
+	if err := migrateSessions(context.Background(), source, dest, migrateConflictPolicy(conflict), deleteSource); err != nil {
+		source.Close()
+		dest.Close()
+		log.Fatal(err)
+	}
+}
+
+// newNamedSessionStore constructs the session store backend called kind,
+// using the rest of cfg as-is
+func newNamedSessionStore(cfg *Config, kind string, sessionServer *mcpserver.SessionServer) (storage.SessionStore, error) {
+	storeCfg := *cfg
+	storeCfg.Store = kind
+	return newSessionStore(&storeCfg, sessionServer.MCPServer)
+}
+
+// migrateConflictPolicy decides what migrateSessions does with a session the
+// destination store already has, e.g. when a migration is re-run
+type migrateConflictPolicy string
+
+const (
+	// conflictSkip leaves the destination's copy as it is
+	conflictSkip migrateConflictPolicy = "skip"
+
+	// conflictOverwrite replaces the destination's copy with the source's
+	conflictOverwrite migrateConflictPolicy = "overwrite"
+
+	// conflictNewer keeps whichever copy was written most recently
+	conflictNewer migrateConflictPolicy = "newer"
+)
+
+// migrateResult counts what migrateSessions did with each session
+type migrateResult struct {
+	Copied      int // Sessions the destination didn't have, or couldn't say
+	Overwritten int // Sessions the destination had, replaced by the source's copy
+	Kept        int // Sessions the destination had, left as they were
+	Skipped     int // Sessions that expired or were deleted mid-migration
+}
+
+// migrateSessions copies every session in source to dest, printing progress
+// as it goes. Sessions dest already has are handled according to conflict.
+// With deleteSource, each session is removed from source once dest is
+// confirmed to have it, including sessions whose destination copy was kept.
+func migrateSessions(ctx context.Context, source, dest storage.SessionStore, conflict migrateConflictPolicy, deleteSource bool) error {
+	lister, ok := source.(storage.SessionLister)
+	if !ok {
+		return fmt.Errorf("the source session store doesn't support listing sessions")
+	}
+	inspector, canVerify := dest.(storage.SessionInspector)
+	if deleteSource && !canVerify {
+		return fmt.Errorf("--delete-source needs a destination store that can verify copied sessions")
+	}
+	if conflict != conflictOverwrite && !canVerify {
+		return fmt.Errorf("--conflict=%s needs a destination store that can read stored sessions", conflict)
+	}
+	sourceInspector, canInspectSource := source.(storage.SessionInspector)
+	if conflict == conflictNewer && !canInspectSource {
+		return fmt.Errorf("--conflict=newer needs a source store that can read stored sessions")
+	}
+
+	sessionIDs, err := lister.ListSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list source sessions: %w", err)
+	}
+	fmt.Printf("Migrating %d sessions\n", len(sessionIDs))
+
+	var result migrateResult
+	for i, sessionID := range sessionIDs {
+		// The destination is checked even when overwriting, where it can be,
+		// so overwritten sessions are counted
+		var existing []byte
+		if canVerify {
+			existing, err = inspector.Inspect(ctx, sessionID)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to check for session %s in the destination store: %w", sessionID, err)
+			}
+		}
+
+		copySession := existing == nil || conflict == conflictOverwrite
+		if !copySession && conflict == conflictNewer {
+			stored, err := sourceInspector.Inspect(ctx, sessionID)
+			if errors.Is(err, fs.ErrNotExist) {
+				log.Printf("Skipping session %s, it expired or was deleted during the migration", sessionID)
+				result.Skipped++
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read session %s: %w", sessionID, err)
+			}
+			copySession = updatedAt(stored).After(updatedAt(existing))
+		}
+
+		if copySession {
+			session, err := source.Get(ctx, sessionID)
+			if err != nil {
+				return fmt.Errorf("failed to load session %s: %w", sessionID, err)
+			}
+			if session == nil {
+				log.Printf("Skipping session %s, it expired or was deleted during the migration", sessionID)
+				result.Skipped++
+				continue
+			}
+
+			if err := dest.Set(sessionID, session); err != nil {
+				return fmt.Errorf("failed to store session %s: %w", sessionID, err)
+			}
+		}
+
+		if deleteSource {
+			if _, err := inspector.Inspect(ctx, sessionID); err != nil {
+				return fmt.Errorf("failed to verify copy of session %s: %w", sessionID, err)
+			}
+			if err := source.Delete(sessionID); err != nil {
+				return fmt.Errorf("failed to delete session %s from the source store: %w", sessionID, err)
+			}
+		}
+
+		switch {
+		case !copySession:
+			result.Kept++
+		case existing != nil:
+			result.Overwritten++
+		default:
+			result.Copied++
+		}
+		if (i+1)%100 == 0 {
+			fmt.Printf("  %d/%d\n", i+1, len(sessionIDs))
+		}
+	}
+
+	fmt.Printf("Copied %d new sessions, overwrote %d and kept %d existing (--conflict=%s), skipped %d\n",
+		result.Copied, result.Overwritten, result.Kept, conflict, result.Skipped)
+	return nil
+}
+
+// updatedAt returns when a stored session record was last written, or the
+// zero time for records that don't say or can't be decoded
+func updatedAt(data []byte) time.Time {
+	var sessionData storage.SessionData
+	if err := json.Unmarshal(data, &sessionData); err != nil {
+		return time.Time{}
+	}
+	return sessionData.UpdatedAt
+}
+
+func runSessionsRestore(cmd *cobra.Command, args []string) {
+	cfg, err := parseConfig(cmd)
+	if err != nil {
+		log.Fatalf("Failed to parse configuration: %v", err)
+	}
+
+	path, _ := cmd.Flags().GetString("archive")
+	skipExisting, _ := cmd.Flags().GetBool("skip-existing")
+
+	archive, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Failed to open snapshot archive: %v", err)
+	}
+	defer archive.Close()
+
+	// Stores need a server to connect loaded sessions to, even though
+	// restoring sessions never loads them
+	sessionServer := mcpserver.NewSessionServer(nil)
+
+	sessionStore, err := newSessionStore(cfg, sessionServer.MCPServer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sessionStore.Close()
+
+	result, err := storage.RestoreSnapshot(context.Background(), sessionStore, archive, skipExisting)
+	fmt.Printf("Restored %d sessions, skipped %d existing and %d expired\n", result.Restored, result.Skipped, result.Expired)
+	if err != nil {
+		sessionStore.Close()
+		log.Fatal(err)
+	}
+}
+
+func runSessionsWatch(cmd *cobra.Command, args []string) {
+	cfg, err := parseConfig(cmd)
+	if err != nil {
+		log.Fatalf("Failed to parse configuration: %v", err)
+	}
+
+	// Stores need a server to connect loaded sessions to, even though
+	// watching sessions never loads them
+	sessionServer := mcpserver.NewSessionServer(nil)
+
+	sessionStore, err := newSessionStore(cfg, sessionServer.MCPServer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sessionStore.Close()
+
+	watcher, ok := sessionStore.(storage.SessionWatcher)
+	if !ok {
+		sessionStore.Close()
+		log.Fatalf("The %s session store doesn't support watching sessions", cfg.Store)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := watcher.WatchSessions(ctx, printSessionEvent); err != nil {
+		sessionStore.Close()
+		log.Fatal(err)
+	}
+}
+
+// printSessionEvent prints a session event as a single line
+func printSessionEvent(event storage.SessionEvent) {
+	fmt.Printf("%s %-6s %s\n", event.Time.Format(time.RFC3339Nano), event.Type, event.SessionID)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestPrintSessionRaw(t *testing.T) {
+	tests := []struct {
+		name   string
+		record []byte
+		want   string
+	}{
+		{
+			name:   "JSON",
+			record: []byte(`{"session_id":"session-1", "updated_at":"2026-01-01T00:00:00Z"}`),
+			want:   `{"session_id":"session-1", "updated_at":"2026-01-01T00:00:00Z"}` + "\n",
+		},
+		{
+			name:   "indented JSON",
+			record: []byte("{\n  \"session_id\": \"session-1\"\n}"),
+			want:   "{\n  \"session_id\": \"session-1\"\n}\n",
+		},
+		{
+			name:   "compressed",
+			record: []byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe},
+			want:   base64.StdEncoding.EncodeToString([]byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe}) + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			out := captureStdout(t, func() {
+				err = printSession(tt.record, true)
+			})
+			if err != nil {
+				t.Fatalf("printSession() error = %v", err)
+			}
+			if out != tt.want {
+				t.Errorf("printSession() printed %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintSessionDecoded(t *testing.T) {
+	var err error
+	out := captureStdout(t, func() {
+		err = printSession([]byte(`{"session_id":"session-1","updated_at":"2026-01-01T00:00:00Z"}`), false)
+	})
+	if err != nil {
+		t.Fatalf("printSession() error = %v", err)
+	}
+	want := "{\n  \"session_id\": \"session-1\",\n  \"updated_at\": \"2026-01-01T00:00:00Z\"\n}\n"
+	if out != want {
+		t.Errorf("printSession() printed %q, want %q", out, want)
+	}
+
+	if err := printSession([]byte("not json"), false); err == nil {
+		t.Error("printSession() of a record that isn't JSON succeeded")
+	}
+}
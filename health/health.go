@@ -0,0 +1,152 @@
+// Package health aggregates named health checks registered by the server's
+// subsystems into a single report.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check reports whether a subsystem is healthy. It should return promptly
+// once ctx is done.
+type Check func(ctx context.Context) error
+
+// Check statuses, for a single check and for the report as a whole
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Registry holds the named checks that make up the server's health
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{
+		checks: make(map[string]Check),
+	}
+}
+
+// Register adds a check under name. Registering the same name twice panics,
+// since the second subsystem would hide the first.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.checks[name]; ok {
+		panic(fmt.Sprintf("health: check %q registered twice", name))
+	}
+	r.checks[name] = check
+}
+
+// CheckResult is the outcome of a single check
+type CheckResult struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Report is the outcome of every registered check. Status is StatusOK only
+// if every check passed.
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"` // Sorted by name
+}
+
+// Run runs every registered check in parallel and waits for them, or for ctx
+// to be done. Checks still running then are reported as failed with the
+// context's error, and are left to finish in the background.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.checks))
+	checks := make(map[string]Check, len(r.checks))
+	for name, check := range r.checks {
+		names = append(names, name)
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make([]CheckResult, len(names)),
+	}
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = runCheck(ctx, name, checks[name])
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// runCheck runs a single check, giving up on it when ctx is done. A check
+// that panics is reported as failed.
+func runCheck(ctx context.Context, name string, check Check) CheckResult {
+	start := time.Now()
+	done := make(chan error, 1) // Buffered so an abandoned check can still finish
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				log.Printf("Health check %s panicked: %v", name, v)
+				done <- fmt.Errorf("check panicked: %v", v)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Name:       name,
+		Status:     StatusOK,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Handler serves the report as JSON, with 200 if every check passed and 503
+// otherwise. Checks are given up to timeout to finish.
+func Handler(registry *Registry, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		report := registry.Run(ctx)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Printf("Failed to write health report: %v", err)
+		}
+	})
+}
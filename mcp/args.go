@@ -0,0 +1,106 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// argSchema is a tool's input schema, resolved for strict argument checks
+type argSchema struct {
+	schema   *jsonschema.Schema
+	resolved *jsonschema.Resolved
+}
+
+// newArgSchema resolves a tool's input schema. A schema can only be resolved
+// once and mcp.AddTool resolves the tool's own, so a copy is used.
+func newArgSchema(schema *jsonschema.Schema) (*argSchema, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var copied jsonschema.Schema
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+	resolved, err := copied.Resolve(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &argSchema{schema: &copied, resolved: resolved}, nil
+}
+
+// check validates a call's raw arguments. Unknown and missing arguments are
+// reported by name, ahead of anything else the schema rejects.
+func (a *argSchema) check(raw json.RawMessage) error {
+	args := map[string]any{}
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return fmt.Errorf("arguments must be a JSON object")
+		}
+	}
+
+	var unknown []string
+	for name := range args {
+		if _, ok := a.schema.Properties[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown arguments: %s", quoteNames(unknown))
+	}
+
+	var missing []string
+	for _, name := range a.schema.Required {
+		if _, ok := args[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required arguments: %s", quoteNames(missing))
+	}
+
+	return a.resolved.Validate(args)
+}
+
+// quoteNames formats argument names as a sorted, comma-separated list
+func quoteNames(names []string) string {
+	sort.Strings(names)
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// validateToolArgs is receiving middleware that checks tool arguments against
+// the tool's input schema before the call is dispatched. Invalid arguments
+// are returned as a tool error naming the problem, so the client sees an
+// actionable message instead of the SDK's schema dump.
+func (s *SessionServer) validateToolArgs(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, ss *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		if call, ok := params.(*mcp.CallToolParamsFor[json.RawMessage]); ok && method == "tools/call" {
+			s.toolsMu.RLock()
+			schema := s.argSchemas[call.Name]
+			s.toolsMu.RUnlock()
+
+			if schema != nil {
+				if err := schema.check(call.Arguments); err != nil {
+					return &mcp.CallToolResult{
+						Content: []mcp.Content{
+							Text(fmt.Sprintf("invalid arguments for tool %q: %v", call.Name, err)),
+						},
+						IsError: true,
+					}, nil
+				}
+			}
+		}
+		return next(ctx, ss, method, params)
+	}
+}
@@ -0,0 +1,93 @@
+package mcpserver
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type repeatArgs struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// repeat is a tool handler returning its word argument count times
+func repeat(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[repeatArgs]) (*mcp.CallToolResultFor[any], error) {
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{Text(strings.Repeat(params.Arguments.Word, params.Arguments.Count))}}, nil
+}
+
+func TestStrictArgs(t *testing.T) {
+	server := NewSessionServer(&SessionServerOptions{StrictArgs: true})
+	AddTool(server, &mcp.Tool{Name: "repeat"}, repeat)
+	session := connect(t, server)
+
+	tests := []struct {
+		name    string
+		args    map[string]any
+		want    string
+		wantErr string
+	}{
+		{name: "valid", args: map[string]any{"word": "ab", "count": 2}, want: "abab"},
+		{name: "unknown property", args: map[string]any{"word": "ab", "count": 2, "times": 3, "extra": true}, wantErr: `invalid arguments for tool "repeat": unknown arguments: "extra", "times"`},
+		{name: "missing property", args: map[string]any{"word": "ab"}, wantErr: `invalid arguments for tool "repeat": missing required arguments: "count"`},
+		{name: "wrong type", args: map[string]any{"word": "ab", "count": "two"}, wantErr: `invalid arguments for tool "repeat": `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := callTool(t, session, "repeat", tt.args)
+			if tt.wantErr == "" {
+				if res.IsError || resultText(res) != tt.want {
+					t.Errorf("repeat = %q (error %v), want %q", resultText(res), res.IsError, tt.want)
+				}
+				return
+			}
+			if !res.IsError || !strings.HasPrefix(resultText(res), tt.wantErr) {
+				t.Errorf("repeat = %q (error %v), want a tool error starting %q", resultText(res), res.IsError, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStrictArgsUnresolvableSchema(t *testing.T) {
+	var logs bytes.Buffer
+	logger := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	server := NewSessionServer(&SessionServerOptions{StrictArgs: true})
+	AddTool(server, &mcp.Tool{
+		Name: "broken",
+		InputSchema: &jsonschema.Schema{
+			Type:       "object",
+			Properties: map[string]*jsonschema.Schema{"word": {Ref: "#/$defs/missing"}},
+		},
+	}, repeat)
+
+	// The tool is skipped rather than panicking or being half registered
+	if !strings.Contains(logs.String(), `Not registering tool "broken"`) {
+		t.Errorf("log = %q, want the skipped tool logged", logs.String())
+	}
+	for _, tool := range server.Tools() {
+		if tool.Name == "broken" {
+			t.Error("Tools() lists a tool whose schema couldn't be resolved")
+		}
+	}
+	session := connect(t, server)
+	advertised, err := session.ListTools(t.Context(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range advertised.Tools {
+		if tool.Name == "broken" {
+			t.Error("tools/list advertises a tool whose schema couldn't be resolved")
+		}
+	}
+	if _, err := session.CallTool(t.Context(), &mcp.CallToolParams{Name: "broken", Arguments: map[string]any{}}); err == nil {
+		t.Error(`calling "broken" succeeded`)
+	}
+}
@@ -0,0 +1,127 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultAuditBufferSize is how many records a JSONAuditSink queues before it
+// starts dropping them
+const defaultAuditBufferSize = 1024
+
+// AuditRecord describes a single tool invocation
+type AuditRecord struct {
+	Time       time.Time `json:"time"`
+	SessionID  string    `json:"session_id"`
+	Tool       string    `json:"tool"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS float64   `json:"duration_ms"`
+}
+
+// AuditSink receives a record for every tool invocation. Record is called on
+// the request path, so implementations must not block.
+type AuditSink interface {
+	Record(record AuditRecord)
+}
+
+// JSONAuditSink writes audit records to a writer as JSON lines. Records are
+// queued on a bounded buffer and written in the background; when the buffer
+// is full new records are dropped and counted in
+// mcp_audit_records_dropped_total, so a slow destination never delays tool
+// calls.
+type JSONAuditSink struct {
+	records chan AuditRecord
+	mu      sync.RWMutex
+	closed  bool // Set once records is closed
+	done    chan struct{}
+}
+
+// NewJSONAuditSink starts a sink that writes to w, queueing up to bufferSize
+// records. A bufferSize of 0 or less uses the default of 1024. Close must be
+// called to flush queued records before w is closed.
+func NewJSONAuditSink(w io.Writer, bufferSize int) *JSONAuditSink {
+	if bufferSize <= 0 {
+		bufferSize = defaultAuditBufferSize
+	}
+
+	sink := &JSONAuditSink{
+		records: make(chan AuditRecord, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go sink.run(w)
+	return sink
+}
+
+// Record queues a record to be written, dropping it if the buffer is full or
+// the sink has been closed, e.g. for a tool call still running when shutdown
+// gave up waiting for it
+func (s *JSONAuditSink) Record(record AuditRecord) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.records <- record:
+	default:
+		auditRecordsDroppedTotal.Inc()
+	}
+}
+
+// Close stops accepting records and waits for queued ones to be written
+func (s *JSONAuditSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.records)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+// run writes queued records to w until the sink is closed
+func (s *JSONAuditSink) run(w io.Writer) {
+	defer close(s.done)
+
+	encoder := json.NewEncoder(w)
+	for record := range s.records {
+		if err := encoder.Encode(record); err != nil {
+			log.Printf("Failed to write audit record: %v", err)
+		}
+	}
+}
+
+// withAudit records every call of a handler to sink, including calls that
+// fail or time out
+func withAudit[In, Out any](name string, sink AuditSink, h mcp.ToolHandlerFor[In, Out]) mcp.ToolHandlerFor[In, Out] {
+	if sink == nil {
+		return h
+	}
+
+	return func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[In]) (*mcp.CallToolResultFor[Out], error) {
+		start := time.Now()
+		res, err := h(ctx, ss, params)
+
+		record := AuditRecord{
+			Time:       start.UTC(),
+			SessionID:  ss.ID(),
+			Tool:       name,
+			Success:    err == nil && (res == nil || !res.IsError),
+			DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		sink.Record(record)
+
+		return res, err
+	}
+}
@@ -0,0 +1,127 @@
+package mcpserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// auditRecorder is an AuditSink keeping the records it receives
+type auditRecorder struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (a *auditRecorder) Record(record AuditRecord) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.records = append(a.records, record)
+}
+
+func (a *auditRecorder) Records() []AuditRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]AuditRecord(nil), a.records...)
+}
+
+func TestAuditToolCalls(t *testing.T) {
+	audit := &auditRecorder{}
+	server := NewSessionServer(&SessionServerOptions{Audit: audit})
+	AddTool(server, &mcp.Tool{Name: "echo"}, echo)
+	AddTool(server, &mcp.Tool{Name: "tool_error"}, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+		return &mcp.CallToolResultFor[any]{IsError: true, Content: []mcp.Content{Text("bad input")}}, nil
+	})
+	AddTool(server, &mcp.Tool{Name: "fail"}, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+		return nil, errors.New("backend down")
+	})
+	session := connect(t, server)
+
+	callTool(t, session, "echo", map[string]any{"message": "hi"})
+	callTool(t, session, "tool_error", map[string]any{})
+	callTool(t, session, "fail", map[string]any{})
+
+	records := audit.Records()
+	if len(records) != 3 {
+		t.Fatalf("audited %d calls, want 3: %+v", len(records), records)
+	}
+	tests := []struct {
+		tool        string
+		wantSuccess bool
+		wantError   string
+	}{
+		{tool: "echo", wantSuccess: true},
+		{tool: "tool_error", wantSuccess: false},
+		{tool: "fail", wantSuccess: false, wantError: "backend down"},
+	}
+	for i, tt := range tests {
+		record := records[i]
+		if record.Tool != tt.tool || record.Success != tt.wantSuccess || record.Error != tt.wantError {
+			t.Errorf("record %d = %+v, want tool %s with success %v and error %q", i, record, tt.tool, tt.wantSuccess, tt.wantError)
+		}
+		if record.Time.IsZero() || record.DurationMS < 0 {
+			t.Errorf("record %d = %+v, want its time and duration", i, record)
+		}
+	}
+}
+
+// blockingWriter blocks every write until release is closed, signalling
+// writing when the first write starts
+type blockingWriter struct {
+	writing chan struct{}
+	release chan struct{}
+	once    sync.Once
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.writing) })
+	<-w.release
+	return w.buf.Write(p)
+}
+
+func TestJSONAuditSink(t *testing.T) {
+	w := &blockingWriter{writing: make(chan struct{}), release: make(chan struct{})}
+	sink := NewJSONAuditSink(w, 1)
+	dropped := testutil.ToFloat64(auditRecordsDroppedTotal)
+
+	// The first record is being written and the second fills the buffer, so
+	// the third is dropped rather than blocking
+	sink.Record(AuditRecord{Tool: "first", Success: true})
+	<-w.writing
+	sink.Record(AuditRecord{Tool: "second", Success: true})
+	sink.Record(AuditRecord{Tool: "third", Success: true})
+	if got := testutil.ToFloat64(auditRecordsDroppedTotal) - dropped; got != 1 {
+		t.Errorf("dropped records counted = %v, want 1", got)
+	}
+
+	close(w.release)
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Records after Close are dropped without panicking
+	sink.Record(AuditRecord{Tool: "after close"})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+
+	var tools []string
+	scanner := bufio.NewScanner(&w.buf)
+	for scanner.Scan() {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("audit line %q isn't a record: %v", scanner.Text(), err)
+		}
+		tools = append(tools, record.Tool)
+	}
+	if len(tools) != 2 || tools[0] != "first" || tools[1] != "second" {
+		t.Errorf("audit log has records for %v, want first and second", tools)
+	}
+}
@@ -0,0 +1,86 @@
+package mcpserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Bar chart geometry, in pixels
+const (
+	chartHeight   = 200
+	chartBarWidth = 20
+	chartBarGap   = 4
+	chartMaxBars  = 100
+)
+
+var (
+	chartBackground = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	chartBar        = color.RGBA{R: 0x33, G: 0x66, B: 0xcc, A: 0xff}
+)
+
+type RenderChartArgs struct {
+	Values []float64 `json:"values" jsonschema:"bar heights to plot, 1 to 100 values of 0 or more"`
+}
+
+// handleRenderChartTool is an example of returning non-text content: it
+// draws the given values as a bar chart and returns it as a PNG image
+func (s *SessionServer) handleRenderChartTool(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[RenderChartArgs]) (*mcp.CallToolResultFor[any], error) {
+	data, err := renderBarChart(params.Arguments.Values)
+	if err != nil {
+		return nil, err
+	}
+	chart, err := Image("image/png", data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mcp.CallToolResultFor[any]{
+		Content: []mcp.Content{
+			chart,
+			Text(fmt.Sprintf("Bar chart of %d values", len(params.Arguments.Values))),
+		},
+	}, nil
+}
+
+// renderBarChart draws values as bars scaled to the largest one and encodes
+// the chart as a PNG
+func renderBarChart(values []float64) ([]byte, error) {
+	if len(values) == 0 || len(values) > chartMaxBars {
+		return nil, fmt.Errorf("chart needs 1 to %d values, got %d", chartMaxBars, len(values))
+	}
+	var largest float64
+	for _, v := range values {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, errors.New("chart values must be finite and 0 or more")
+		}
+		largest = max(largest, v)
+	}
+
+	width := len(values)*(chartBarWidth+chartBarGap) + chartBarGap
+	img := image.NewRGBA(image.Rect(0, 0, width, chartHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(chartBackground), image.Point{}, draw.Src)
+	for i, v := range values {
+		height := 0
+		if largest > 0 {
+			height = int(math.Round(v / largest * chartHeight))
+		}
+		x := chartBarGap + i*(chartBarWidth+chartBarGap)
+		bar := image.Rect(x, chartHeight-height, x+chartBarWidth, chartHeight)
+		draw.Draw(img, bar, image.NewUniform(chartBar), image.Point{}, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode chart: %w", err)
+	}
+	return buf.Bytes(), nil
+}
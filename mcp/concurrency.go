@@ -0,0 +1,131 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ConcurrencyPolicy decides what happens to a tool call that would exceed a
+// session's concurrency limit
+type ConcurrencyPolicy string
+
+const (
+	// ConcurrencyQueue makes the call wait for a running call to finish,
+	// for up to the tool's timeout
+	ConcurrencyQueue ConcurrencyPolicy = "queue"
+
+	// ConcurrencyReject fails the call straight away with a tool error
+	ConcurrencyReject ConcurrencyPolicy = "reject"
+)
+
+// errTooManyCalls is returned by acquire under the reject policy
+var errTooManyCalls = errors.New("too many concurrent tool calls")
+
+// sessionLimiter bounds the tool calls running at once for each session.
+// Sessions only have an entry while they have calls running or waiting, so
+// closed sessions don't accumulate.
+type sessionLimiter struct {
+	limit  int
+	policy ConcurrencyPolicy
+
+	mu       sync.Mutex
+	sessions map[string]*sessionSlots
+}
+
+// sessionSlots is one session's semaphore, shared by the calls holding or
+// waiting for it
+type sessionSlots struct {
+	slots chan struct{}
+	refs  int
+}
+
+// newSessionLimiter creates a limiter allowing limit calls per session, or
+// returns nil if limit is 0 or less
+func newSessionLimiter(limit int, policy ConcurrencyPolicy) *sessionLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	if policy == "" {
+		policy = ConcurrencyQueue
+	}
+	return &sessionLimiter{
+		limit:    limit,
+		policy:   policy,
+		sessions: make(map[string]*sessionSlots),
+	}
+}
+
+// acquire takes one of the session's slots, waiting for one to free up or
+// failing at once depending on the policy. It returns the function that
+// releases the slot.
+func (l *sessionLimiter) acquire(ctx context.Context, sessionID string) (func(), error) {
+	l.mu.Lock()
+	s, ok := l.sessions[sessionID]
+	if !ok {
+		s = &sessionSlots{slots: make(chan struct{}, l.limit)}
+		l.sessions[sessionID] = s
+	}
+	s.refs++
+	l.mu.Unlock()
+
+	if l.policy == ConcurrencyReject {
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			l.unref(sessionID, s)
+			return nil, errTooManyCalls
+		}
+	} else {
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			l.unref(sessionID, s)
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-s.slots
+			l.unref(sessionID, s)
+		})
+	}, nil
+}
+
+// unref drops a call's reference to a session's slots, removing the entry
+// once nothing holds or waits for it
+func (l *sessionLimiter) unref(sessionID string, s *sessionSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s.refs--; s.refs == 0 {
+		delete(l.sessions, sessionID)
+	}
+}
+
+// withConcurrencyLimit bounds how many calls to any of the server's tools run
+// at once on each session. Calls over the limit wait or are rejected with a
+// tool error according to the limiter's policy; rejections are counted in
+// mcp_tool_calls_rejected_total. A nil limiter disables the limit.
+func withConcurrencyLimit[In, Out any](name string, limiter *sessionLimiter, h mcp.ToolHandlerFor[In, Out]) mcp.ToolHandlerFor[In, Out] {
+	if limiter == nil {
+		return h
+	}
+
+	return func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[In]) (*mcp.CallToolResultFor[Out], error) {
+		release, err := limiter.acquire(ctx, ss.ID())
+		if errors.Is(err, errTooManyCalls) {
+			toolCallsRejectedTotal.WithLabelValues(name).Inc()
+			return nil, fmt.Errorf("tool %q rejected: this session already has %d tool calls running, retry once one finishes", name, limiter.limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return h(ctx, ss, params)
+	}
+}
@@ -0,0 +1,168 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// The in-memory transport handles one request at a time, so these tests drive
+// withConcurrencyLimit directly to get calls overlapping on a session.
+
+type blockArgs struct{}
+
+// blockingHandler returns a handler that signals started and then waits for
+// release or its context
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) mcp.ToolHandlerFor[blockArgs, any] {
+	return func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[blockArgs]) (*mcp.CallToolResultFor[any], error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return &mcp.CallToolResultFor[any]{}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// callLimited runs h on another goroutine, delivering its error
+func callLimited(ctx context.Context, h mcp.ToolHandlerFor[blockArgs, any]) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		_, err := h(ctx, &mcp.ServerSession{}, &mcp.CallToolParamsFor[blockArgs]{})
+		errs <- err
+	}()
+	return errs
+}
+
+// activeSessions returns how many sessions the limiter has an entry for
+func activeSessions(l *sessionLimiter) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.sessions)
+}
+
+func TestConcurrencyLimitReject(t *testing.T) {
+	limiter := newSessionLimiter(1, ConcurrencyReject)
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	h := withConcurrencyLimit("block", limiter, blockingHandler(started, release))
+	rejected := testutil.ToFloat64(toolCallsRejectedTotal.WithLabelValues("block"))
+
+	first := callLimited(t.Context(), h)
+	<-started
+
+	err := <-callLimited(t.Context(), h)
+	if err == nil || !strings.Contains(err.Error(), `tool "block" rejected: this session already has 1 tool calls running`) {
+		t.Errorf("second call error = %v, want it rejected", err)
+	}
+	if got := testutil.ToFloat64(toolCallsRejectedTotal.WithLabelValues("block")) - rejected; got != 1 {
+		t.Errorf("rejected calls counted = %v, want 1", got)
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if err := <-callLimited(t.Context(), h); err != nil {
+		t.Errorf("call once the first finished: %v", err)
+	}
+	if n := activeSessions(limiter); n != 0 {
+		t.Errorf("limiter has %d session entries with no calls running, want 0", n)
+	}
+}
+
+func TestConcurrencyLimitQueue(t *testing.T) {
+	limiter := newSessionLimiter(1, "")
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	h := withConcurrencyLimit("block", limiter, blockingHandler(started, release))
+
+	first := callLimited(t.Context(), h)
+	<-started
+	second := callLimited(t.Context(), h)
+	select {
+	case <-started:
+		t.Fatal("second call started while the first was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	for _, errs := range []<-chan error{first, second} {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Errorf("queued call: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("queued call didn't finish")
+		}
+	}
+	if n := activeSessions(limiter); n != 0 {
+		t.Errorf("limiter has %d session entries with no calls running, want 0", n)
+	}
+}
+
+func TestConcurrencyLimitQueueTimeout(t *testing.T) {
+	limiter := newSessionLimiter(1, ConcurrencyQueue)
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	h := withConcurrencyLimit("block", limiter, blockingHandler(started, release))
+
+	first := callLimited(t.Context(), h)
+	<-started
+
+	// A waiting call gives up when its context does, without running
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if err := <-callLimited(ctx, h); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued call error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case <-started:
+		t.Error("queued call ran after its context expired")
+	default:
+	}
+
+	close(release)
+	<-first
+	if n := activeSessions(limiter); n != 0 {
+		t.Errorf("limiter has %d session entries with no calls running, want 0", n)
+	}
+}
+
+func TestConcurrencyLimitReleasesOnFailure(t *testing.T) {
+	limiter := newSessionLimiter(1, ConcurrencyReject)
+	failing := withConcurrencyLimit("fail", limiter, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[blockArgs]) (*mcp.CallToolResultFor[any], error) {
+		return nil, errors.New("backend down")
+	})
+	panicking := withConcurrencyLimit("panic", limiter, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[blockArgs]) (*mcp.CallToolResultFor[any], error) {
+		panic("boom")
+	})
+	ok := withConcurrencyLimit("ok", limiter, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[blockArgs]) (*mcp.CallToolResultFor[any], error) {
+		return &mcp.CallToolResultFor[any]{}, nil
+	})
+
+	if err := <-callLimited(t.Context(), failing); err == nil {
+		t.Fatal("failing call succeeded")
+	}
+	if err := <-callLimited(t.Context(), ok); err != nil {
+		t.Errorf("call after a handler error: %v, want the slot released", err)
+	}
+
+	func() {
+		defer func() { recover() }()
+		panicking(t.Context(), &mcp.ServerSession{}, &mcp.CallToolParamsFor[blockArgs]{})
+	}()
+	if err := <-callLimited(t.Context(), ok); err != nil {
+		t.Errorf("call after a handler panic: %v, want the slot released", err)
+	}
+	if n := activeSessions(limiter); n != 0 {
+		t.Errorf("limiter has %d session entries with no calls running, want 0", n)
+	}
+}
@@ -0,0 +1,86 @@
+package mcpserver
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Text wraps text in TextContent. MCP messages are JSON, which must be valid
+// UTF-8, so invalid byte sequences are replaced with U+FFFD rather than being
+// passed on for the encoder or the client to mangle. Tools should build text
+// results with Text rather than constructing mcp.TextContent directly.
+func Text(text string) *mcp.TextContent {
+	return &mcp.TextContent{Text: validUTF8(text)}
+}
+
+// TypedText wraps text with an explicit MIME type, such as "text/markdown" or
+// "application/json", in an embedded resource identified by uri. Plain
+// TextContent has no MIME type, so use this when clients need to know how to
+// render the text. Invalid UTF-8 is replaced in the same way as Text.
+func TypedText(uri, mimeType, text string) *mcp.EmbeddedResource {
+	return &mcp.EmbeddedResource{
+		Resource: &mcp.ResourceContents{
+			URI:      uri,
+			MIMEType: mimeType,
+			Text:     validUTF8(text),
+		},
+	}
+}
+
+// Image wraps image data, such as a rendered PNG, in ImageContent. data is
+// the raw image; the SDK base64-encodes it on the wire. mimeType must be a
+// valid image/* media type, since clients use it to decide how to decode
+// the data.
+func Image(mimeType string, data []byte) (*mcp.ImageContent, error) {
+	mediaType, err := validMIMEType(mimeType)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(mediaType, "image/") {
+		return nil, fmt.Errorf("MIME type %q is not an image type", mimeType)
+	}
+	return &mcp.ImageContent{MIMEType: mimeType, Data: data}, nil
+}
+
+// Blob wraps arbitrary binary data in an embedded resource identified by
+// uri, for content that isn't text or an image, e.g. a generated PDF. The
+// SDK base64-encodes data on the wire. mimeType must be a valid media type.
+func Blob(uri, mimeType string, data []byte) (*mcp.EmbeddedResource, error) {
+	if _, err := validMIMEType(mimeType); err != nil {
+		return nil, err
+	}
+	// A nil Blob is encoded as a text resource, so keep empty data a blob
+	if data == nil {
+		data = []byte{}
+	}
+	return &mcp.EmbeddedResource{
+		Resource: &mcp.ResourceContents{
+			URI:      uri,
+			MIMEType: mimeType,
+			Blob:     data,
+		},
+	}, nil
+}
+
+// validMIMEType checks mimeType parses as a type/subtype media type and
+// returns the media type without parameters, lowercased
+func validMIMEType(mimeType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	typ, subtype, ok := strings.Cut(mediaType, "/")
+	if err != nil || !ok || typ == "" || subtype == "" {
+		return "", fmt.Errorf("invalid MIME type %q", mimeType)
+	}
+	return mediaType, nil
+}
+
+// validUTF8 returns text with each run of invalid UTF-8 replaced by U+FFFD
+func validUTF8(text string) string {
+	if utf8.ValidString(text) {
+		return text
+	}
+	return strings.ToValidUTF8(text, string(utf8.RuneError))
+}
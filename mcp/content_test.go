@@ -0,0 +1,155 @@
+package mcpserver
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "valid", text: "héllo, 世界", want: "héllo, 世界"},
+		{name: "invalid byte", text: "bad \xff byte", want: "bad � byte"},
+		{name: "invalid run", text: "bad \xff\xfe\xfd run", want: "bad � run"},
+		{name: "truncated rune", text: "cut \xe4\xb8", want: "cut �"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.text).Text; got != tt.want {
+				t.Errorf("Text(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			got := TypedText("file:///report.md", "text/markdown", tt.text)
+			if got.Resource.Text != tt.want || got.Resource.MIMEType != "text/markdown" || got.Resource.URI != "file:///report.md" {
+				t.Errorf("TypedText(%q) = %+v, want text %q with the URI and MIME type", tt.text, got.Resource, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextToolResult(t *testing.T) {
+	server := NewSessionServer(nil)
+	AddTool(server, &mcp.Tool{Name: "invalid_text"}, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{
+			Text("bad \xff byte"),
+			TypedText("file:///report.md", "text/markdown", "# bad \xff byte"),
+		}}, nil
+	})
+	session := connect(t, server)
+
+	res := callTool(t, session, "invalid_text", map[string]any{})
+	if res.IsError || len(res.Content) != 2 {
+		t.Fatalf("invalid_text returned %+v", res)
+	}
+	if got := resultText(res); got != "bad � byte" {
+		t.Errorf("client received text %q, want the invalid byte replaced", got)
+	}
+	resource, ok := res.Content[1].(*mcp.EmbeddedResource)
+	if !ok || resource.Resource.Text != "# bad � byte" || resource.Resource.MIMEType != "text/markdown" {
+		t.Errorf("client received %+v, want a markdown resource with the invalid byte replaced", res.Content[1])
+	}
+}
+
+func TestImage(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		wantErr  bool
+	}{
+		{mimeType: "image/png"},
+		{mimeType: "IMAGE/SVG+XML; charset=utf-8"},
+		{mimeType: "application/pdf", wantErr: true},
+		{mimeType: "image", wantErr: true},
+		{mimeType: "image/", wantErr: true},
+		{mimeType: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Image(tt.mimeType, []byte("data"))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Image(%q) = %+v, want an error", tt.mimeType, got)
+			}
+			continue
+		}
+		if err != nil || got.MIMEType != tt.mimeType || string(got.Data) != "data" {
+			t.Errorf("Image(%q) = %+v, %v, want the data with its MIME type", tt.mimeType, got, err)
+		}
+	}
+}
+
+func TestBlob(t *testing.T) {
+	got, err := Blob("file:///report.pdf", "application/pdf", []byte("%PDF"))
+	if err != nil || got.Resource.URI != "file:///report.pdf" || got.Resource.MIMEType != "application/pdf" || string(got.Resource.Blob) != "%PDF" {
+		t.Errorf("Blob() = %+v, %v, want the data with its URI and MIME type", got.Resource, err)
+	}
+	if got, err := Blob("file:///empty", "application/octet-stream", nil); err != nil || got.Resource.Blob == nil {
+		t.Errorf("Blob() with nil data = %+v, %v, want an empty blob", got.Resource, err)
+	}
+	if _, err := Blob("file:///report.pdf", "pdf", []byte("%PDF")); err == nil {
+		t.Error("Blob() with an invalid MIME type succeeded")
+	}
+}
+
+func TestBinaryToolResult(t *testing.T) {
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	server := NewSessionServer(nil)
+	AddTool(server, &mcp.Tool{Name: "binary"}, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+		image, err := Image("image/png", data)
+		if err != nil {
+			return nil, err
+		}
+		blob, err := Blob("file:///data.bin", "application/octet-stream", data)
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{image, blob}}, nil
+	})
+	session := connect(t, server)
+
+	res := callTool(t, session, "binary", map[string]any{})
+	if res.IsError || len(res.Content) != 2 {
+		t.Fatalf("binary returned %+v", res)
+	}
+	image, ok := res.Content[0].(*mcp.ImageContent)
+	if !ok || image.MIMEType != "image/png" || !bytes.Equal(image.Data, data) {
+		t.Errorf("client received %+v, want the PNG data intact", res.Content[0])
+	}
+	blob, ok := res.Content[1].(*mcp.EmbeddedResource)
+	if !ok || blob.Resource.MIMEType != "application/octet-stream" || !bytes.Equal(blob.Resource.Blob, data) {
+		t.Errorf("client received %+v, want the blob data intact", res.Content[1])
+	}
+}
+
+func TestRenderChartTool(t *testing.T) {
+	session := connect(t, NewSessionServer(nil))
+
+	res := callTool(t, session, "render_chart", RenderChartArgs{Values: []float64{1, 4, 2}})
+	if res.IsError || len(res.Content) != 2 {
+		t.Fatalf("render_chart returned %+v", res)
+	}
+	chart, ok := res.Content[0].(*mcp.ImageContent)
+	if !ok || chart.MIMEType != "image/png" {
+		t.Fatalf("render_chart content = %+v, want a PNG image", res.Content[0])
+	}
+	img, err := png.Decode(bytes.NewReader(chart.Data))
+	if err != nil {
+		t.Fatalf("render_chart image doesn't decode: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 3*(chartBarWidth+chartBarGap)+chartBarGap || bounds.Dy() != chartHeight {
+		t.Errorf("chart size = %v, want room for 3 bars", bounds.Size())
+	}
+	if text, ok := res.Content[1].(*mcp.TextContent); !ok || text.Text != "Bar chart of 3 values" {
+		t.Errorf("render_chart caption = %+v", res.Content[1])
+	}
+
+	for _, values := range [][]float64{nil, {-1}, make([]float64, chartMaxBars+1)} {
+		if res := callTool(t, session, "render_chart", RenderChartArgs{Values: values}); !res.IsError {
+			t.Errorf("render_chart(%d values) succeeded, want an error", len(values))
+		}
+	}
+}
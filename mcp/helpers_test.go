@@ -0,0 +1,50 @@
+package mcpserver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connect returns a client session connected to server over an in-memory
+// transport, closed when the test ends
+func connect(t *testing.T, server *SessionServer) *mcp.ClientSession {
+	t.Helper()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.MCPServer.Connect(t.Context(), serverTransport)
+	if err != nil {
+		t.Fatalf("failed to connect server: %v", err)
+	}
+	t.Cleanup(func() { serverSession.Close() })
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "mcp-test", Version: "0.0.0"}, nil)
+	session, err := client.Connect(t.Context(), clientTransport)
+	if err != nil {
+		t.Fatalf("failed to connect client: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+// callTool calls a tool, failing the test if the request itself fails. Tool
+// errors are returned in the result.
+func callTool(t *testing.T, session *mcp.ClientSession, name string, args any) *mcp.CallToolResult {
+	t.Helper()
+	res, err := session.CallTool(t.Context(), &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("CallTool(%q) error = %v", name, err)
+	}
+	return res
+}
+
+// resultText joins the text content of a result
+func resultText(res *mcp.CallToolResult) string {
+	var texts []string
+	for _, content := range res.Content {
+		if text, ok := content.(*mcp.TextContent); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,50 @@
+package mcpserver
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// auditRecordsDroppedTotal counts audit records dropped because the audit
+	// sink's buffer was full
+	auditRecordsDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mcp_audit_records_dropped_total",
+		Help: "Tool invocation audit records dropped because the audit log buffer was full.",
+	})
+
+	// toolCallsTotal counts finished tool calls by outcome: "success", or
+	// "error" for calls that failed or returned a tool error
+	toolCallsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_tool_calls_total",
+		Help: "Tool calls handled, by tool and outcome.",
+	}, []string{"tool", "outcome"})
+
+	// toolCallDuration records how long tool calls take, including time spent
+	// waiting for a concurrency slot
+	toolCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mcp_tool_call_duration_seconds",
+		Help:    "Tool call duration in seconds, by tool.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"tool"})
+
+	// toolPanicsTotal counts panics recovered from tool handlers
+	toolPanicsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_tool_panics_total",
+		Help: "Panics recovered from tool handlers, by tool.",
+	}, []string{"tool"})
+
+	// toolResultsTruncatedTotal counts tool results cut down to the result
+	// size limit
+	toolResultsTruncatedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_tool_results_truncated_total",
+		Help: "Tool results truncated for exceeding the result size limit, by tool.",
+	}, []string{"tool"})
+
+	// toolCallsRejectedTotal counts tool calls rejected for exceeding the
+	// per-session concurrency limit
+	toolCallsRejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_tool_calls_rejected_total",
+		Help: "Tool calls rejected because their session was at its concurrency limit, by tool.",
+	}, []string{"tool"})
+)
@@ -0,0 +1,91 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// durationCount returns how many calls to tool mcp_tool_call_duration_seconds
+// has observed
+func durationCount(t *testing.T, tool string) uint64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "mcp_tool_call_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "tool" && label.GetValue() == tool {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestToolMetrics(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	server := NewSessionServer(nil)
+	AddTool(server, &mcp.Tool{Name: "metrics_fail"}, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+		return nil, errors.New("backend down")
+	})
+	AddTool(server, &mcp.Tool{Name: "metrics_tool_error"}, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+		return &mcp.CallToolResultFor[any]{IsError: true, Content: []mcp.Content{Text("not allowed")}}, nil
+	})
+	AddTool(server, &mcp.Tool{Name: "metrics_panic"}, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+		panic("boom")
+	})
+	session := connect(t, server)
+
+	tests := []struct {
+		tool    string
+		calls   int
+		outcome string
+	}{
+		{tool: "hello_world", calls: 2, outcome: "success"},
+		{tool: "metrics_fail", calls: 1, outcome: "error"},
+		{tool: "metrics_tool_error", calls: 1, outcome: "error"},
+		{tool: "metrics_panic", calls: 1, outcome: "error"},
+	}
+	for _, tt := range tests {
+		calls := testutil.ToFloat64(toolCallsTotal.WithLabelValues(tt.tool, tt.outcome))
+		durations := durationCount(t, tt.tool)
+		for range tt.calls {
+			callTool(t, session, tt.tool, map[string]any{})
+		}
+		if got := testutil.ToFloat64(toolCallsTotal.WithLabelValues(tt.tool, tt.outcome)) - calls; got != float64(tt.calls) {
+			t.Errorf("%s calls counted with outcome %s = %v, want %d", tt.tool, tt.outcome, got, tt.calls)
+		}
+		if got := durationCount(t, tt.tool) - durations; got != uint64(tt.calls) {
+			t.Errorf("%s call durations observed = %d, want %d", tt.tool, got, tt.calls)
+		}
+	}
+}
+
+func TestToolMetricsUnknownTool(t *testing.T) {
+	session := connect(t, NewSessionServer(nil))
+	before := testutil.CollectAndCount(toolCallsTotal)
+
+	// Names from requests never become label values
+	if _, err := session.CallTool(t.Context(), &mcp.CallToolParams{Name: "no_such_tool", Arguments: map[string]any{}}); err == nil {
+		t.Fatal("calling an unknown tool succeeded")
+	}
+	if got := testutil.CollectAndCount(toolCallsTotal); got != before {
+		t.Errorf("tool call series = %d after an unknown tool was called, want %d", got, before)
+	}
+}
@@ -0,0 +1,73 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+)
+
+// SessionNotFoundPolicy decides what happens when a tool finds its session
+// is no longer in the store, e.g. because it was evicted or expired between
+// requests
+type SessionNotFoundPolicy string
+
+const (
+	// SessionNotFoundReconnect fails the call with a tool error telling the
+	// client its session has expired and it should reconnect
+	SessionNotFoundReconnect SessionNotFoundPolicy = "reconnect"
+
+	// SessionNotFoundRecreate writes a fresh record for the session and runs
+	// the call again. It needs a store given to EnableSessionRecreation.
+	SessionNotFoundRecreate SessionNotFoundPolicy = "recreate"
+)
+
+// EnableSessionRecreation gives the server a store to write fresh session
+// records to under SessionNotFoundRecreate. Without one, missing sessions
+// are handled as under SessionNotFoundReconnect. It must be called before
+// the server starts handling requests.
+func (s *SessionServer) EnableSessionRecreation(store storage.SessionImporter) {
+	s.recreateStore = store
+}
+
+// withSessionNotFound handles a handler error saying the calling session is
+// missing from the store, anything matching storage.ErrNotFound, according to
+// the server's policy. Other results are returned as they are, including
+// other fs.ErrNotExist errors such as a plugin command that doesn't exist.
+func withSessionNotFound[In, Out any](name string, s *SessionServer, h mcp.ToolHandlerFor[In, Out]) mcp.ToolHandlerFor[In, Out] {
+	return func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[In]) (*mcp.CallToolResultFor[Out], error) {
+		res, err := h(ctx, ss, params)
+		if !errors.Is(err, storage.ErrNotFound) {
+			return res, err
+		}
+
+		if s.notFound == SessionNotFoundRecreate && s.recreateStore != nil {
+			recreateErr := recreateSession(ctx, s.recreateStore, ss.ID())
+			if recreateErr == nil {
+				log.Printf("Recreated missing session %s for tool %q", ss.ID(), name)
+				res, err = h(ctx, ss, params)
+				if !errors.Is(err, storage.ErrNotFound) {
+					return res, err
+				}
+			} else {
+				log.Printf("Failed to recreate missing session %s for tool %q: %v", ss.ID(), name, recreateErr)
+			}
+		}
+		return nil, fmt.Errorf("session %s has expired, please reconnect to start a new session", ss.ID())
+	}
+}
+
+// recreateSession writes a fresh record for sessionID with the store's TTL,
+// the record a new session starts with
+func recreateSession(ctx context.Context, store storage.SessionImporter, sessionID string) error {
+	data, err := json.Marshal(storage.SessionData{SessionID: sessionID, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	return store.Import(ctx, sessionID, data, 0)
+}
@@ -0,0 +1,110 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+)
+
+// recordingImporter records the sessions imported into it
+type recordingImporter struct {
+	mu       sync.Mutex
+	imported map[string]storage.SessionData
+	err      error // Returned by Import when set
+}
+
+func (r *recordingImporter) Import(ctx context.Context, sessionID string, data []byte, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	var session storage.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return err
+	}
+	if r.imported == nil {
+		r.imported = make(map[string]storage.SessionData)
+	}
+	r.imported[sessionID] = session
+	return nil
+}
+
+func (r *recordingImporter) has(sessionID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.imported[sessionID]
+	return ok
+}
+
+func TestSessionNotFound(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	missing := fmt.Errorf("failed to load session: %w", storage.ErrNotFound)
+	tests := []struct {
+		name       string
+		policy     SessionNotFoundPolicy
+		importer   *recordingImporter // Passed to EnableSessionRecreation if set
+		err        error              // Returned by the tool until its session is recreated
+		stillGone  bool               // Whether the tool keeps failing after a recreate
+		want       string
+		wantCalls  int
+		wantImport bool
+	}{
+		{name: "default", err: missing, want: "has expired, please reconnect to start a new session", wantCalls: 1},
+		{name: "reconnect", policy: SessionNotFoundReconnect, err: missing, want: "has expired, please reconnect to start a new session", wantCalls: 1},
+		{name: "recreate", policy: SessionNotFoundRecreate, importer: &recordingImporter{}, err: missing, want: "found", wantCalls: 2, wantImport: true},
+		{name: "recreate still missing", policy: SessionNotFoundRecreate, importer: &recordingImporter{}, err: missing, stillGone: true, want: "has expired, please reconnect", wantCalls: 2, wantImport: true},
+		{name: "recreate failed", policy: SessionNotFoundRecreate, importer: &recordingImporter{err: storage.ErrReadOnly}, err: missing, want: "has expired, please reconnect", wantCalls: 1},
+		{name: "recreate without a store", policy: SessionNotFoundRecreate, err: missing, want: "has expired, please reconnect", wantCalls: 1},
+		{name: "unavailable", policy: SessionNotFoundRecreate, importer: &recordingImporter{}, err: fmt.Errorf("failed to load session: %w", storage.ErrUnavailable), want: storage.ErrUnavailable.Error(), wantCalls: 1},
+		{name: "other not exist error", policy: SessionNotFoundRecreate, importer: &recordingImporter{}, err: fs.ErrNotExist, want: fs.ErrNotExist.Error(), wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewSessionServer(&SessionServerOptions{SessionNotFound: tt.policy})
+			if tt.importer != nil {
+				server.EnableSessionRecreation(tt.importer)
+			}
+			var mu sync.Mutex
+			calls := 0
+			AddTool(server, &mcp.Tool{Name: "lookup"}, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				if tt.importer == nil || !tt.importer.has(ss.ID()) || tt.stillGone {
+					return nil, tt.err
+				}
+				return &mcp.CallToolResultFor[any]{Content: []mcp.Content{Text("found")}}, nil
+			})
+			session := connect(t, server)
+
+			res := callTool(t, session, "lookup", map[string]any{})
+			if got := resultText(res); !strings.Contains(got, tt.want) {
+				t.Errorf("lookup = %q, want it to contain %q", got, tt.want)
+			}
+			if wantError := tt.want != "found"; res.IsError != wantError {
+				t.Errorf("lookup error = %v, want %v", res.IsError, wantError)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("tool handler ran %d times, want %d", calls, tt.wantCalls)
+			}
+			// In-memory sessions have no ID
+			if imported := tt.importer != nil && tt.importer.has(""); imported != tt.wantImport {
+				t.Errorf("session recreated = %v, want %v", imported, tt.wantImport)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package mcpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// pluginOutputLimit caps how much output is read from a plugin command or
+// HTTP endpoint, so a misbehaving plugin can't exhaust memory
+const pluginOutputLimit = 8 << 20
+
+// pluginStderrLimit caps how much of a failed command's stderr is returned
+// to the client
+const pluginStderrLimit = 4 << 10
+
+// pluginToolName matches the tool names plugins may use
+var pluginToolName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ToolDescriptor declares a tool loaded from a plugins directory. Calls run
+// a command or are proxied to an HTTP endpoint, and exactly one of Command
+// and HTTP must be set. Either way the call's arguments are sent as a JSON
+// object and the output is returned as the tool's text result.
+type ToolDescriptor struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty"` // JSON Schema for the arguments, any object if empty
+	Timeout     string          `json:"timeout,omitempty"`      // Overrides the server's tool timeout, e.g. "10s"
+
+	Command *CommandTool `json:"command,omitempty"`
+	HTTP    *HTTPTool    `json:"http,omitempty"`
+}
+
+// CommandTool runs a command for each call. The arguments are written to its
+// stdin and its stdout is the result. A non-zero exit status is returned as a
+// tool error including the start of stderr. The command gets PATH and Env as
+// its environment, not the server's, so the server's secrets aren't passed on.
+type CommandTool struct {
+	Path string            `json:"path"`
+	Args []string          `json:"args,omitempty"`
+	Dir  string            `json:"dir,omitempty"`
+	Env  map[string]string `json:"env,omitempty"`
+}
+
+// HTTPTool sends each call's arguments to an endpoint as the request body.
+// A 2xx response body is the result; any other status is returned as a tool
+// error.
+type HTTPTool struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"` // Default: POST
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// pluginTool is a validated descriptor, ready to register
+type pluginTool struct {
+	descriptor ToolDescriptor
+	schema     *jsonschema.Schema
+	timeout    time.Duration // 0 uses the server's tool timeout
+	file       string
+}
+
+// loadPluginTools reads and validates the descriptors in the *.json files
+// of dir, in file name order. Invalid descriptors, and descriptors reusing a
+// name already loaded, are skipped and returned as errors naming their file.
+// err is only set if the directory itself can't be read.
+func loadPluginTools(dir string) (tools []pluginTool, skipped []error, err error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, nil, fmt.Errorf("failed to read plugins directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read plugins directory: %w", err)
+	}
+	sort.Strings(paths)
+
+	seen := make(map[string]string)
+	for _, path := range paths {
+		file := filepath.Base(path)
+		tool, err := readPluginTool(path)
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+		if other, ok := seen[tool.descriptor.Name]; ok {
+			skipped = append(skipped, fmt.Errorf("%s: tool %q is already defined in %s", file, tool.descriptor.Name, other))
+			continue
+		}
+		seen[tool.descriptor.Name] = file
+		tool.file = file
+		tools = append(tools, tool)
+	}
+	return tools, skipped, nil
+}
+
+// readPluginTool reads and validates a single descriptor file
+func readPluginTool(path string) (pluginTool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return pluginTool{}, err
+	}
+	var descriptor ToolDescriptor
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&descriptor); err != nil {
+		return pluginTool{}, fmt.Errorf("invalid descriptor: %w", err)
+	}
+	return validateDescriptor(descriptor)
+}
+
+// validateDescriptor checks a descriptor and resolves its schema and timeout
+func validateDescriptor(d ToolDescriptor) (pluginTool, error) {
+	if !pluginToolName.MatchString(d.Name) {
+		return pluginTool{}, fmt.Errorf("invalid tool name %q, only 1-64 letters, digits, '-' and '_' are allowed", d.Name)
+	}
+
+	switch {
+	case d.Command == nil && d.HTTP == nil:
+		return pluginTool{}, fmt.Errorf("tool %q needs a command or an http endpoint", d.Name)
+	case d.Command != nil && d.HTTP != nil:
+		return pluginTool{}, fmt.Errorf("tool %q can't have both a command and an http endpoint", d.Name)
+	case d.Command != nil:
+		if d.Command.Path == "" {
+			return pluginTool{}, fmt.Errorf("tool %q has no command path", d.Name)
+		}
+	case d.HTTP != nil:
+		u, err := url.Parse(d.HTTP.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return pluginTool{}, fmt.Errorf("tool %q needs an absolute http or https URL, got %q", d.Name, d.HTTP.URL)
+		}
+		if d.HTTP.Method == "" {
+			d.HTTP.Method = http.MethodPost
+		}
+		d.HTTP.Method = strings.ToUpper(d.HTTP.Method)
+	}
+
+	tool := pluginTool{descriptor: d}
+	if d.Timeout != "" {
+		timeout, err := time.ParseDuration(d.Timeout)
+		if err != nil || timeout <= 0 {
+			return pluginTool{}, fmt.Errorf("tool %q has an invalid timeout %q", d.Name, d.Timeout)
+		}
+		tool.timeout = timeout
+	}
+
+	tool.schema = &jsonschema.Schema{Type: "object"}
+	if len(d.InputSchema) > 0 {
+		tool.schema = &jsonschema.Schema{}
+		if err := json.Unmarshal(d.InputSchema, tool.schema); err != nil {
+			return pluginTool{}, fmt.Errorf("tool %q has an invalid input schema: %w", d.Name, err)
+		}
+		if tool.schema.Type != "object" {
+			return pluginTool{}, fmt.Errorf("tool %q input schema must have type \"object\"", d.Name)
+		}
+	}
+	// mcp.AddTool panics on a schema it can't resolve, so check it first
+	if _, err := newArgSchema(tool.schema); err != nil {
+		return pluginTool{}, fmt.Errorf("tool %q has an invalid input schema: %w", d.Name, err)
+	}
+	return tool, nil
+}
+
+// LoadPlugins registers the tools described in dir, replacing the plugin
+// tools registered by the previous call, so it can be called again to
+// reload the directory. Tools whose files were removed are unregistered.
+// Invalid descriptors, and descriptors that would replace a built-in tool,
+// are skipped with a logged error. It returns the number of tools loaded. If
+// the directory can't be read, the registered tools are left as they are.
+func (s *SessionServer) LoadPlugins(dir string) (int, error) {
+	s.pluginsMu.Lock()
+	defer s.pluginsMu.Unlock()
+
+	tools, skipped, err := loadPluginTools(dir)
+	if err != nil {
+		return 0, err
+	}
+	for _, err := range skipped {
+		log.Printf("Skipping plugin tool: %v", err)
+	}
+
+	loaded := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		name := tool.descriptor.Name
+		if !s.toolAllowed(name) {
+			continue
+		}
+		s.toolsMu.RLock()
+		_, registered := s.tools[name]
+		s.toolsMu.RUnlock()
+		if registered && !s.pluginTools[name] {
+			log.Printf("Skipping plugin tool: %s: tool %q is built in", tool.file, name)
+			continue
+		}
+
+		var opts []ToolOption
+		if tool.timeout > 0 {
+			opts = append(opts, WithTimeout(tool.timeout))
+		}
+		AddTool(s, &mcp.Tool{
+			Name:        name,
+			Description: tool.descriptor.Description,
+			InputSchema: tool.schema,
+		}, pluginHandler(tool.descriptor), opts...)
+		loaded[name] = true
+	}
+
+	var removed []string
+	for name := range s.pluginTools {
+		if !loaded[name] {
+			removed = append(removed, name)
+		}
+	}
+	if len(removed) > 0 {
+		s.RemoveTools(removed...)
+	}
+	s.pluginTools = loaded
+	return len(loaded), nil
+}
+
+// pluginHandler returns the handler that runs a descriptor's command or
+// calls its endpoint
+func pluginHandler(d ToolDescriptor) mcp.ToolHandlerFor[map[string]any, any] {
+	return func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResultFor[any], error) {
+		args := params.Arguments
+		if args == nil {
+			args = map[string]any{}
+		}
+		input, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode arguments: %w", err)
+		}
+
+		var output string
+		if d.Command != nil {
+			output, err = runPluginCommand(ctx, d.Command, input)
+		} else {
+			output, err = callPluginEndpoint(ctx, d.HTTP, input)
+		}
+
+		var toolErr *pluginToolError
+		if errors.As(err, &toolErr) {
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{Text(toolErr.Error())},
+				IsError: true,
+			}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.CallToolResultFor[any]{
+			Content: []mcp.Content{Text(output)},
+		}, nil
+	}
+}
+
+// pluginToolError is a failure reported by the plugin itself, which is
+// returned to the client as a tool error
+type pluginToolError struct {
+	msg string
+}
+
+func (e *pluginToolError) Error() string {
+	return e.msg
+}
+
+// runPluginCommand runs a command tool with input on stdin
+func runPluginCommand(ctx context.Context, c *CommandTool, input []byte) (string, error) {
+	cmd := exec.CommandContext(ctx, c.Path, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH")}
+	for key, value := range c.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.Stdin = bytes.NewReader(input)
+	stdout := &limitedBuffer{limit: pluginOutputLimit}
+	stderr := &limitedBuffer{limit: pluginStderrLimit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		msg := fmt.Sprintf("command exited with status %d", exitErr.ExitCode())
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			msg += ": " + detail
+		}
+		return "", &pluginToolError{msg}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to run plugin command: %w", err)
+	}
+	if stdout.truncated {
+		return "", &pluginToolError{fmt.Sprintf("command output is larger than %d bytes", pluginOutputLimit)}
+	}
+	return stdout.String(), nil
+}
+
+// callPluginEndpoint sends input to an HTTP tool's endpoint
+func callPluginEndpoint(ctx context.Context, h *HTTPTool, input []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, h.Method, h.URL, bytes.NewReader(input))
+	if err != nil {
+		return "", fmt.Errorf("failed to create plugin request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range h.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("plugin endpoint request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, pluginOutputLimit+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read plugin endpoint response: %w", err)
+	}
+	if len(body) > pluginOutputLimit {
+		return "", &pluginToolError{fmt.Sprintf("endpoint response is larger than %d bytes", pluginOutputLimit)}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := fmt.Sprintf("endpoint returned %s", resp.Status)
+		if detail := strings.TrimSpace(string(body[:min(len(body), pluginStderrLimit)])); detail != "" {
+			msg += ": " + detail
+		}
+		return "", &pluginToolError{msg}
+	}
+	return string(body), nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it, noting whether
+// anything was dropped. The buffer isn't embedded, since its ReadFrom would
+// let io.Copy fill it past the limit.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := b.limit - b.buf.Len(); n > room {
+		b.truncated = true
+		p = p[:max(room, 0)]
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,224 @@
+package mcpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeDescriptor writes a plugin tool descriptor to file in dir
+func writeDescriptor(t *testing.T, dir, file string, descriptor ToolDescriptor) {
+	t.Helper()
+	data, err := json.Marshal(descriptor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, file), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// commandTool returns a descriptor for a tool running a shell script
+func commandTool(name, script string) ToolDescriptor {
+	return ToolDescriptor{Name: name, Command: &CommandTool{Path: "sh", Args: []string{"-c", script}}}
+}
+
+// toolNames returns the names of the tools registered on server
+func toolNames(server *SessionServer) []string {
+	var names []string
+	for _, tool := range server.Tools() {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestLoadPluginsReload(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	dir := t.TempDir()
+	writeDescriptor(t, dir, "a.json", commandTool("plugin_a", "cat"))
+	writeDescriptor(t, dir, "b.json", commandTool("plugin_b", "cat"))
+	server := NewSessionServer(nil)
+
+	loaded, err := server.LoadPlugins(dir)
+	if err != nil || loaded != 2 {
+		t.Fatalf("LoadPlugins() = %d, %v, want 2 tools", loaded, err)
+	}
+	if names := toolNames(server); !slices.Contains(names, "plugin_a") || !slices.Contains(names, "plugin_b") {
+		t.Errorf("tools = %v, want both plugins registered", names)
+	}
+
+	// Removed files unregister their tools and new files add theirs
+	if err := os.Remove(filepath.Join(dir, "b.json")); err != nil {
+		t.Fatal(err)
+	}
+	writeDescriptor(t, dir, "c.json", commandTool("plugin_c", "cat"))
+	loaded, err = server.LoadPlugins(dir)
+	if err != nil || loaded != 2 {
+		t.Fatalf("reloading LoadPlugins() = %d, %v, want 2 tools", loaded, err)
+	}
+	names := toolNames(server)
+	if !slices.Contains(names, "plugin_a") || !slices.Contains(names, "plugin_c") || slices.Contains(names, "plugin_b") {
+		t.Errorf("tools after reload = %v, want plugin_a and plugin_c", names)
+	}
+	session := connect(t, server)
+	advertised, err := session.ListTools(t.Context(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range advertised.Tools {
+		if tool.Name == "plugin_b" {
+			t.Error("tools/list still advertises a removed plugin")
+		}
+	}
+
+	// A directory that can't be read leaves the tools as they were
+	if _, err := server.LoadPlugins(filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadPlugins() of a missing directory succeeded")
+	}
+	if names := toolNames(server); !slices.Contains(names, "plugin_a") {
+		t.Errorf("tools after a failed reload = %v, want them unchanged", names)
+	}
+}
+
+func TestLoadPluginsSkipsConflicts(t *testing.T) {
+	var logs bytes.Buffer
+	logger := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	dir := t.TempDir()
+	writeDescriptor(t, dir, "a.json", commandTool("plugin", "echo first"))
+	writeDescriptor(t, dir, "b.json", commandTool("plugin", "echo second"))
+	writeDescriptor(t, dir, "c.json", commandTool("hello_world", "echo replaced"))
+	writeDescriptor(t, dir, "d.json", ToolDescriptor{Name: "no_command"})
+	server := NewSessionServer(nil)
+
+	loaded, err := server.LoadPlugins(dir)
+	if err != nil || loaded != 1 {
+		t.Fatalf("LoadPlugins() = %d, %v, want only the first plugin loaded", loaded, err)
+	}
+	for _, want := range []string{
+		`b.json: tool "plugin" is already defined in a.json`,
+		`c.json: tool "hello_world" is built in`,
+		`d.json: tool "no_command" needs a command or an http endpoint`,
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log = %q, want it to contain %q", logs.String(), want)
+		}
+	}
+
+	session := connect(t, server)
+	if got := strings.TrimSpace(resultText(callTool(t, session, "plugin", map[string]any{}))); got != "first" {
+		t.Errorf("plugin = %q, want the first descriptor's tool", got)
+	}
+	if got := resultText(callTool(t, session, "hello_world", map[string]any{})); got != "Hello world!" {
+		t.Errorf("hello_world = %q, want the built-in tool", got)
+	}
+}
+
+func TestPluginCommand(t *testing.T) {
+	t.Setenv("PLUGIN_TEST_SECRET", "server-secret")
+	dir := t.TempDir()
+	writeDescriptor(t, dir, "echo.json", ToolDescriptor{Name: "echo_args", Command: &CommandTool{Path: "cat"}})
+	writeDescriptor(t, dir, "fail.json", commandTool("fail", "echo partial; echo something broke >&2; exit 3"))
+	writeDescriptor(t, dir, "large.json", commandTool("large", fmt.Sprintf("head -c %d /dev/zero", pluginOutputLimit+1)))
+	writeDescriptor(t, dir, "limit.json", commandTool("at_limit", fmt.Sprintf("head -c %d /dev/zero | tr '\\0' x", pluginOutputLimit)))
+	env := commandTool("env", "env")
+	env.Command.Env = map[string]string{"PLUGIN_SETTING": "on"}
+	writeDescriptor(t, dir, "env.json", env)
+
+	server := NewSessionServer(nil)
+	if _, err := server.LoadPlugins(dir); err != nil {
+		t.Fatal(err)
+	}
+	session := connect(t, server)
+
+	t.Run("arguments on stdin", func(t *testing.T) {
+		res := callTool(t, session, "echo_args", map[string]any{"word": "hi"})
+		if res.IsError || resultText(res) != `{"word":"hi"}` {
+			t.Errorf("echo_args = %q (error %v), want the arguments as JSON", resultText(res), res.IsError)
+		}
+	})
+
+	t.Run("non-zero exit", func(t *testing.T) {
+		res := callTool(t, session, "fail", map[string]any{})
+		if !res.IsError || resultText(res) != "command exited with status 3: something broke" {
+			t.Errorf("fail = %q (error %v), want a tool error with stderr", resultText(res), res.IsError)
+		}
+	})
+
+	t.Run("output limit", func(t *testing.T) {
+		res := callTool(t, session, "large", map[string]any{})
+		if !res.IsError || resultText(res) != fmt.Sprintf("command output is larger than %d bytes", pluginOutputLimit) {
+			t.Errorf("large = %.100q (error %v), want a tool error for the output size", resultText(res), res.IsError)
+		}
+		res = callTool(t, session, "at_limit", map[string]any{})
+		if res.IsError || len(resultText(res)) != pluginOutputLimit {
+			t.Errorf("at_limit returned %d bytes (error %v), want all %d", len(resultText(res)), res.IsError, pluginOutputLimit)
+		}
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		res := callTool(t, session, "env", map[string]any{})
+		output := resultText(res)
+		if strings.Contains(output, "PLUGIN_TEST_SECRET") || strings.Contains(output, "server-secret") {
+			t.Errorf("command environment includes the server's: %q", output)
+		}
+		if !strings.Contains(output, "PLUGIN_SETTING=on") || !strings.Contains(output, "PATH=") {
+			t.Errorf("command environment = %q, want PATH and the descriptor's variables", output)
+		}
+	})
+}
+
+func TestPluginHTTP(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			if r.Method != http.MethodPut || r.Header.Get("X-Plugin-Token") != "token" || r.Header.Get("Content-Type") != "application/json" {
+				http.Error(w, "unexpected request", http.StatusBadRequest)
+				return
+			}
+			io.Copy(w, r.Body)
+		case "/large":
+			w.Write(bytes.Repeat([]byte("x"), pluginOutputLimit+1))
+		default:
+			http.Error(w, "no such tool", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(endpoint.Close)
+
+	dir := t.TempDir()
+	writeDescriptor(t, dir, "echo.json", ToolDescriptor{Name: "http_echo", HTTP: &HTTPTool{URL: endpoint.URL + "/echo", Method: "put", Headers: map[string]string{"X-Plugin-Token": "token"}}})
+	writeDescriptor(t, dir, "missing.json", ToolDescriptor{Name: "http_missing", HTTP: &HTTPTool{URL: endpoint.URL + "/missing"}})
+	writeDescriptor(t, dir, "large.json", ToolDescriptor{Name: "http_large", HTTP: &HTTPTool{URL: endpoint.URL + "/large"}})
+	server := NewSessionServer(nil)
+	if _, err := server.LoadPlugins(dir); err != nil {
+		t.Fatal(err)
+	}
+	session := connect(t, server)
+
+	res := callTool(t, session, "http_echo", map[string]any{"word": "hi"})
+	if res.IsError || resultText(res) != `{"word":"hi"}` {
+		t.Errorf("http_echo = %q (error %v), want the arguments echoed", resultText(res), res.IsError)
+	}
+	res = callTool(t, session, "http_missing", map[string]any{})
+	if !res.IsError || resultText(res) != "endpoint returned 404 Not Found: no such tool" {
+		t.Errorf("http_missing = %q (error %v), want a tool error with the status and body", resultText(res), res.IsError)
+	}
+	res = callTool(t, session, "http_large", map[string]any{})
+	if !res.IsError || resultText(res) != fmt.Sprintf("endpoint response is larger than %d bytes", pluginOutputLimit) {
+		t.Errorf("http_large = %.100q (error %v), want a tool error for the response size", resultText(res), res.IsError)
+	}
+}
@@ -0,0 +1,115 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// withResultLimit caps the serialized size of a handler's results at limit
+// bytes. Larger results are replaced by a truncated copy ending with a notice
+// saying so, and counted in mcp_tool_results_truncated_total. A limit of 0 or
+// less disables the cap.
+func withResultLimit[In, Out any](name string, limit int, h mcp.ToolHandlerFor[In, Out]) mcp.ToolHandlerFor[In, Out] {
+	if limit <= 0 {
+		return h
+	}
+
+	return func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[In]) (*mcp.CallToolResultFor[Out], error) {
+		res, err := h(ctx, ss, params)
+		if err != nil || res == nil {
+			return res, err
+		}
+
+		// A result that can't be serialized is left for the SDK to report
+		data, err := json.Marshal(res)
+		if err != nil || len(data) <= limit {
+			return res, nil
+		}
+
+		toolResultsTruncatedTotal.WithLabelValues(name).Inc()
+		log.Printf("Truncated result of tool %q (session %s): %d bytes, limit is %d", name, ss.ID(), len(data), limit)
+		return truncateResult(res, name, len(data), limit), nil
+	}
+}
+
+// truncateResult keeps as much of a result's content, in order, as fits in
+// limit bytes along with a notice that the result was truncated. The first
+// text content that doesn't fit is cut short; anything after it, and the
+// structured content, is dropped. If the notice alone exceeds the limit,
+// only the notice is returned.
+func truncateResult[Out any](res *mcp.CallToolResultFor[Out], name string, size, limit int) *mcp.CallToolResultFor[Out] {
+	notice := Text(fmt.Sprintf("[Result truncated: tool %q returned %d bytes, more than the %d byte limit. The content shown is incomplete.]", name, size, limit))
+	truncated := &mcp.CallToolResultFor[Out]{
+		Meta:    res.Meta,
+		Content: []mcp.Content{notice},
+		IsError: res.IsError,
+	}
+
+	// Each kept item adds its own encoding and a separating comma
+	used := encodedSize(truncated)
+	var kept []mcp.Content
+	for _, content := range res.Content {
+		room := limit - used - 1
+		if n := encodedSize(content); n <= room {
+			kept = append(kept, content)
+			used += n + 1
+			continue
+		}
+		if text, ok := content.(*mcp.TextContent); ok {
+			if cut := truncateText(text, room); cut != nil {
+				kept = append(kept, cut)
+			}
+		}
+		break
+	}
+
+	truncated.Content = append(kept, notice)
+	return truncated
+}
+
+// truncateText returns the longest prefix of text whose encoding fits in
+// room bytes, cut on a character boundary, or nil if none does
+func truncateText(text *mcp.TextContent, room int) *mcp.TextContent {
+	// The encoding is never shorter than the text itself, so the answer is
+	// at most room bytes long
+	cut := *text
+	lo, hi := 0, min(len(text.Text), room)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		cut.Text = text.Text[:runeStart(text.Text, mid)]
+		if encodedSize(&cut) <= room {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	n := runeStart(text.Text, lo)
+	if n == 0 {
+		return nil
+	}
+	cut.Text = text.Text[:n]
+	return &cut
+}
+
+// runeStart moves n back to the start of the character it falls in
+func runeStart(s string, n int) int {
+	for n > 0 && n < len(s) && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return n
+}
+
+// encodedSize returns the length of v's JSON encoding
+func encodedSize(v any) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
@@ -0,0 +1,95 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type sizeArgs struct {
+	Text string `json:"text"`
+}
+
+// sized is a tool handler returning its text argument as text content,
+// followed by an image and structured content
+func sized(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[sizeArgs]) (*mcp.CallToolResultFor[any], error) {
+	return &mcp.CallToolResultFor[any]{
+		Content: []mcp.Content{
+			Text(params.Arguments.Text),
+			&mcp.ImageContent{MIMEType: "image/png", Data: []byte("png")},
+		},
+		StructuredContent: map[string]string{"text": params.Arguments.Text},
+	}, nil
+}
+
+func TestResultLimit(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	const limit = 400
+	server := NewSessionServer(&SessionServerOptions{MaxResultBytes: limit})
+	AddTool(server, &mcp.Tool{Name: "sized"}, sized)
+	AddTool(server, &mcp.Tool{Name: "unlimited"}, sized, WithMaxResultBytes(0))
+	session := connect(t, server)
+	truncated := testutil.ToFloat64(toolResultsTruncatedTotal.WithLabelValues("sized"))
+
+	t.Run("within limit", func(t *testing.T) {
+		res := callTool(t, session, "sized", sizeArgs{Text: "short"})
+		if len(res.Content) != 2 || resultText(res) != "short" || res.StructuredContent == nil {
+			t.Errorf("sized = %+v, want the whole result", res)
+		}
+	})
+
+	t.Run("over limit", func(t *testing.T) {
+		text := strings.Repeat("héllo ", 200)
+		res := callTool(t, session, "sized", sizeArgs{Text: text})
+		if res.StructuredContent != nil {
+			t.Error("truncated result kept its structured content")
+		}
+		if len(res.Content) != 2 {
+			t.Fatalf("truncated result has %d content items, want the cut text and the notice", len(res.Content))
+		}
+		cut, ok := res.Content[0].(*mcp.TextContent)
+		if !ok || cut.Text == "" || !strings.HasPrefix(text, cut.Text) || !utf8.ValidString(cut.Text) {
+			t.Errorf("first content = %+v, want a prefix of the text cut on a character boundary", res.Content[0])
+		}
+		notice, ok := res.Content[1].(*mcp.TextContent)
+		if !ok || !strings.Contains(notice.Text, `[Result truncated: tool "sized" returned`) || !strings.Contains(notice.Text, "400 byte limit") {
+			t.Errorf("last content = %+v, want the truncation notice", res.Content[1])
+		}
+		if data, err := json.Marshal(res); err != nil || len(data) > limit {
+			t.Errorf("truncated result is %d bytes, want at most %d", len(data), limit)
+		}
+		if got := testutil.ToFloat64(toolResultsTruncatedTotal.WithLabelValues("sized")) - truncated; got != 1 {
+			t.Errorf("truncated results counted = %v, want 1", got)
+		}
+	})
+
+	t.Run("tool override", func(t *testing.T) {
+		text := strings.Repeat("x", 1000)
+		res := callTool(t, session, "unlimited", sizeArgs{Text: text})
+		if resultText(res) != text || len(res.Content) != 2 {
+			t.Errorf("unlimited returned %d bytes of text, want the whole result", len(resultText(res)))
+		}
+	})
+}
+
+func TestTruncateResultNoticeOnly(t *testing.T) {
+	// A limit too small for any content keeps just the notice
+	res := &mcp.CallToolResultFor[any]{Content: []mcp.Content{Text(strings.Repeat("x", 100))}, IsError: true}
+	truncated := truncateResult(res, "tiny", 120, 10)
+	if len(truncated.Content) != 1 || !truncated.IsError {
+		t.Fatalf("truncateResult() = %+v, want only the notice, still an error", truncated)
+	}
+	if text, ok := truncated.Content[0].(*mcp.TextContent); !ok || !strings.HasPrefix(text.Text, "[Result truncated") {
+		t.Errorf("truncateResult() content = %+v, want the notice", truncated.Content[0])
+	}
+}
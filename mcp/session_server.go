@@ -2,30 +2,159 @@ package mcpserver
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
 
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+)
+
+// Default server identity reported to clients and by server_stats
+const (
+	defaultServerName    = "mcp-go-session-example"
+	defaultServerVersion = "1.0.0"
 )
 
 type SessionServer struct {
 	MCPServer *mcp.Server
+
+	toolsMu     sync.RWMutex
+	tools       map[string]*mcp.Tool // Registered tools by name
+	toolTimeout time.Duration        // Default timeout for tool calls
+	maxResults  int                  // Default result size limit in bytes, 0 for none
+	limiter     *sessionLimiter      // Per-session tool call concurrency limit, if set
+	audit       AuditSink            // Receives a record of every tool call, if set
+	version     string               // Version reported by server_stats
+	enabled     map[string]bool      // Tools that may be registered, all if nil
+	disabled    map[string]bool      // Tools that are never registered
+	startTime   time.Time
+
+	timeouts map[string]time.Duration // Enforced timeout of each registered tool, guarded by toolsMu
+
+	notFound      SessionNotFoundPolicy   // What tools do when their session is missing
+	recreateStore storage.SessionImporter // Store fresh records are written to, if recreating
+
+	strictArgs bool                  // Whether tool arguments are checked before dispatch
+	argSchemas map[string]*argSchema // Resolved input schemas for strict checks, by tool name
+
+	pluginsMu   sync.Mutex      // Serializes plugin reloads
+	pluginTools map[string]bool // Tools registered by LoadPlugins
+}
+
+// SessionServerOptions configures a SessionServer. A nil *SessionServerOptions
+// uses the defaults.
+type SessionServerOptions struct {
+	// ToolTimeout bounds how long a tool call may run before the client gets a
+	// timeout error. Tools can override it with WithTimeout. 0 means no timeout.
+	ToolTimeout time.Duration
+
+	// MaxResultBytes caps the serialized size of tool results. Larger results
+	// are truncated and end with a notice saying so. Tools can override it
+	// with WithMaxResultBytes. 0 means no limit.
+	MaxResultBytes int
+
+	// MaxConcurrentToolCalls bounds the tool calls running at once on each
+	// session; ConcurrencyPolicy says whether calls over it wait (the
+	// default) or are rejected. 0 means no limit.
+	MaxConcurrentToolCalls int
+	ConcurrencyPolicy      ConcurrencyPolicy
+
+	// Audit receives a record of every tool call. Nil disables auditing.
+	Audit AuditSink
+
+	// Implementation overrides the identity the server reports to clients in
+	// its initialize response. An empty Name or Version keeps the default.
+	Implementation *mcp.Implementation
+
+	// EnabledTools, if set, limits the tools that are registered to those
+	// named. DisabledTools are never registered, even if enabled. Filtered
+	// tools aren't advertised, and calling one returns a "not available" error.
+	EnabledTools  []string
+	DisabledTools []string
+
+	// StrictArgs checks tool arguments against each tool's input schema
+	// before the call is dispatched. Unknown arguments, missing required
+	// ones and other schema violations are returned as a tool error saying
+	// what's wrong, rather than the SDK's generic invalid params error.
+	StrictArgs bool
+
+	// SessionNotFound decides what happens when a tool call fails because
+	// its session is missing from the store, e.g. evicted between requests.
+	// The default, SessionNotFoundReconnect, returns a tool error telling
+	// the client to reconnect.
+	SessionNotFound SessionNotFoundPolicy
 }
 
-func NewSessionServer() *SessionServer {
-	server := mcp.NewServer(&mcp.Implementation{
-		Name:    "mcp-go-session-example",
-		Version: "1.0.0",
-	}, nil)
+func NewSessionServer(opts *SessionServerOptions) *SessionServer {
+	if opts == nil {
+		opts = &SessionServerOptions{}
+	}
+
+	impl := mcp.Implementation{}
+	if opts.Implementation != nil {
+		impl = *opts.Implementation
+	}
+	if impl.Name == "" {
+		impl.Name = defaultServerName
+	}
+	if impl.Version == "" {
+		impl.Version = defaultServerVersion
+	}
+
+	server := mcp.NewServer(&impl, nil)
 
 	ss := &SessionServer{
-		MCPServer: server,
+		MCPServer:   server,
+		tools:       make(map[string]*mcp.Tool),
+		timeouts:    make(map[string]time.Duration),
+		toolTimeout: opts.ToolTimeout,
+		maxResults:  opts.MaxResultBytes,
+		limiter:     newSessionLimiter(opts.MaxConcurrentToolCalls, opts.ConcurrencyPolicy),
+		audit:       opts.Audit,
+		version:     impl.Version,
+		disabled:    toolSet(opts.DisabledTools),
+		strictArgs:  opts.StrictArgs,
+		notFound:    opts.SessionNotFound,
+		argSchemas:  make(map[string]*argSchema),
+		startTime:   time.Now(),
+	}
+	if len(opts.EnabledTools) > 0 {
+		ss.enabled = toolSet(opts.EnabledTools)
+	}
+	if ss.enabled != nil || len(ss.disabled) > 0 {
+		server.AddReceivingMiddleware(ss.rejectFilteredTools)
+	}
+	if ss.strictArgs {
+		server.AddReceivingMiddleware(ss.validateToolArgs)
 	}
 
 	// Add the hello world tool
-	mcp.AddTool(server, &mcp.Tool{
+	AddTool(ss, &mcp.Tool{
 		Name:        "hello_world",
 		Description: "A simple tool that outputs 'Hello world!'",
 	}, ss.handleHelloWorldTool)
 
+	// Add the tool catalog
+	AddTool(ss, &mcp.Tool{
+		Name:        "list_tools",
+		Description: "Lists the tools registered on this server with their input schemas",
+	}, ss.handleListToolsTool)
+
+	// Add the server stats tool, an example of typed structured output
+	AddTool(ss, &mcp.Tool{
+		Name:        "server_stats",
+		Description: "Reports this server instance's uptime, connected session count and version",
+	}, ss.handleServerStatsTool)
+
+	// Add the chart tool, an example of returning image content
+	AddTool(ss, &mcp.Tool{
+		Name:        "render_chart",
+		Description: "Draws a list of values as a bar chart and returns it as a PNG image",
+	}, ss.handleRenderChartTool)
+
 	return ss
 }
 
@@ -36,7 +165,152 @@ type HelloWorldArgs struct {
 func (s *SessionServer) handleHelloWorldTool(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[HelloWorldArgs]) (*mcp.CallToolResultFor[any], error) {
 	return &mcp.CallToolResultFor[any]{
 		Content: []mcp.Content{
-			&mcp.TextContent{Text: "Hello world!"},
+			Text("Hello world!"),
+		},
+	}, nil
+}
+
+// EnableKeepalive registers the keepalive tool, which resets the calling
+// session's TTL in store so agents can keep a session alive while idle
+func (s *SessionServer) EnableKeepalive(store storage.SessionToucher) {
+	AddTool(s, &mcp.Tool{
+		Name:        "keepalive",
+		Description: "Extends the current session's lifetime without changing it",
+	}, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[KeepaliveArgs]) (*mcp.CallToolResultFor[any], error) {
+		if err := store.Touch(ctx, ss.ID()); err != nil {
+			return nil, fmt.Errorf("failed to extend session: %w", err)
+		}
+		return &mcp.CallToolResultFor[any]{
+			Content: []mcp.Content{
+				Text("Session kept alive"),
+			},
+		}, nil
+	})
+}
+
+type KeepaliveArgs struct {
+	// No arguments needed, the calling session is extended
+}
+
+// EnableCapabilities registers the server_capabilities tool, which reports
+// the limits this server enforces so clients can stay within them. The tool
+// timeout and result size limit are this server's defaults, and each
+// registered tool's own timeout is listed when the tool is called, so tools
+// added later are included; the remaining limits are enforced outside the
+// MCP server, so the caller supplies them.
+func (s *SessionServer) EnableCapabilities(limits ServerLimits) {
+	limits.ToolTimeout = s.toolTimeout.Seconds()
+	limits.MaxToolResultBytes = s.maxResults
+
+	AddTool(s, &mcp.Tool{
+		Name:        "server_capabilities",
+		Description: "Reports the limits this server enforces on requests, tool calls and sessions",
+	}, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[ServerCapabilitiesArgs]) (*mcp.CallToolResultFor[ServerLimits], error) {
+		limits := limits
+		limits.ToolTimeouts = make(map[string]float64)
+		for name, timeout := range s.ToolTimeouts() {
+			limits.ToolTimeouts[name] = timeout.Seconds()
+		}
+
+		text, err := json.MarshalIndent(limits, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal server limits: %w", err)
+		}
+		return &mcp.CallToolResultFor[ServerLimits]{
+			Content: []mcp.Content{
+				Text(string(text)),
+			},
+			StructuredContent: limits,
+		}, nil
+	})
+}
+
+type ServerCapabilitiesArgs struct {
+	// No arguments needed, the limits are the server's configuration
+}
+
+// ServerLimits is the structured content returned by server_capabilities. A
+// limit of 0 means there is none. It only holds limits, never credentials.
+type ServerLimits struct {
+	MaxRequestBytes      int64              `json:"maxRequestBytes" jsonschema:"largest request body accepted in bytes, 0 for no limit"`
+	ToolTimeout          float64            `json:"toolTimeout" jsonschema:"seconds a tool call may run by default, 0 for no timeout"`
+	ToolTimeouts         map[string]float64 `json:"toolTimeouts" jsonschema:"seconds a call to each tool may run by tool name, 0 for no timeout"`
+	MaxToolResultBytes   int                `json:"maxToolResultBytes" jsonschema:"largest tool result sent in full by default in bytes, 0 for no limit"`
+	MaxSessionStateBytes int                `json:"maxSessionStateBytes" jsonschema:"largest serialized session state stored in bytes, 0 for no limit"`
+	SessionTTL           float64            `json:"sessionTTL" jsonschema:"seconds an idle session is kept, 0 if sessions never expire"`
+}
+
+type ListToolsArgs struct {
+	// No arguments needed, all registered tools are listed
+}
+
+// ToolInfo describes a registered tool in list_tools output
+type ToolInfo struct {
+	Name         string             `json:"name"`
+	Description  string             `json:"description,omitempty"`
+	InputSchema  *jsonschema.Schema `json:"inputSchema,omitempty"`
+	OutputSchema *jsonschema.Schema `json:"outputSchema,omitempty"`
+}
+
+// ListToolsResult is the structured content returned by list_tools
+type ListToolsResult struct {
+	Tools []ToolInfo `json:"tools"`
+}
+
+func (s *SessionServer) handleListToolsTool(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[ListToolsArgs]) (*mcp.CallToolResultFor[any], error) {
+	result := ListToolsResult{Tools: []ToolInfo{}}
+	for _, tool := range s.Tools() {
+		result.Tools = append(result.Tools, ToolInfo{
+			Name:         tool.Name,
+			Description:  tool.Description,
+			InputSchema:  tool.InputSchema,
+			OutputSchema: tool.OutputSchema,
+		})
+	}
+
+	text, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tool list: %w", err)
+	}
+
+	return &mcp.CallToolResultFor[any]{
+		Content: []mcp.Content{
+			Text(string(text)),
+		},
+		StructuredContent: result,
+	}, nil
+}
+
+type ServerStatsArgs struct {
+	// No arguments needed, stats are for this server instance
+}
+
+// ServerStats is the structured content returned by server_stats. Its output
+// schema is inferred from the struct and advertised in tools/list.
+type ServerStats struct {
+	Uptime       int64  `json:"uptime" jsonschema:"seconds since this server instance started"`
+	SessionCount int    `json:"sessionCount" jsonschema:"sessions connected to this server instance"`
+	Version      string `json:"version" jsonschema:"server version"`
+}
+
+func (s *SessionServer) handleServerStatsTool(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[ServerStatsArgs]) (*mcp.CallToolResultFor[ServerStats], error) {
+	stats := ServerStats{
+		Uptime:  int64(time.Since(s.startTime) / time.Second),
+		Version: s.version,
+	}
+	for range s.MCPServer.Sessions() {
+		stats.SessionCount++
+	}
+
+	text, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal server stats: %w", err)
+	}
+
+	return &mcp.CallToolResultFor[ServerStats]{
+		Content: []mcp.Content{
+			Text(string(text)),
 		},
+		StructuredContent: stats,
 	}, nil
 }
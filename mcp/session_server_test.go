@@ -0,0 +1,262 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type echoArgs struct {
+	Message string `json:"message"`
+}
+
+// echo is a tool handler returning its message argument
+func echo(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[echoArgs]) (*mcp.CallToolResultFor[any], error) {
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: params.Arguments.Message}}}, nil
+}
+
+func TestListTools(t *testing.T) {
+	server := NewSessionServer(nil)
+	AddTool(server, &mcp.Tool{Name: "echo", Description: "Echoes its message"}, echo)
+	session := connect(t, server)
+
+	res := callTool(t, session, "list_tools", map[string]any{})
+	if res.IsError {
+		t.Fatalf("list_tools failed: %s", resultText(res))
+	}
+	var listed ListToolsResult
+	if err := json.Unmarshal([]byte(resultText(res)), &listed); err != nil {
+		t.Fatalf("list_tools returned %q: %v", resultText(res), err)
+	}
+
+	var names []string
+	var echoTool ToolInfo
+	for _, tool := range listed.Tools {
+		names = append(names, tool.Name)
+		if tool.Name == "echo" {
+			echoTool = tool
+		}
+	}
+	if !slices.IsSorted(names) || !slices.Contains(names, "list_tools") || !slices.Contains(names, "hello_world") {
+		t.Errorf("list_tools listed %v, want the registered tools sorted by name", names)
+	}
+	if echoTool.Description != "Echoes its message" || echoTool.InputSchema == nil || echoTool.InputSchema.Properties["message"] == nil {
+		t.Errorf("list_tools listed echo as %+v, want its description and input schema", echoTool)
+	}
+
+	// The listing matches what the SDK advertises
+	advertised, err := session.ListTools(t.Context(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(advertised.Tools) != len(listed.Tools) {
+		t.Errorf("list_tools listed %d tools, tools/list has %d", len(listed.Tools), len(advertised.Tools))
+	}
+
+	// Removed tools are dropped from the listing
+	server.RemoveTools("echo")
+	res = callTool(t, session, "list_tools", map[string]any{})
+	if err := json.Unmarshal([]byte(resultText(res)), &listed); err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range listed.Tools {
+		if tool.Name == "echo" {
+			t.Error("list_tools still lists a removed tool")
+		}
+	}
+}
+
+func TestServerStats(t *testing.T) {
+	server := NewSessionServer(nil)
+	session := connect(t, server)
+
+	res := callTool(t, session, "server_stats", map[string]any{})
+	if res.IsError {
+		t.Fatalf("server_stats failed: %s", resultText(res))
+	}
+	var stats ServerStats
+	if err := json.Unmarshal([]byte(resultText(res)), &stats); err != nil {
+		t.Fatalf("server_stats returned %q: %v", resultText(res), err)
+	}
+	if stats.Version != defaultServerVersion || stats.SessionCount != 1 || stats.Uptime < 0 {
+		t.Errorf("server_stats = %+v, want version %s and the one connected session", stats, defaultServerVersion)
+	}
+
+	// The structured content matches the text, and its schema is advertised
+	structured, err := json.Marshal(res.StructuredContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var structuredStats ServerStats
+	if err := json.Unmarshal(structured, &structuredStats); err != nil || structuredStats != stats {
+		t.Errorf("server_stats structured content = %s, want %+v", structured, stats)
+	}
+	tools, err := session.ListTools(t.Context(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range tools.Tools {
+		if tool.Name == "server_stats" {
+			if tool.OutputSchema == nil || tool.OutputSchema.Properties["sessionCount"] == nil {
+				t.Errorf("server_stats output schema = %+v, want it inferred from ServerStats", tool.OutputSchema)
+			}
+			return
+		}
+	}
+	t.Error("server_stats isn't advertised")
+}
+
+// serverInfo connects a client to server and returns the implementation the
+// server reported in its initialize response
+func serverInfo(t *testing.T, server *SessionServer) *mcp.Implementation {
+	t.Helper()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.MCPServer.Connect(t.Context(), serverTransport)
+	if err != nil {
+		t.Fatalf("failed to connect server: %v", err)
+	}
+	t.Cleanup(func() { serverSession.Close() })
+
+	var info *mcp.Implementation
+	client := mcp.NewClient(&mcp.Implementation{Name: "mcp-test", Version: "0.0.0"}, nil)
+	client.AddSendingMiddleware(func(next mcp.MethodHandler[*mcp.ClientSession]) mcp.MethodHandler[*mcp.ClientSession] {
+		return func(ctx context.Context, cs *mcp.ClientSession, method string, params mcp.Params) (mcp.Result, error) {
+			res, err := next(ctx, cs, method, params)
+			if initialized, ok := res.(*mcp.InitializeResult); ok {
+				info = initialized.ServerInfo
+			}
+			return res, err
+		}
+	})
+	session, err := client.Connect(t.Context(), clientTransport)
+	if err != nil {
+		t.Fatalf("failed to connect client: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	if info == nil {
+		t.Fatal("initialize response had no server info")
+	}
+	return info
+}
+
+func TestImplementation(t *testing.T) {
+	tests := []struct {
+		name string
+		impl *mcp.Implementation
+		want mcp.Implementation
+	}{
+		{name: "default", want: mcp.Implementation{Name: defaultServerName, Version: defaultServerVersion}},
+		{
+			name: "custom",
+			impl: &mcp.Implementation{Name: "acme-tools", Title: "Acme Tools", Version: "2.3.0"},
+			want: mcp.Implementation{Name: "acme-tools", Title: "Acme Tools", Version: "2.3.0"},
+		},
+		{
+			name: "name only",
+			impl: &mcp.Implementation{Name: "acme-tools"},
+			want: mcp.Implementation{Name: "acme-tools", Version: defaultServerVersion},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewSessionServer(&SessionServerOptions{Implementation: tt.impl})
+			if got := serverInfo(t, server); *got != tt.want {
+				t.Errorf("server info = %+v, want %+v", *got, tt.want)
+			}
+
+			// server_stats reports the same version
+			res := callTool(t, connect(t, server), "server_stats", map[string]any{})
+			var stats ServerStats
+			if err := json.Unmarshal([]byte(resultText(res)), &stats); err != nil {
+				t.Fatal(err)
+			}
+			if stats.Version != tt.want.Version {
+				t.Errorf("server_stats version = %q, want %q", stats.Version, tt.want.Version)
+			}
+		})
+	}
+
+	// The options aren't modified
+	impl := &mcp.Implementation{Name: "acme-tools"}
+	NewSessionServer(&SessionServerOptions{Implementation: impl})
+	if impl.Version != "" {
+		t.Errorf("NewSessionServer() set the caller's Implementation.Version to %q", impl.Version)
+	}
+}
+
+// serverCapabilities calls server_capabilities, returning the limits it
+// reported
+func serverCapabilities(t *testing.T, session *mcp.ClientSession) ServerLimits {
+	t.Helper()
+	res := callTool(t, session, "server_capabilities", map[string]any{})
+	if res.IsError {
+		t.Fatalf("server_capabilities failed: %s", resultText(res))
+	}
+	var limits ServerLimits
+	if err := json.Unmarshal([]byte(resultText(res)), &limits); err != nil {
+		t.Fatalf("server_capabilities returned %q: %v", resultText(res), err)
+	}
+	return limits
+}
+
+func TestServerCapabilities(t *testing.T) {
+	server := NewSessionServer(&SessionServerOptions{ToolTimeout: 30 * time.Second, MaxResultBytes: 1 << 20})
+	server.EnableCapabilities(ServerLimits{MaxRequestBytes: 4 << 20, MaxSessionStateBytes: 64 << 10, SessionTTL: 3600})
+	// Tools added after the capabilities tool are still listed
+	AddTool(server, &mcp.Tool{Name: "echo"}, echo, WithTimeout(5*time.Second))
+	session := connect(t, server)
+
+	limits := serverCapabilities(t, session)
+	if limits.MaxRequestBytes != 4<<20 || limits.MaxSessionStateBytes != 64<<10 || limits.SessionTTL != 3600 {
+		t.Errorf("server_capabilities = %+v, want the limits it was given", limits)
+	}
+	if limits.ToolTimeout != 30 || limits.MaxToolResultBytes != 1<<20 {
+		t.Errorf("server_capabilities = %+v, want the server's tool timeout and result limit", limits)
+	}
+}
+
+func TestServerCapabilitiesToolTimeouts(t *testing.T) {
+	server := NewSessionServer(&SessionServerOptions{ToolTimeout: 30 * time.Second})
+	server.EnableCapabilities(ServerLimits{})
+	AddTool(server, &mcp.Tool{Name: "echo"}, echo, WithTimeout(5*time.Second))
+	AddTool(server, &mcp.Tool{Name: "slow"}, echo, WithExpectedDuration(2*time.Minute))
+	AddTool(server, &mcp.Tool{Name: "quick"}, echo, WithExpectedDuration(time.Second))
+	AddTool(server, &mcp.Tool{Name: "pinned"}, echo, WithTimeout(5*time.Second), WithExpectedDuration(2*time.Minute))
+	AddTool(server, &mcp.Tool{Name: "removed"}, echo)
+	server.RemoveTools("removed")
+	session := connect(t, server)
+
+	want := map[string]float64{
+		"echo":                5,
+		"slow":                120, // Raised to the expected duration
+		"quick":               30,  // Never lowered below the default
+		"pinned":              5,   // WithTimeout takes precedence
+		"hello_world":         30,
+		"server_capabilities": 30,
+	}
+	limits := serverCapabilities(t, session)
+	for name, timeout := range want {
+		if got, ok := limits.ToolTimeouts[name]; !ok || got != timeout {
+			t.Errorf("advertised timeout of %s = %v (listed %v), want %v", name, got, ok, timeout)
+		}
+	}
+	if _, ok := limits.ToolTimeouts["removed"]; ok {
+		t.Error("removed tool still listed in the tool timeouts")
+	}
+}
+
+func TestServerCapabilitiesToolTimeoutsWithoutDefault(t *testing.T) {
+	server := NewSessionServer(&SessionServerOptions{})
+	server.EnableCapabilities(ServerLimits{})
+	// With no default timeout an expected duration doesn't add one
+	AddTool(server, &mcp.Tool{Name: "slow"}, echo, WithExpectedDuration(2*time.Minute))
+	session := connect(t, server)
+
+	if got := serverCapabilities(t, session).ToolTimeouts["slow"]; got != 0 {
+		t.Errorf("advertised timeout of slow = %v, want 0", got)
+	}
+}
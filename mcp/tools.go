@@ -0,0 +1,259 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// toolConfig holds per-tool settings applied by AddTool
+type toolConfig struct {
+	timeout        time.Duration
+	timeoutSet     bool          // Whether WithTimeout was given
+	expected       time.Duration // Declared by WithExpectedDuration
+	maxResultBytes int
+}
+
+// ToolOption customizes how a single tool is registered
+type ToolOption func(*toolConfig)
+
+// WithTimeout overrides the server's default tool timeout for one tool.
+// A timeout of 0 disables it for that tool.
+func WithTimeout(timeout time.Duration) ToolOption {
+	return func(c *toolConfig) {
+		c.timeout = timeout
+		c.timeoutSet = true
+	}
+}
+
+// WithExpectedDuration declares how long a tool's calls are expected to take.
+// If that's longer than the server's default tool timeout, the tool's timeout
+// is raised to match, so slow tools aren't cut off and clients see a
+// realistic timeout for them in server_capabilities. WithTimeout takes
+// precedence, and a default of 0 (no timeout) is left as it is.
+func WithExpectedDuration(d time.Duration) ToolOption {
+	return func(c *toolConfig) {
+		c.expected = d
+	}
+}
+
+// WithMaxResultBytes overrides the server's default result size limit for
+// one tool. A limit of 0 disables it for that tool.
+func WithMaxResultBytes(limit int) ToolOption {
+	return func(c *toolConfig) {
+		c.maxResultBytes = limit
+	}
+}
+
+// AddTool registers a tool on the session server's MCP server and records it
+// in the server's tool registry, so it's included in list_tools output.
+// Tools should always be added through this function rather than mcp.AddTool,
+// so that the server's handler wrappers are applied.
+func AddTool[In, Out any](s *SessionServer, t *mcp.Tool, h mcp.ToolHandlerFor[In, Out], opts ...ToolOption) {
+	if !s.toolAllowed(t.Name) {
+		return
+	}
+
+	config := toolConfig{timeout: s.toolTimeout, maxResultBytes: s.maxResults}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if !config.timeoutSet && config.timeout > 0 && config.expected > config.timeout {
+		config.timeout = config.expected
+	}
+
+	// Recovery must wrap the handler directly, since the timeout wrapper runs
+	// it on another goroutine. The concurrency limit sits inside the timeout,
+	// so waiting for a slot counts against it and a slot stays taken until a
+	// timed-out handler has actually returned. A call retried after its
+	// missing session is recreated runs inside both.
+	h = withRecover(t.Name, h)
+	h = withSessionNotFound(t.Name, s, h)
+	h = withConcurrencyLimit(t.Name, s.limiter, h)
+	h = withToolTimeout(t.Name, config.timeout, h)
+	h = withResultLimit(t.Name, config.maxResultBytes, h)
+	h = withMetrics(t.Name, h)
+	h = withAudit(t.Name, s.audit, h)
+
+	// The strict argument schema is resolved before the tool is registered,
+	// so a schema that can't be resolved leaves no trace of the tool. The
+	// input schema is inferred the same way mcp.AddTool would infer it.
+	var schema *argSchema
+	if s.strictArgs {
+		if t.InputSchema == nil {
+			inferred, err := jsonschema.For[In]()
+			if err != nil {
+				log.Printf("Not registering tool %q: inferring input schema: %v", t.Name, err)
+				return
+			}
+			t.InputSchema = inferred
+		}
+		var err error
+		if schema, err = newArgSchema(t.InputSchema); err != nil {
+			log.Printf("Not registering tool %q: resolving input schema: %v", t.Name, err)
+			return
+		}
+	}
+
+	// mcp.AddTool fills in the remaining schemas on t
+	mcp.AddTool(s.MCPServer, t, h)
+
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+	s.tools[t.Name] = t
+	s.timeouts[t.Name] = config.timeout
+	if schema != nil {
+		s.argSchemas[t.Name] = schema
+	}
+}
+
+// RemoveTools removes tools from the MCP server and the tool registry
+func (s *SessionServer) RemoveTools(names ...string) {
+	s.MCPServer.RemoveTools(names...)
+
+	s.toolsMu.Lock()
+	defer s.toolsMu.Unlock()
+	for _, name := range names {
+		delete(s.tools, name)
+		delete(s.timeouts, name)
+		delete(s.argSchemas, name)
+	}
+}
+
+// toolAllowed reports whether the enabled and disabled tool lists allow a
+// tool to be registered
+func (s *SessionServer) toolAllowed(name string) bool {
+	if s.enabled != nil && !s.enabled[name] {
+		return false
+	}
+	return !s.disabled[name]
+}
+
+// rejectFilteredTools is receiving middleware that answers calls to tools
+// filtered out by the enabled and disabled tool lists with a clearer error
+// than the SDK's "unknown tool"
+func (s *SessionServer) rejectFilteredTools(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, ss *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		if call, ok := params.(*mcp.CallToolParamsFor[json.RawMessage]); ok && method == "tools/call" && !s.toolAllowed(call.Name) {
+			return nil, fmt.Errorf("tool %q is not available on this server", call.Name)
+		}
+		return next(ctx, ss, method, params)
+	}
+}
+
+// toolSet builds a lookup set from a list of tool names
+func toolSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// Tools returns the registered tools sorted by name
+func (s *SessionServer) Tools() []*mcp.Tool {
+	s.toolsMu.RLock()
+	defer s.toolsMu.RUnlock()
+
+	tools := make([]*mcp.Tool, 0, len(s.tools))
+	for _, tool := range s.tools {
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name < tools[j].Name
+	})
+	return tools
+}
+
+// ToolTimeouts returns the timeout enforced for each registered tool by name,
+// 0 for tools without one. These are the values withToolTimeout was given,
+// so they're what clients should plan their deadlines around.
+func (s *SessionServer) ToolTimeouts() map[string]time.Duration {
+	s.toolsMu.RLock()
+	defer s.toolsMu.RUnlock()
+
+	timeouts := make(map[string]time.Duration, len(s.timeouts))
+	for name, timeout := range s.timeouts {
+		timeouts[name] = timeout
+	}
+	return timeouts
+}
+
+// withToolTimeout bounds a handler's execution time. The handler gets a child
+// context that's cancelled at the deadline, so store calls made with it are
+// cancelled too. If the handler hasn't returned by then a tool error is
+// returned to the client; a handler that ignores its context keeps running in
+// the background until it returns.
+func withToolTimeout[In, Out any](name string, timeout time.Duration, h mcp.ToolHandlerFor[In, Out]) mcp.ToolHandlerFor[In, Out] {
+	if timeout <= 0 {
+		return h
+	}
+
+	return func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[In]) (*mcp.CallToolResultFor[Out], error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		type result struct {
+			res *mcp.CallToolResultFor[Out]
+			err error
+		}
+		done := make(chan result, 1)
+		go func() {
+			res, err := h(ctx, ss, params)
+			done <- result{res, err}
+		}()
+
+		select {
+		case r := <-done:
+			return r.res, r.err
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("tool %q timed out after %s", name, timeout)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// withRecover turns a panic in a handler into a tool error, so a buggy tool
+// can't crash the server. The panic is logged with its stack trace and
+// counted in mcp_tool_panics_total.
+func withRecover[In, Out any](name string, h mcp.ToolHandlerFor[In, Out]) mcp.ToolHandlerFor[In, Out] {
+	return func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[In]) (res *mcp.CallToolResultFor[Out], err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				toolPanicsTotal.WithLabelValues(name).Inc()
+				log.Printf("Recovered panic in tool %q (session %s): %v\n%s", name, ss.ID(), v, debug.Stack())
+				res, err = nil, fmt.Errorf("tool %q failed with an internal error", name)
+			}
+		}()
+		return h(ctx, ss, params)
+	}
+}
+
+// withMetrics counts a handler's calls and records their duration in
+// mcp_tool_calls_total and mcp_tool_call_duration_seconds. The tool label is
+// the name the tool was registered with, never one taken from a request, so
+// the label set is bounded by the registered tools.
+func withMetrics[In, Out any](name string, h mcp.ToolHandlerFor[In, Out]) mcp.ToolHandlerFor[In, Out] {
+	return func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[In]) (*mcp.CallToolResultFor[Out], error) {
+		start := time.Now()
+		res, err := h(ctx, ss, params)
+		toolCallDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+
+		outcome := "success"
+		if err != nil || (res != nil && res.IsError) {
+			outcome = "error"
+		}
+		toolCallsTotal.WithLabelValues(name, outcome).Inc()
+		return res, err
+	}
+}
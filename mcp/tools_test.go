@@ -0,0 +1,197 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type sleepArgs struct {
+	Duration string `json:"duration"`
+}
+
+// sleep is a tool handler that waits for its duration argument or for its
+// context to be done, reporting which happened on cancelled
+func sleep(cancelled chan<- error) mcp.ToolHandlerFor[sleepArgs, any] {
+	return func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[sleepArgs]) (*mcp.CallToolResultFor[any], error) {
+		d, err := time.ParseDuration(params.Arguments.Duration)
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(d):
+			return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil
+		case <-ctx.Done():
+			cancelled <- ctx.Err()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestToolTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeout     time.Duration // Server default
+		opts        []ToolOption
+		duration    string
+		wantTimeout bool
+	}{
+		{name: "within default", timeout: time.Second, duration: "10ms"},
+		{name: "over default", timeout: 50 * time.Millisecond, duration: "5s", wantTimeout: true},
+		{name: "tool override", timeout: time.Second, opts: []ToolOption{WithTimeout(50 * time.Millisecond)}, duration: "5s", wantTimeout: true},
+		{name: "override disables", timeout: 50 * time.Millisecond, opts: []ToolOption{WithTimeout(0)}, duration: "100ms"},
+		{name: "no default", duration: "100ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewSessionServer(&SessionServerOptions{ToolTimeout: tt.timeout})
+			cancelled := make(chan error, 1)
+			AddTool(server, &mcp.Tool{Name: "sleep"}, sleep(cancelled), tt.opts...)
+			session := connect(t, server)
+
+			res := callTool(t, session, "sleep", sleepArgs{Duration: tt.duration})
+			if !tt.wantTimeout {
+				if res.IsError || resultText(res) != "done" {
+					t.Fatalf("sleep = %q (error %v), want it to finish", resultText(res), res.IsError)
+				}
+				return
+			}
+			if !res.IsError || !strings.Contains(resultText(res), `tool "sleep" timed out after 50ms`) {
+				t.Fatalf("sleep = %q (error %v), want a timeout error", resultText(res), res.IsError)
+			}
+			// The handler's context is cancelled at the deadline
+			select {
+			case err := <-cancelled:
+				if err != context.DeadlineExceeded {
+					t.Errorf("handler context error = %v, want context.DeadlineExceeded", err)
+				}
+			case <-time.After(time.Second):
+				t.Error("handler context wasn't cancelled at the timeout")
+			}
+		})
+	}
+}
+
+func TestToolPanic(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	server := NewSessionServer(nil)
+	AddTool(server, &mcp.Tool{Name: "panic"}, func(ctx context.Context, ss *mcp.ServerSession, params *mcp.CallToolParamsFor[struct{}]) (*mcp.CallToolResultFor[any], error) {
+		panic("boom")
+	})
+	session := connect(t, server)
+	panics := testutil.ToFloat64(toolPanicsTotal.WithLabelValues("panic"))
+
+	res := callTool(t, session, "panic", map[string]any{})
+	if !res.IsError || !strings.Contains(resultText(res), `tool "panic" failed with an internal error`) {
+		t.Errorf("panic = %q (error %v), want an internal error", resultText(res), res.IsError)
+	}
+	if strings.Contains(resultText(res), "boom") {
+		t.Errorf("panic = %q, want the panic value kept out of the result", resultText(res))
+	}
+	if got := testutil.ToFloat64(toolPanicsTotal.WithLabelValues("panic")) - panics; got != 1 {
+		t.Errorf("panics counted = %v, want 1", got)
+	}
+
+	// The session keeps working
+	if res := callTool(t, session, "hello_world", map[string]any{}); res.IsError {
+		t.Errorf("hello_world after a panic failed: %s", resultText(res))
+	}
+}
+
+func TestEnabledDisabledTools(t *testing.T) {
+	tests := []struct {
+		name         string
+		enabled      []string
+		disabled     []string
+		wantTools    []string
+		wantFiltered []string
+	}{
+		{
+			name:         "enabled",
+			enabled:      []string{"hello_world", "echo"},
+			wantTools:    []string{"echo", "hello_world"},
+			wantFiltered: []string{"list_tools", "server_stats"},
+		},
+		{
+			name:         "disabled",
+			disabled:     []string{"echo", "render_chart"},
+			wantTools:    []string{"hello_world", "list_tools", "server_stats"},
+			wantFiltered: []string{"echo", "render_chart"},
+		},
+		{
+			name:         "disabled wins",
+			enabled:      []string{"hello_world", "echo"},
+			disabled:     []string{"echo"},
+			wantTools:    []string{"hello_world"},
+			wantFiltered: []string{"echo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewSessionServer(&SessionServerOptions{EnabledTools: tt.enabled, DisabledTools: tt.disabled})
+			AddTool(server, &mcp.Tool{Name: "echo"}, echo)
+			session := connect(t, server)
+
+			advertised, err := session.ListTools(t.Context(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, tool := range advertised.Tools {
+				names = append(names, tool.Name)
+			}
+			slices.Sort(names)
+			for _, name := range tt.wantTools {
+				if !slices.Contains(names, name) {
+					t.Errorf("tools/list = %v, want %s advertised", names, name)
+				}
+			}
+			for _, name := range tt.wantFiltered {
+				if slices.Contains(names, name) {
+					t.Errorf("tools/list = %v, want %s filtered out", names, name)
+				}
+				_, err := session.CallTool(t.Context(), &mcp.CallToolParams{Name: name, Arguments: map[string]any{}})
+				if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("tool %q is not available on this server", name)) {
+					t.Errorf("CallTool(%q) error = %v, want a not available error", name, err)
+				}
+			}
+			if res := callTool(t, session, "hello_world", map[string]any{}); res.IsError {
+				t.Errorf("hello_world failed: %s", resultText(res))
+			}
+		})
+	}
+}
+
+func TestToolExpectedDuration(t *testing.T) {
+	server := NewSessionServer(&SessionServerOptions{ToolTimeout: 50 * time.Millisecond})
+	server.EnableCapabilities(ServerLimits{})
+	cancelled := make(chan error, 1)
+	AddTool(server, &mcp.Tool{Name: "sleep"}, sleep(cancelled), WithExpectedDuration(300*time.Millisecond))
+	session := connect(t, server)
+
+	// The raised timeout is the one enforced, and the one advertised
+	if res := callTool(t, session, "sleep", sleepArgs{Duration: "100ms"}); res.IsError || resultText(res) != "done" {
+		t.Errorf("sleep within the expected duration = %q (error %v), want it to finish", resultText(res), res.IsError)
+	}
+	res := callTool(t, session, "sleep", sleepArgs{Duration: "5s"})
+	if !res.IsError || !strings.Contains(resultText(res), `tool "sleep" timed out after 300ms`) {
+		t.Errorf("sleep past the expected duration = %q (error %v), want a timeout error", resultText(res), res.IsError)
+	}
+	if got := serverCapabilities(t, session).ToolTimeouts["sleep"]; got != 0.3 {
+		t.Errorf("advertised timeout of sleep = %v, want 0.3", got)
+	}
+	if got := server.ToolTimeouts()["sleep"]; got != 300*time.Millisecond {
+		t.Errorf("ToolTimeouts()[sleep] = %v, want 300ms", got)
+	}
+}
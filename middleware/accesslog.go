@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccessLogFormat selects how LogAccess writes each request
+type AccessLogFormat string
+
+const (
+	// AccessLogJSON writes one JSON object per request
+	AccessLogJSON AccessLogFormat = "json"
+
+	// AccessLogCommon writes Common Log Format lines, as Apache's %h %l %u %t "%r" %>s %b
+	AccessLogCommon AccessLogFormat = "clf"
+
+	// AccessLogCombined writes Combined Log Format lines: Common Log Format
+	// followed by the quoted referrer and user agent
+	AccessLogCombined AccessLogFormat = "combined"
+)
+
+// clfTimeFormat is the timestamp layout of Common Log Format, without the
+// surrounding brackets
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// accessRecord is the JSON form of an access log entry
+type accessRecord struct {
+	Time       time.Time `json:"time"`
+	RemoteAddr string    `json:"remote_addr"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Proto      string    `json:"proto"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMS float64   `json:"duration_ms"`
+	SessionID  string    `json:"session_id,omitempty"`
+	Referer    string    `json:"referer,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// LogAccess writes a line to w for every request once it's been handled,
+// in the given format. Bytes are those written to the client, so for event
+// streams and other responses without a known size they're whatever had
+// been sent when the stream ended. An empty format disables the log.
+func LogAccess(format AccessLogFormat, w io.Writer, next http.Handler) http.Handler {
+	if format == "" {
+		return next
+	}
+	var mu sync.Mutex
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		aw := &accessLogResponseWriter{ResponseWriter: rw}
+		defer func() {
+			var line []byte
+			switch format {
+			case AccessLogJSON:
+				var err error
+				line, err = json.Marshal(accessRecord{
+					Time:       start.UTC(),
+					RemoteAddr: remoteHost(r),
+					Method:     r.Method,
+					Path:       r.RequestURI,
+					Proto:      r.Proto,
+					Status:     aw.statusCode(),
+					Bytes:      aw.bytes,
+					DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+					SessionID:  r.Header.Get(sessionIDHeader),
+					Referer:    r.Referer(),
+					UserAgent:  r.UserAgent(),
+				})
+				if err != nil {
+					log.Printf("Failed to encode access log record: %v", err)
+					return
+				}
+			default:
+				line = []byte(clfLine(format, r, start, aw.statusCode(), aw.bytes))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				log.Printf("Failed to write access log: %v", err)
+			}
+		}()
+		next.ServeHTTP(aw, r)
+	})
+}
+
+// clfLine formats a request in Common or Combined Log Format. There's no
+// identd or HTTP authentication user, so both are "-", as is a size of 0.
+func clfLine(format AccessLogFormat, r *http.Request, start time.Time, status int, n int64) string {
+	size := "-"
+	if n > 0 {
+		size = strconv.FormatInt(n, 10)
+	}
+	line := fmt.Sprintf(`%s - - [%s] "%s" %d %s`,
+		remoteHost(r), start.Format(clfTimeFormat),
+		clfEscape(r.Method+" "+r.RequestURI+" "+r.Proto), status, size)
+	if format == AccessLogCombined {
+		line += fmt.Sprintf(` "%s" "%s"`, clfField(r.Referer()), clfField(r.UserAgent()))
+	}
+	return line
+}
+
+// clfField escapes a quoted field, writing "-" for an empty one
+func clfField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return clfEscape(s)
+}
+
+// clfEscape escapes quotes, backslashes and unprintable bytes the way
+// Apache does, so client-supplied values can't break up a line
+func clfEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// remoteHost returns the host part of the connection's peer address
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// accessLogResponseWriter records the status and the number of body bytes
+// written
+type accessLogResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// statusCode returns the status sent, which is 200 if the handler wrote a
+// body without one or wrote nothing at all
+func (w *accessLogResponseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *accessLogResponseWriter) WriteHeader(status int) {
+	// Informational responses come before the final status
+	if w.status == 0 && status >= 200 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush sends buffered data to the client, so event streams aren't held up
+func (w *accessLogResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *accessLogResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
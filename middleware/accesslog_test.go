@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clfTime matches a Common Log Format timestamp in brackets
+const clfTime = `\[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\]`
+
+// respondWith writes status and body
+func respondWith(status int, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+}
+
+func TestLogAccessCommon(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.Handler
+		uri     string
+		want    string
+	}{
+		{name: "with body", handler: respondWith(http.StatusAccepted, "hello"), uri: "/mcp?x=1", want: `"POST /mcp?x=1 HTTP/1.1" 202 5`},
+		{name: "empty body", handler: okHandler, uri: "/mcp", want: `"POST /mcp HTTP/1.1" 200 -`},
+		{name: "escaped request line", handler: okHandler, uri: "/mcp\"\n\\", want: `"POST /mcp\"\x0a\\ HTTP/1.1" 200 -`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			req.RequestURI = tt.uri
+			req.Header.Set("User-Agent", "test-client")
+			LogAccess(AccessLogCommon, &logs, tt.handler).ServeHTTP(httptest.NewRecorder(), req)
+
+			want := regexp.MustCompile(`^192\.0\.2\.1 - - ` + clfTime + ` ` + regexp.QuoteMeta(tt.want) + "\n$")
+			if !want.MatchString(logs.String()) {
+				t.Errorf("access log = %q, want it to match %s", logs.String(), want)
+			}
+		})
+	}
+}
+
+func TestLogAccessCombined(t *testing.T) {
+	tests := []struct {
+		name      string
+		referer   string
+		userAgent string
+		want      string
+	}{
+		{name: "with headers", referer: "https://example.com/", userAgent: "test-client/1.0", want: `"https://example.com/" "test-client/1.0"`},
+		{name: "missing headers", want: `"-" "-"`},
+		{name: "escaped headers", referer: `a"b`, userAgent: "tab\there", want: `"a\"b" "tab\x09here"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			req := httptest.NewRequest(http.MethodGet, "/mcp", nil)
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			if tt.userAgent != "" {
+				req.Header.Set("User-Agent", tt.userAgent)
+			}
+			LogAccess(AccessLogCombined, &logs, respondWith(http.StatusNotFound, "gone")).ServeHTTP(httptest.NewRecorder(), req)
+
+			want := regexp.MustCompile(`^192\.0\.2\.1 - - ` + clfTime + ` "GET /mcp HTTP/1\.1" 404 4 ` + regexp.QuoteMeta(tt.want) + "\n$")
+			if !want.MatchString(logs.String()) {
+				t.Errorf("access log = %q, want it to match %s", logs.String(), want)
+			}
+		})
+	}
+}
+
+func TestLogAccessJSON(t *testing.T) {
+	var logs bytes.Buffer
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set(sessionIDHeader, "session-1")
+	req.Header.Set("User-Agent", "test-client")
+	before := time.Now().UTC()
+	LogAccess(AccessLogJSON, &logs, respondWith(http.StatusCreated, "created")).ServeHTTP(httptest.NewRecorder(), req)
+
+	var record accessRecord
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("access log %q isn't JSON: %v", logs.String(), err)
+	}
+	if record.RemoteAddr != "192.0.2.1" || record.Method != http.MethodPost || record.Path != "/mcp" || record.Proto != "HTTP/1.1" {
+		t.Errorf("access log record = %+v, want the request line", record)
+	}
+	if record.Status != http.StatusCreated || record.Bytes != 7 || record.SessionID != "session-1" || record.UserAgent != "test-client" {
+		t.Errorf("access log record = %+v, want the status, size, session and user agent", record)
+	}
+	if record.Time.Before(before.Truncate(time.Second)) || record.DurationMS < 0 {
+		t.Errorf("access log record = %+v, want the start time and duration", record)
+	}
+	if strings.Count(logs.String(), "\n") != 1 {
+		t.Errorf("access log = %q, want one line", logs.String())
+	}
+}
+
+func TestLogAccessStream(t *testing.T) {
+	var logs bytes.Buffer
+	handler := LogAccess(AccessLogCommon, &logs, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for range 3 {
+			w.Write([]byte("data: {}\n\n"))
+			if err := http.NewResponseController(w).Flush(); err != nil {
+				t.Errorf("Flush() error = %v", err)
+			}
+		}
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/mcp", nil))
+
+	if !rec.Flushed {
+		t.Error("stream wasn't flushed through the access log")
+	}
+	if !strings.HasSuffix(logs.String(), `"GET /mcp HTTP/1.1" 200 30`+"\n") {
+		t.Errorf("access log = %q, want the bytes streamed", logs.String())
+	}
+}
+
+func TestLogAccessDisabled(t *testing.T) {
+	var logs bytes.Buffer
+	LogAccess("", &logs, okHandler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/mcp", nil))
+	if logs.Len() != 0 {
+		t.Errorf("access log = %q with no format, want nothing written", logs.String())
+	}
+}
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultBodyLogMaxBytes is how much of each body LogBodies keeps when no
+// cap is configured
+const DefaultBodyLogMaxBytes = 64 << 10
+
+// redactedValue replaces the values of redacted fields in logged bodies
+const redactedValue = "[REDACTED]"
+
+// BodyLogOptions configures LogBodies
+type BodyLogOptions struct {
+	// SampleRate is the fraction of requests logged, from 0 to 1
+	SampleRate float64
+
+	// DebugHeader names a header that, when a request sets it to any
+	// non-empty value, has that request logged regardless of the sample
+	// rate. Empty disables it.
+	DebugHeader string
+
+	// RedactFields lists JSON object keys whose values are replaced before
+	// logging, at any depth. Matching ignores case.
+	RedactFields []string
+
+	// MaxBytes caps how much of each request and response body is kept.
+	// 0 or less uses DefaultBodyLogMaxBytes.
+	MaxBytes int
+}
+
+// LogBodies logs the JSON-RPC request and response bodies of a sample of
+// requests, for diagnosing protocol issues. Bodies are copied as they're read
+// and written, up to the size cap, so streamed responses still reach the
+// client as they're written; the exchange is logged once the handler
+// returns. Redacted fields are replaced in JSON bodies and in each event of
+// a server-sent event stream. Anything that can't be parsed, including
+// bodies cut short by the cap, is left out rather than logged unredacted.
+// With a zero sample rate and no debug header it's disabled.
+func LogBodies(opts BodyLogOptions, next http.Handler) http.Handler {
+	if opts.SampleRate <= 0 && opts.DebugHeader == "" {
+		return next
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultBodyLogMaxBytes
+	}
+	redact := make(map[string]bool, len(opts.RedactFields))
+	for _, field := range opts.RedactFields {
+		if field = strings.TrimSpace(field); field != "" {
+			redact[strings.ToLower(field)] = true
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sampled := opts.SampleRate > 0 && rand.Float64() < opts.SampleRate
+		if !sampled && (opts.DebugHeader == "" || r.Header.Get(opts.DebugHeader) == "") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		request := &cappedBuffer{limit: opts.MaxBytes}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(r.Body, request), r.Body}
+		}
+		lw := &bodyLogResponseWriter{
+			ResponseWriter: w,
+			body:           &cappedBuffer{limit: opts.MaxBytes},
+		}
+		next.ServeHTTP(lw, r)
+
+		status := lw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		log.Printf("Body log: %s %s session=%q status=%d request=%s response=%s",
+			r.Method, r.URL.Path, r.Header.Get(sessionIDHeader), status,
+			redactBody(r.Header.Get("Content-Type"), request, redact),
+			redactBody(lw.Header().Get("Content-Type"), lw.body, redact))
+	})
+}
+
+// cappedBuffer keeps the first limit bytes written to it and counts the rest
+type cappedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+	total int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// truncated reports whether more was written than was kept
+func (b *cappedBuffer) truncated() bool {
+	return b.total > b.buf.Len()
+}
+
+// bodyLogResponseWriter copies what the handler writes into a capped buffer
+type bodyLogResponseWriter struct {
+	http.ResponseWriter
+	body   *cappedBuffer
+	status int
+}
+
+func (w *bodyLogResponseWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= http.StatusOK {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *bodyLogResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.body.Write(p[:n])
+	return n, err
+}
+
+// Flush sends buffered data to the client, so event streams aren't held up
+func (w *bodyLogResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *bodyLogResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// redactBody formats a captured body for logging with redacted fields
+// replaced. Only JSON and server-sent event bodies are logged.
+func redactBody(contentType string, body *cappedBuffer, redact map[string]bool) string {
+	if body.total == 0 {
+		return `""`
+	}
+	if body.truncated() {
+		return omittedBody(body.total, "larger than the body log limit")
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		redacted, ok := redactJSON(body.buf.Bytes(), redact)
+		if !ok {
+			return omittedBody(body.total, "not valid JSON")
+		}
+		return redacted
+	case "text/event-stream":
+		return redactEventStream(body.buf.String(), redact)
+	default:
+		return omittedBody(body.total, "content type "+mediaType)
+	}
+}
+
+// redactEventStream redacts the JSON in each data line of an event stream.
+// Data lines that aren't JSON are left out.
+func redactEventStream(stream string, redact map[string]bool) string {
+	lines := strings.Split(strings.TrimRight(stream, "\n"), "\n")
+	for i, line := range lines {
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		redacted, ok := redactJSON([]byte(data), redact)
+		if !ok {
+			redacted = `"[omitted: not valid JSON]"`
+		}
+		lines[i] = "data: " + redacted
+	}
+	encoded, _ := json.Marshal(strings.Join(lines, "\n"))
+	return string(encoded)
+}
+
+// redactJSON re-encodes data with redacted fields replaced
+func redactJSON(data []byte, redact map[string]bool) (string, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return "", false
+	}
+	encoded, err := json.Marshal(redactValue(value, redact))
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
+
+// redactValue replaces the values of redacted keys anywhere within value
+func redactValue(value any, redact map[string]bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if redact[strings.ToLower(key)] {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(child, redact)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = redactValue(child, redact)
+		}
+	}
+	return value
+}
+
+// omittedBody describes a body that isn't logged
+func omittedBody(size int, reason string) string {
+	encoded, _ := json.Marshal(fmt.Sprintf("[omitted %d bytes: %s]", size, reason))
+	return string(encoded)
+}
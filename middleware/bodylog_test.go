@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureLog returns a buffer receiving the standard logger's output for the
+// rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var logs bytes.Buffer
+	logger, flags := log.Writer(), log.Flags()
+	log.SetOutput(&logs)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(logger)
+		log.SetFlags(flags)
+	})
+	return &logs
+}
+
+// respondJSON writes body as a JSON response
+func respondJSON(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	})
+}
+
+// postJSON returns a JSON POST request for body
+func postJSON(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestLogBodiesSampling(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        BodyLogOptions
+		debugHeader bool
+		wantLogged  bool
+	}{
+		{name: "always sampled", opts: BodyLogOptions{SampleRate: 1}, wantLogged: true},
+		{name: "debug header", opts: BodyLogOptions{DebugHeader: "X-Debug-Body"}, debugHeader: true, wantLogged: true},
+		{name: "no debug header", opts: BodyLogOptions{DebugHeader: "X-Debug-Body"}},
+		{name: "disabled", opts: BodyLogOptions{}, debugHeader: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLog(t)
+			req := postJSON(`{"id":1}`)
+			if tt.debugHeader {
+				req.Header.Set("X-Debug-Body", "1")
+			}
+			LogBodies(tt.opts, respondJSON(`{"id":1,"result":{}}`)).ServeHTTP(httptest.NewRecorder(), req)
+			if logged := strings.Contains(logs.String(), "Body log:"); logged != tt.wantLogged {
+				t.Errorf("logged = %v, want %v: %q", logged, tt.wantLogged, logs.String())
+			}
+		})
+	}
+
+	t.Run("sample rate", func(t *testing.T) {
+		logs := captureLog(t)
+		handler := LogBodies(BodyLogOptions{SampleRate: 0.5}, respondJSON(`{}`))
+		for range 1000 {
+			handler.ServeHTTP(httptest.NewRecorder(), postJSON(`{}`))
+		}
+		if logged := strings.Count(logs.String(), "Body log:"); logged < 350 || logged > 650 {
+			t.Errorf("logged %d of 1000 requests, want about half", logged)
+		}
+	})
+}
+
+func TestLogBodies(t *testing.T) {
+	logs := captureLog(t)
+	handler := LogBodies(BodyLogOptions{SampleRate: 1, RedactFields: []string{"password", " Token "}}, respondJSON(`{"result":{"token":"abc","items":[{"Password":"x","name":"y"}]}}`))
+	rec := httptest.NewRecorder()
+	req := postJSON(`{"params":{"arguments":{"password":"hunter2","user":"alice"}}}`)
+	req.Header.Set("Mcp-Session-Id", "session-1")
+	handler.ServeHTTP(rec, req)
+
+	line := logs.String()
+	for _, want := range []string{
+		`Body log: POST /mcp session="session-1" status=200`,
+		`request={"params":{"arguments":{"password":"[REDACTED]","user":"alice"}}}`,
+		`response={"result":{"items":[{"Password":"[REDACTED]","name":"y"}],"token":"[REDACTED]"}}`,
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("log = %q, want it to contain %q", line, want)
+		}
+	}
+	if strings.Contains(line, "hunter2") || strings.Contains(line, "abc") {
+		t.Errorf("log = %q, want redacted values left out", line)
+	}
+	// The client still gets the unredacted response
+	if !strings.Contains(rec.Body.String(), `"token":"abc"`) {
+		t.Errorf("response = %q, want it unchanged", rec.Body.String())
+	}
+}
+
+func TestLogBodiesTruncation(t *testing.T) {
+	logs := captureLog(t)
+	response := `{"result":"` + strings.Repeat("x", 100) + `"}`
+	handler := LogBodies(BodyLogOptions{SampleRate: 1, MaxBytes: 32}, respondJSON(response))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, postJSON(`{"id":1}`))
+
+	line := logs.String()
+	if !strings.Contains(line, `request={"id":1}`) {
+		t.Errorf("log = %q, want the request within the limit logged", line)
+	}
+	if want := `response="[omitted 113 bytes: larger than the body log limit]"`; !strings.Contains(line, want) {
+		t.Errorf("log = %q, want %q", line, want)
+	}
+	if rec.Body.String() != response {
+		t.Errorf("client got %d bytes, want the whole response", rec.Body.Len())
+	}
+}
+
+func TestLogBodiesEventStream(t *testing.T) {
+	logs := captureLog(t)
+	handler := LogBodies(BodyLogOptions{SampleRate: 1, RedactFields: []string{"secret"}}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: message\ndata: {\"secret\":\"s\",\"id\":1}\n\ndata: not json\n\n")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), postJSON(`{}`))
+
+	want := `response="event: message\ndata: {\"id\":1,\"secret\":\"[REDACTED]\"}\n\ndata: \"[omitted: not valid JSON]\""`
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("log = %q, want it to contain %q", logs.String(), want)
+	}
+}
+
+func TestLogBodiesOmitsUnparseable(t *testing.T) {
+	logs := captureLog(t)
+	handler := LogBodies(BodyLogOptions{SampleRate: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "password=hunter2")
+	}))
+	req := postJSON(`{"password":`)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := logs.String()
+	if strings.Contains(line, "hunter2") {
+		t.Errorf("log = %q, want the plain text body left out", line)
+	}
+	for _, want := range []string{`request="[omitted 12 bytes: not valid JSON]"`, `response="[omitted 16 bytes: content type text/plain]"`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("log = %q, want it to contain %q", line, want)
+		}
+	}
+}
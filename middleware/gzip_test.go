@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const gzipTestBody = `{"jsonrpc":"2.0","id":1,"result":{}}`
+
+// respond writes gzipTestBody with the given content type and encoding
+func respond(contentType, contentEncoding string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		if contentEncoding != "" {
+			w.Header().Set("Content-Encoding", contentEncoding)
+		}
+		io.WriteString(w, gzipTestBody)
+	})
+}
+
+func TestGzip(t *testing.T) {
+	tests := []struct {
+		name            string
+		acceptEncoding  string
+		contentType     string
+		contentEncoding string
+		wantGzip        bool
+	}{
+		{name: "accepted", acceptEncoding: "gzip", contentType: "application/json", wantGzip: true},
+		{name: "accepted among others", acceptEncoding: "deflate, GZIP;q=0.5", contentType: "application/json", wantGzip: true},
+		{name: "not accepted", acceptEncoding: "deflate", contentType: "application/json"},
+		{name: "no header", contentType: "application/json"},
+		{name: "refused with q=0", acceptEncoding: "gzip;q=0", contentType: "application/json"},
+		{name: "refused with q=0.0", acceptEncoding: "deflate, gzip; q=0.0", contentType: "application/json"},
+		{name: "event stream", acceptEncoding: "gzip", contentType: "text/event-stream"},
+		{name: "event stream with charset", acceptEncoding: "gzip", contentType: "text/event-stream; charset=utf-8"},
+		{name: "already encoded", acceptEncoding: "gzip", contentType: "application/json", contentEncoding: "br"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			Gzip(respond(tt.contentType, tt.contentEncoding)).ServeHTTP(rec, req)
+
+			if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", vary)
+			}
+			encoding := rec.Header().Get("Content-Encoding")
+			if !tt.wantGzip {
+				if encoding != tt.contentEncoding {
+					t.Errorf("Content-Encoding = %q, want %q", encoding, tt.contentEncoding)
+				}
+				if body := rec.Body.String(); body != gzipTestBody {
+					t.Errorf("body = %q, want it passed through", body)
+				}
+				return
+			}
+
+			if encoding != "gzip" {
+				t.Fatalf("Content-Encoding = %q, want gzip", encoding)
+			}
+			gz, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("body isn't gzip: %v", err)
+			}
+			body, err := io.ReadAll(gz)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != gzipTestBody {
+				t.Errorf("decompressed body = %q, want %q", body, gzipTestBody)
+			}
+		})
+	}
+}
+
+func TestGzipEventStreamFlushes(t *testing.T) {
+	// Each event reaches the client as it's written, not when the stream ends
+	events := make(chan struct{})
+	handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		<-events
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	defer close(events)
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	buf := make([]byte, len("data: first\n\n"))
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		t.Fatalf("reading the first event error = %v", err)
+	}
+	if !strings.HasPrefix(string(buf), "data: first") {
+		t.Errorf("first event = %q, want it uncompressed", buf)
+	}
+}
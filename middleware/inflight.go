@@ -0,0 +1,37 @@
+package middleware
+
+import "net/http"
+
+// LimitInFlight bounds the requests being handled at once across all
+// sessions. A request arriving when limit are already in flight isn't
+// queued; it gets 503 Service Unavailable with Retry-After, so clients back
+// off or go to another instance, and is counted in
+// mcp_http_in_flight_rejected_total. A request holds its slot until its
+// handler returns, so a streaming response holds it for as long as the
+// stream stays open. The number in flight is reported in
+// mcp_http_requests_in_flight whether or not there's a limit; a limit of 0
+// or less disables it.
+func LimitInFlight(limit int, next http.Handler) http.Handler {
+	var slots chan struct{}
+	if limit > 0 {
+		slots = make(chan struct{}, limit)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			default:
+				inFlightRejectedTotal.Inc()
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "server overloaded, try again later", http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		requestsInFlight.Inc()
+		defer requestsInFlight.Dec()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLimitInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := LimitInFlight(2, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	inFlight := testutil.ToFloat64(requestsInFlight)
+	rejected := testutil.ToFloat64(inFlightRejectedTotal)
+
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+			codes[i] = rec.Code
+		}()
+		<-started
+	}
+	if got := testutil.ToFloat64(requestsInFlight) - inFlight; got != 2 {
+		t.Errorf("requests in flight = %v, want 2", got)
+	}
+
+	// At the limit further requests are turned away, not queued
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status at the limit = %d, want 503", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("rejected response has no Retry-After header")
+	}
+	if got := testutil.ToFloat64(inFlightRejectedTotal) - rejected; got != 1 {
+		t.Errorf("rejected requests counted = %v, want 1", got)
+	}
+
+	close(release)
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d status = %d, want 200", i, code)
+		}
+	}
+	if got := testutil.ToFloat64(requestsInFlight) - inFlight; got != 0 {
+		t.Errorf("requests in flight after they finished = %v, want 0", got)
+	}
+
+	// Finished requests free their slots
+	go func() { <-started }()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status once the slots were freed = %d, want 200", rec.Code)
+	}
+}
+
+func TestLimitInFlightDisabled(t *testing.T) {
+	inFlight := testutil.ToFloat64(requestsInFlight)
+	handler := LimitInFlight(0, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Requests are still counted without a limit
+		if got := testutil.ToFloat64(requestsInFlight) - inFlight; got != 1 {
+			t.Errorf("requests in flight = %v, want 1", got)
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 with no limit", rec.Code)
+	}
+}
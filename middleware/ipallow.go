@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// IPAllowOptions configures AllowIPs
+type IPAllowOptions struct {
+	// Allowed lists the networks clients may connect from
+	Allowed []netip.Prefix
+
+	// TrustedProxies lists the networks of proxies whose X-Forwarded-For
+	// header is believed. When empty the header is ignored, since any client
+	// can set it, and the client IP is always the connection's peer address.
+	TrustedProxies []netip.Prefix
+}
+
+// ParsePrefixes parses CIDRs such as "10.0.0.0/8" or "fd00::/8". A bare IP
+// address is taken as a single-address prefix.
+func ParsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// AllowIPs rejects requests from clients outside the allowed networks with
+// 403 Forbidden. The client IP is the connection's peer address, unless that
+// peer is a trusted proxy: then X-Forwarded-For is read from the right,
+// skipping trusted proxies, and the first untrusted address is the client.
+// Entries to its left were written by the client and are never used. An
+// empty allow list disables the check.
+func AllowIPs(opts IPAllowOptions, next http.Handler) http.Handler {
+	if len(opts.Allowed) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, ok := clientIP(r, opts.TrustedProxies)
+		if !ok || !containsAddr(opts.Allowed, ip) {
+			ipDeniedTotal.Inc()
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP determines the IP address of the client that sent r
+func clientIP(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	peer, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	if !containsAddr(trusted, peer) {
+		return peer, true
+	}
+
+	header := strings.Join(r.Header.Values("X-Forwarded-For"), ",")
+	if header == "" {
+		return peer, true
+	}
+
+	// Each proxy appends the address it received the request from, so walk
+	// the header back from the nearest hop
+	hops := strings.Split(header, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			return netip.Addr{}, false
+		}
+		if !containsAddr(trusted, hop) {
+			return hop, true
+		}
+	}
+
+	// Every hop is a trusted proxy, so the request started inside them
+	return peer, true
+}
+
+// parseAddr parses an IP address with or without a port
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+// containsAddr reports whether any of prefixes contains addr
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func mustParsePrefixes(t *testing.T, cidrs ...string) []netip.Prefix {
+	t.Helper()
+	prefixes, err := ParsePrefixes(cidrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prefixes
+}
+
+func TestAllowIPs(t *testing.T) {
+	allowed := mustParsePrefixes(t, "203.0.113.0/24", "2001:db8::/32")
+	trusted := mustParsePrefixes(t, "10.0.0.0/8")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		trusted    []netip.Prefix
+		wantStatus int
+	}{
+		{name: "allowed peer", remoteAddr: "203.0.113.5:4000", wantStatus: http.StatusOK},
+		{name: "denied peer", remoteAddr: "198.51.100.5:4000", wantStatus: http.StatusForbidden},
+		{name: "allowed IPv6 peer", remoteAddr: "[2001:db8::1]:4000", wantStatus: http.StatusOK},
+		{name: "IPv4-mapped IPv6 peer", remoteAddr: "[::ffff:203.0.113.5]:4000", wantStatus: http.StatusOK},
+		{name: "IPv6 peer with zone", remoteAddr: "[2001:db8::1%eth0]:4000", wantStatus: http.StatusOK},
+		{name: "unparseable peer", remoteAddr: "not-an-address", wantStatus: http.StatusForbidden},
+		{
+			name:       "untrusted peer's header ignored",
+			remoteAddr: "198.51.100.5:4000",
+			xff:        []string{"203.0.113.5"},
+			trusted:    trusted,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "no trusted proxies",
+			remoteAddr: "10.0.0.1:4000",
+			xff:        []string{"203.0.113.5"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.1:4000",
+			xff:        []string{"203.0.113.5"},
+			trusted:    trusted,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "trusted hops skipped",
+			remoteAddr: "10.0.0.1:4000",
+			xff:        []string{"203.0.113.5, 10.0.0.3, 10.0.0.2"},
+			trusted:    trusted,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "trusted hops across headers",
+			remoteAddr: "10.0.0.1:4000",
+			xff:        []string{"203.0.113.5", "10.0.0.2"},
+			trusted:    trusted,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "spoofed leftmost entry",
+			remoteAddr: "10.0.0.1:4000",
+			xff:        []string{"203.0.113.5, 198.51.100.5"},
+			trusted:    trusted,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "denied client behind proxy",
+			remoteAddr: "10.0.0.1:4000",
+			xff:        []string{"198.51.100.5, 10.0.0.2"},
+			trusted:    trusted,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "malformed hop",
+			remoteAddr: "10.0.0.1:4000",
+			xff:        []string{"203.0.113.5, garbage"},
+			trusted:    trusted,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "hop with port",
+			remoteAddr: "10.0.0.1:4000",
+			xff:        []string{"203.0.113.5:5000"},
+			trusted:    trusted,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "every hop trusted",
+			remoteAddr: "10.0.0.1:4000",
+			xff:        []string{"10.0.0.3, 10.0.0.2"},
+			trusted:    trusted,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := AllowIPs(IPAllowOptions{Allowed: allowed, TrustedProxies: tt.trusted}, okHandler)
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, xff := range tt.xff {
+				req.Header.Add("X-Forwarded-For", xff)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAllowIPsDisabled(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	req.RemoteAddr = "198.51.100.5:4000"
+	rec := httptest.NewRecorder()
+	AllowIPs(IPAllowOptions{}, okHandler).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want an empty allow list to disable the check", rec.Code)
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	got, err := ParsePrefixes([]string{"10.1.2.3/8", " 192.0.2.1 ", "::ffff:192.0.2.2", "2001:db8::1", "fd00::/8"})
+	if err != nil {
+		t.Fatalf("ParsePrefixes() error = %v", err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+		netip.MustParsePrefix("192.0.2.2/32"),
+		netip.MustParsePrefix("2001:db8::1/128"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParsePrefixes() = %v, want %v", got, want)
+	}
+
+	for _, cidr := range []string{"10.0.0.0/33", "not-an-ip", ""} {
+		if _, err := ParsePrefixes([]string{cidr}); err == nil {
+			t.Errorf("ParsePrefixes(%q) succeeded", cidr)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echoBody responds with the request body it received
+var echoBody = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(body)
+})
+
+// chunkedRequest returns a request whose body length isn't known up front,
+// like a chunked upload
+func chunkedRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", io.NopCloser(strings.NewReader(body)))
+	req.ContentLength = -1
+	return req
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int64
+		req        *http.Request
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "under limit",
+			limit:      16,
+			req:        httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"id":1}`)),
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":1}`,
+		},
+		{
+			name:       "at limit",
+			limit:      8,
+			req:        httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"id":1}`)),
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":1}`,
+		},
+		{
+			name:       "over limit",
+			limit:      4,
+			req:        httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"id":1}`)),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "chunked under limit",
+			limit:      16,
+			req:        chunkedRequest(`{"id":1}`),
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":1}`,
+		},
+		{
+			name:       "chunked over limit",
+			limit:      4,
+			req:        chunkedRequest(`{"id":1}`),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "no body",
+			limit:      4,
+			req:        httptest.NewRequest(http.MethodGet, "/mcp", nil),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "disabled",
+			limit:      0,
+			req:        httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(strings.Repeat("x", 1<<20))),
+			wantStatus: http.StatusOK,
+			wantBody:   strings.Repeat("x", 1<<20),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			MaxBodyBytes(tt.limit, echoBody).ServeHTTP(rec, tt.req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && rec.Body.String() != tt.wantBody {
+				t.Errorf("handler saw a %d byte body, want %d bytes", rec.Body.Len(), len(tt.wantBody))
+			}
+		})
+	}
+}
+
+func TestMaxBodyBytesDoesNotCallNextWhenTooLarge(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	rec := httptest.NewRecorder()
+	MaxBodyBytes(4, next).ServeHTTP(rec, chunkedRequest("too large"))
+
+	if called {
+		t.Error("next handler was called for an oversized body")
+	}
+}
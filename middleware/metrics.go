@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// httpPanicsTotal counts panics recovered by Recover
+	httpPanicsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mcp_http_panics_total",
+		Help: "Panics recovered while serving HTTP requests.",
+	})
+
+	// ipDeniedTotal counts requests rejected by AllowIPs
+	ipDeniedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mcp_http_ip_denied_total",
+		Help: "HTTP requests rejected because the client IP isn't in the allowed networks.",
+	})
+
+	// requestsInFlight tracks the requests LimitInFlight is currently letting
+	// through, including open streams
+	requestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mcp_http_requests_in_flight",
+		Help: "HTTP requests to the MCP endpoint currently being handled, including open event streams.",
+	})
+
+	// inFlightRejectedTotal counts requests rejected by LimitInFlight
+	inFlightRejectedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mcp_http_in_flight_rejected_total",
+		Help: "HTTP requests rejected because the concurrent request limit was reached.",
+	})
+)
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns a panic in next into a 500 Internal Server Error, so one bad
+// request can't take down the connection or the process. Every recovered
+// panic is logged with its stack trace and counted in mcp_http_panics_total.
+// http.ErrAbortHandler is re-panicked, since net/http uses it to abort a
+// response on purpose.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			httpPanicsTotal.Inc()
+			log.Printf("Recovered panic serving %s %s (session %q): %v\n%s", r.Method, r.URL.Path, r.Header.Get(sessionIDHeader), v, debug.Stack())
+
+			// If the response has already started this only logs a warning, but
+			// the client still sees the stream end
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
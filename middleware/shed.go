@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// sessionIDHeader is the header Streamable HTTP clients use to identify an
+// existing session
+const sessionIDHeader = "Mcp-Session-Id"
+
+// LoadShedOptions configures ShedLoad
+type LoadShedOptions struct {
+	// Threshold is the backend latency above which requests are shed
+	Threshold time.Duration
+
+	// Latency reports the backend's current latency
+	Latency func() time.Duration
+
+	// ProtectExisting keeps serving requests for existing sessions while
+	// shedding, so only requests that would create a session are rejected.
+	// When false every request is shed.
+	ProtectExisting bool
+}
+
+// ShedLoad rejects requests with 503 Service Unavailable while the backend's
+// latency is above the threshold, so new sessions fail fast instead of
+// queueing behind a degraded session store. Requests without an
+// Mcp-Session-Id header are the ones that create sessions. A threshold of 0
+// or less disables shedding.
+func ShedLoad(opts LoadShedOptions, next http.Handler) http.Handler {
+	if opts.Threshold <= 0 || opts.Latency == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.ProtectExisting && r.Header.Get(sessionIDHeader) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if opts.Latency() > opts.Threshold {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "server overloaded, try again later", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
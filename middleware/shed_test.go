@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// okHandler responds 200 OK with an empty body
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+func TestShedLoad(t *testing.T) {
+	tests := []struct {
+		name            string
+		latency         time.Duration
+		protectExisting bool
+		sessionID       string
+		wantStatus      int
+	}{
+		{name: "below threshold", latency: 10 * time.Millisecond, wantStatus: http.StatusOK},
+		{name: "new session shed", latency: time.Second, protectExisting: true, wantStatus: http.StatusServiceUnavailable},
+		{name: "existing session protected", latency: time.Second, protectExisting: true, sessionID: "session-1", wantStatus: http.StatusOK},
+		{name: "existing session shed", latency: time.Second, sessionID: "session-1", wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ShedLoad(LoadShedOptions{
+				Threshold:       100 * time.Millisecond,
+				Latency:         func() time.Duration { return tt.latency },
+				ProtectExisting: tt.protectExisting,
+			}, okHandler)
+
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if tt.sessionID != "" {
+				req.Header.Set("Mcp-Session-Id", tt.sessionID)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") == "" {
+				t.Error("shed response has no Retry-After header")
+			}
+		})
+	}
+}
+
+func TestShedLoadDisabled(t *testing.T) {
+	latency := func() time.Duration { return time.Hour }
+	for _, opts := range []LoadShedOptions{{Threshold: 0, Latency: latency}, {Threshold: time.Second}} {
+		rec := httptest.NewRecorder()
+		ShedLoad(opts, okHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("ShedLoad(%+v) status = %d, want shedding disabled", opts, rec.Code)
+		}
+	}
+}
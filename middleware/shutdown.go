@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// ShutdownGuard rejects requests that arrive after shutdown has begun. Between
+// http.Server.Shutdown being called and the server finishing, connections
+// kept alive by clients can still deliver new requests, which would start
+// just in time to be cut off. The guard answers them with 503 Service
+// Unavailable and Connection: close instead, so clients retry against
+// another instance, while requests already being handled drain.
+type ShutdownGuard struct {
+	shuttingDown atomic.Bool
+}
+
+// Begin starts rejecting new requests. Call it before http.Server.Shutdown.
+func (g *ShutdownGuard) Begin() {
+	g.shuttingDown.Store(true)
+}
+
+// Handler wraps next in the guard
+func (g *ShutdownGuard) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.shuttingDown.Load() {
+			w.Header().Set("Connection", "close")
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestShutdownGuard(t *testing.T) {
+	var guard ShutdownGuard
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := guard.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status before Begin() = %d, want 200", rec.Code)
+	}
+
+	// A request already running when shutdown begins is left to finish
+	inFlight := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(inFlight, httptest.NewRequest(http.MethodPost, "/slow", nil))
+	}()
+	<-started
+	guard.Begin()
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status after Begin() = %d, want 503", rec.Code)
+	}
+	if got := rec.Header().Get("Connection"); got != "close" {
+		t.Errorf("Connection = %q, want close", got)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("rejected response has no Retry-After header")
+	}
+
+	close(release)
+	<-done
+	if inFlight.Code != http.StatusOK || inFlight.Header().Get("Connection") != "" {
+		t.Errorf("in-flight request got status %d with Connection %q, want it served normally", inFlight.Code, inFlight.Header().Get("Connection"))
+	}
+}
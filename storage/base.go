@@ -0,0 +1,789 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// sessionBackend is implemented by the storage medium behind a BaseSessionStore.
+// Keys passed to the hooks already include the configured prefix.
+type sessionBackend interface {
+	// getRaw returns the stored bytes for key, or ErrNotFound if it's missing.
+	// It never returns empty data with a nil error. Backend failures should
+	// wrap ErrUnavailable.
+	getRaw(ctx context.Context, key string) ([]byte, error)
+	// setRaw stores data under key, expiring after ttl; a zero ttl never expires
+	setRaw(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	// delRaw removes key; removing a missing key is not an error
+	delRaw(ctx context.Context, key string) error
+}
+
+// BaseSessionStore implements the parts of StreamableHTTPSessionStore that are
+// shared by every backend: the read-through cache of active transports, key
+// prefixing, serialization and reconnecting loaded sessions to the MCP server.
+// Concrete stores embed it and provide a sessionBackend for their storage medium.
+type BaseSessionStore struct {
+	backend         sessionBackend
+	prefix          string
+	ttl             time.Duration
+	server          *mcp.Server                               // Reference to the MCP server for connecting sessions
+	activeSessions  map[string]*mcp.StreamableServerTransport // Active sessions by ID
+	activeSessionMu sync.RWMutex
+	cacheHits       atomic.Uint64
+	cacheMisses     atomic.Uint64
+	closedHooksMu   sync.RWMutex
+	closedHooks     []func(sessionID string) // Called after a session is deleted
+	options         StoreOptions
+	churn           *churnTracker // Only set when options.TrackChurn is enabled
+	writeBehind     *writeBehind  // Only set when options.WriteBehind is set
+	locks           *stripedLock  // Per-session locks for WithSessionLock
+	heldLocks       *heldLocks    // Locks currently held, for Drain
+	draining        atomic.Bool   // Set by Drain; new sessions are refused
+	readOnly        atomic.Bool   // Writes are rejected while set
+}
+
+// StoreOptions holds optional behaviour shared by every backend
+type StoreOptions struct {
+	// TrackChurn checks whether each Set creates or updates a session, counting
+	// both and logging when a previously deleted session ID is re-created. It
+	// costs an extra backend read per Set, so it's meant for debugging.
+	TrackChurn bool
+
+	// MaxStateBytes rejects writes whose serialized session state is larger
+	// than this many bytes with ErrStateTooLarge. 0 means unlimited.
+	MaxStateBytes int
+
+	// LargeStateBytes logs writes whose serialized session state is larger
+	// than this many bytes, with the session ID, so runaway sessions can be
+	// found before they hit MaxStateBytes. 0 disables the log.
+	LargeStateBytes int
+
+	// CompressionMinBytes gzips records whose serialized session state is
+	// larger than this many bytes before they're written, leaving smaller
+	// ones as plain JSON, which isn't worth the CPU to compress. Records are
+	// decompressed on load whatever the setting, so it can be changed
+	// without migrating sessions. 0 never compresses.
+	CompressionMinBytes int
+
+	// TTLJitter randomizes each session's TTL by up to this fraction in either
+	// direction, e.g. 0.1 for ±10%, so sessions created together don't all
+	// expire together. It must be in [0, 1); 0 disables jitter.
+	TTLJitter float64
+
+	// DisableHTMLEscape stores <, > and & in session JSON as-is rather than as
+	// \u003c-style escapes
+	DisableHTMLEscape bool
+
+	// IndentJSON indents stored session JSON for easier inspection during
+	// development. Loading accepts both indented and compact JSON.
+	IndentJSON bool
+
+	// KeyVersion adds a key format version after the prefix, e.g. "v1" stores
+	// sessions under "mcp:session:v1:<id>", so a future change to the stored
+	// format can move to new keys instead of colliding with existing ones.
+	// Empty uses the unversioned "mcp:session:<id>" format.
+	KeyVersion string
+
+	// ReadLegacyKeys falls back to the unversioned key when a session isn't
+	// found under the versioned one, for the transition after KeyVersion is
+	// introduced. Writes always use the versioned key. Requires KeyVersion.
+	ReadLegacyKeys bool
+
+	// DisableLocalCache makes every Get read the backend instead of answering
+	// from the active sessions map, so a session deleted or expired through
+	// another instance is noticed straight away, at the cost of a backend read
+	// per request. Transports already connected on this instance are still
+	// reused once the backend confirms the session exists, since connecting a
+	// new one on every request would create a new server session each time.
+	DisableLocalCache bool
+
+	// WriteBehind receives an event after every successful Set, delivered in
+	// the background so a slow sink never delays the write. Nil disables it.
+	WriteBehind WriteBehindSink
+
+	// ReadOnly starts the store in read-only mode, see SetReadOnly
+	ReadOnly bool
+
+	// DropCorruptSessions deletes session records that can't be decoded when
+	// they're loaded and reports the session as not found, so the client can
+	// start a new one. Otherwise the load fails with ErrSerialization every
+	// time, until the record expires.
+	DropCorruptSessions bool
+
+	// CreateIfAbsent writes sessions that aren't active on this instance
+	// with StoreIfAbsent, so when two instances both treat a session as new
+	// the first write wins and the other keeps the stored record instead of
+	// overwriting it. Backends that can't create atomically fail the write.
+	CreateIfAbsent bool
+}
+
+// baseSessionStoreConfig holds the settings shared by all backends
+type baseSessionStoreConfig struct {
+	Prefix  string        // Key prefix for session storage (default: "mcp:session:")
+	TTL     time.Duration // Session TTL (default: 1 hour, NoExpiry to never expire)
+	Server  *mcp.Server   // MCP server that loaded sessions are connected to (required)
+	Options StoreOptions  // Optional behaviour
+}
+
+// errNoServer is returned when a store has no MCP server to connect sessions
+// loaded from the backend to. Any instance may load a session written by
+// another, and a loaded session can't handle requests until it's connected.
+var errNoServer = errors.New("MCP server reference is required: sessions loaded from the backend are connected to it")
+
+// NoExpiry can be used as a store TTL to keep sessions until they're deleted.
+// A zero TTL selects the default instead.
+const NoExpiry time.Duration = -1
+
+// minJitteredTTL is the shortest TTL that jitter can produce
+const minJitteredTTL = time.Second
+
+// newBaseSessionStore validates the shared configuration and creates a
+// BaseSessionStore backed by backend
+func newBaseSessionStore(backend sessionBackend, config baseSessionStoreConfig) (*BaseSessionStore, error) {
+	// Set defaults
+	if config.Prefix == "" {
+		config.Prefix = "mcp:session:"
+	}
+	if config.TTL == 0 {
+		config.TTL = time.Hour
+	}
+
+	if config.Server == nil {
+		return nil, errNoServer
+	}
+	if config.Options.TTLJitter < 0 || config.Options.TTLJitter >= 1 {
+		return nil, fmt.Errorf("TTL jitter must be at least 0 and less than 1, got %v", config.Options.TTLJitter)
+	}
+	// Key versions follow the same rules as session IDs, so they can't contain
+	// the ':' separator and keys stay unambiguous
+	if version := config.Options.KeyVersion; version != "" && ValidateSessionID(version) != nil {
+		return nil, fmt.Errorf("invalid key version %q, only letters, digits, '-' and '_' are allowed", version)
+	}
+	if config.Options.KeyVersion == lockKeyVersion {
+		return nil, fmt.Errorf("key version %q is reserved for session locks", lockKeyVersion)
+	}
+	if config.Options.KeyVersion == selfTestKeyVersion {
+		return nil, fmt.Errorf("key version %q is reserved for self-test sessions", selfTestKeyVersion)
+	}
+	if config.Options.ReadLegacyKeys && config.Options.KeyVersion == "" {
+		return nil, fmt.Errorf("reading legacy keys requires a key version")
+	}
+
+	store := &BaseSessionStore{
+		backend:        backend,
+		prefix:         config.Prefix,
+		ttl:            config.TTL,
+		server:         config.Server,
+		activeSessions: make(map[string]*mcp.StreamableServerTransport),
+		options:        config.Options,
+		locks:          newStripedLock(),
+		heldLocks:      newHeldLocks(),
+	}
+	store.readOnly.Store(config.Options.ReadOnly)
+	if config.Options.TrackChurn {
+		store.churn = newChurnTracker(recentlyDeletedCapacity)
+	}
+	if config.Options.WriteBehind != nil {
+		store.writeBehind = newWriteBehind(config.Options.WriteBehind)
+	}
+
+	return store, nil
+}
+
+// SessionData is the record stored in the backend for each session
+type SessionData struct {
+	SessionID string    `json:"session_id"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"` // When the session was last written; zero for older records
+}
+
+// Get retrieves a session, checking the active sessions before the backend.
+// It fails fast with the context's error if ctx is already done, e.g. because
+// the client disconnected.
+func (b *BaseSessionStore) Get(ctx context.Context, sessionID string) (*mcp.StreamableServerTransport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+
+	// Check active sessions first
+	if !b.options.DisableLocalCache {
+		if transport, ok := b.cached(sessionID); ok {
+			return transport, nil
+		}
+	}
+
+	data, err := b.read(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			b.evict(sessionID)
+			return nil, nil // Session not found
+		}
+		return nil, err
+	}
+
+	return b.restore(ctx, sessionID, data)
+}
+
+// cached returns a session from the active sessions map, counting the lookup
+// as a cache hit or miss
+func (b *BaseSessionStore) cached(sessionID string) (*mcp.StreamableServerTransport, bool) {
+	b.activeSessionMu.RLock()
+	defer b.activeSessionMu.RUnlock()
+	transport, ok := b.activeSessions[sessionID]
+	if ok {
+		b.cacheHits.Add(1)
+	} else {
+		b.cacheMisses.Add(1)
+	}
+	return transport, ok
+}
+
+// isActive reports whether a session is in the active sessions map, without
+// counting the lookup
+func (b *BaseSessionStore) isActive(sessionID string) bool {
+	b.activeSessionMu.RLock()
+	defer b.activeSessionMu.RUnlock()
+	_, ok := b.activeSessions[sessionID]
+	return ok
+}
+
+// restore decodes a session loaded from the backend, connects it to the MCP
+// server and adds it to the active sessions map. A session that's already
+// active is returned as-is rather than connected again. It returns nil if the
+// data belongs to a different session, or was corrupt and has been dropped.
+func (b *BaseSessionStore) restore(ctx context.Context, sessionID string, data []byte) (*mcp.StreamableServerTransport, error) {
+	// Stores built without their constructor have no server; fail the load
+	// rather than dereferencing nil
+	if b.server == nil {
+		return nil, fmt.Errorf("failed to load session %s: %w", sessionID, errNoServer)
+	}
+	if len(data) == 0 {
+		return nil, emptyRecordError(sessionID)
+	}
+
+	var sessionData SessionData
+	data, err := decompressRecord(data)
+	if err == nil {
+		if err = json.Unmarshal(data, &sessionData); err != nil {
+			err = fmt.Errorf("failed to unmarshal session data: %w: %w", ErrSerialization, err)
+		}
+	}
+	if err != nil {
+		corruptSessionsTotal.Inc()
+		if b.options.DropCorruptSessions {
+			return nil, b.dropCorrupt(sessionID, err)
+		}
+		return nil, err
+	}
+
+	// Backends may hash keys, so make sure the payload belongs to the session
+	// that was asked for.
+	if sessionData.SessionID != sessionID {
+		return nil, nil
+	}
+
+	b.activeSessionMu.RLock()
+	active, ok := b.activeSessions[sessionID]
+	b.activeSessionMu.RUnlock()
+	if ok {
+		return active, nil
+	}
+
+	transport := mcp.NewStreamableServerTransport(sessionData.SessionID, nil)
+
+	// Connect the transport to the MCP server
+	serverSession, err := b.server.Connect(ctx, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect session to server: %w", err)
+	}
+
+	// Re-initialize the session.
+	// Ideally we'll persist the client info as well from the actual initialize call, and re-hydrate it here.
+	// For now, we'll just leave it empty.
+	_, err = serverSession.Initialize(ctx, &mcp.InitializeParams{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize server session: %w", err)
+	}
+
+	// Store the transport in the active sessions map
+	b.activeSessionMu.Lock()
+	defer b.activeSessionMu.Unlock()
+	b.activeSessions[sessionID] = transport
+
+	return transport, nil
+}
+
+// dropCorrupt deletes the record of a session that can't be decoded, so its
+// client gets a not-found response and can start a new session instead of
+// failing on every request. If the record can't be deleted, e.g. because the
+// store is read-only, decodeErr is returned and the record is kept.
+func (b *BaseSessionStore) dropCorrupt(sessionID string, decodeErr error) error {
+	if err := b.Delete(sessionID); err != nil {
+		log.Printf("Failed to drop corrupt record for session %s (%v): %v", sessionID, decodeErr, err)
+		return decodeErr
+	}
+	log.Printf("Dropped corrupt record for session %s: %v", sessionID, decodeErr)
+	return nil
+}
+
+// Set stores a session in the backend. The session is only added to the
+// active sessions map once the write has succeeded, so a session that fails
+// to serialize or persist is never served from the cache. With
+// CreateIfAbsent, sessions that aren't active on this instance are written
+// with StoreIfAbsent instead.
+func (b *BaseSessionStore) Set(sessionID string, session *mcp.StreamableServerTransport) error {
+	ctx := context.Background()
+	if b.options.CreateIfAbsent && !b.isActive(sessionID) {
+		_, err := b.StoreIfAbsent(ctx, sessionID, session)
+		return err
+	}
+
+	sessionData, data, err := b.encodeSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	// Only count writes that are actually attempted
+	if b.churn != nil {
+		b.recordWrite(ctx, sessionID)
+	}
+
+	if err := b.backend.setRaw(ctx, b.getKey(sessionID), data, b.expiration()); err != nil {
+		return err
+	}
+	b.written(sessionData, data)
+	b.activate(sessionID, session)
+	return nil
+}
+
+// encodeSession checks a session can be written and returns its record. The
+// size of the encoded record is observed here, so every write is measured
+// exactly once from the bytes it stores.
+func (b *BaseSessionStore) encodeSession(sessionID string) (SessionData, []byte, error) {
+	if err := ValidateSessionID(sessionID); err != nil {
+		return SessionData{}, nil, err
+	}
+	if err := b.checkWritable(sessionID); err != nil {
+		return SessionData{}, nil, err
+	}
+	if b.draining.Load() && !b.isActive(sessionID) {
+		return SessionData{}, nil, fmt.Errorf("session %s not created: %w", sessionID, ErrShuttingDown)
+	}
+
+	// NOTE: This is a simplified serialization. In a real implementation,
+	// you would need to serialize the actual session state properly.
+	// The StreamableServerTransport might need additional methods to support
+	// serialization, or you might need to store only the essential state.
+	sessionData := SessionData{
+		SessionID: sessionID,
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	data, err := b.marshal(sessionData)
+	if err != nil {
+		return SessionData{}, nil, fmt.Errorf("failed to marshal data for session %s: %w: %w", sessionID, ErrSerialization, err)
+	}
+
+	sessionStateBytes.Observe(float64(len(data)))
+	if limit := b.options.MaxStateBytes; limit > 0 && len(data) > limit {
+		sessionStateTooLargeTotal.Inc()
+		log.Printf("Rejected write for session %s: state is %d bytes, limit is %d", sessionID, len(data), limit)
+		return SessionData{}, nil, fmt.Errorf("session %s state is %d bytes, limit is %d: %w", sessionID, len(data), limit, ErrStateTooLarge)
+	}
+	if threshold := b.options.LargeStateBytes; threshold > 0 && len(data) > threshold {
+		log.Printf("Large state for session %s: %d bytes, above the %d byte threshold", sessionID, len(data), threshold)
+	}
+
+	if data, err = b.compress(data); err != nil {
+		return SessionData{}, nil, fmt.Errorf("failed to compress data for session %s: %w: %w", sessionID, ErrSerialization, err)
+	}
+	return sessionData, data, nil
+}
+
+// written reports a successful write to the write-behind sink, if any
+func (b *BaseSessionStore) written(sessionData SessionData, data []byte) {
+	if b.writeBehind != nil {
+		b.writeBehind.enqueue(WriteEvent{
+			Time:      sessionData.UpdatedAt,
+			SessionID: sessionData.SessionID,
+			Bytes:     len(data),
+		})
+	}
+}
+
+// activate stores the transport in the active sessions map. Sessions loaded
+// from the backend are already there, so a new entry means a new session.
+func (b *BaseSessionStore) activate(sessionID string, session *mcp.StreamableServerTransport) {
+	b.activeSessionMu.Lock()
+	defer b.activeSessionMu.Unlock()
+	if _, ok := b.activeSessions[sessionID]; !ok {
+		sessionLifecycleTotal.WithLabelValues("created").Inc()
+		sessionsActive.Inc()
+	}
+	b.activeSessions[sessionID] = session
+}
+
+// Delete removes a session from the backend
+func (b *BaseSessionStore) Delete(sessionID string) error {
+	if err := ValidateSessionID(sessionID); err != nil {
+		return err
+	}
+	if err := b.checkWritable(sessionID); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	if err := b.backend.delRaw(ctx, b.getKey(sessionID)); err != nil {
+		return err
+	}
+	if b.options.ReadLegacyKeys {
+		if err := b.backend.delRaw(ctx, b.legacyKey(sessionID)); err != nil {
+			return err
+		}
+	}
+
+	// Delete from active sessions map. The handler loads a session before
+	// deleting it, so only sessions that were active are counted.
+	b.activeSessionMu.Lock()
+	if _, ok := b.activeSessions[sessionID]; ok {
+		sessionLifecycleTotal.WithLabelValues("deleted").Inc()
+		sessionsActive.Dec()
+	}
+	delete(b.activeSessions, sessionID)
+	b.activeSessionMu.Unlock()
+
+	if b.churn != nil {
+		b.churn.deleted(sessionID)
+	}
+
+	b.closedHooksMu.RLock()
+	hooks := b.closedHooks
+	b.closedHooksMu.RUnlock()
+	for _, hook := range hooks {
+		hook(sessionID)
+	}
+
+	return nil
+}
+
+// WarmCache loads up to limit of the most recently written sessions from the
+// backend into the active sessions map, so the first requests after a
+// restart don't all miss the cache. It needs a backend that implements
+// SessionLister, and returns the number of sessions loaded.
+func (b *BaseSessionStore) WarmCache(ctx context.Context, limit int) (int, error) {
+	lister, ok := b.backend.(SessionLister)
+	if !ok {
+		return 0, fmt.Errorf("session store doesn't support listing sessions")
+	}
+	sessionIDs, err := lister.ListSessions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	type candidate struct {
+		data      []byte
+		sessionID string
+		updatedAt time.Time
+	}
+	candidates := make([]candidate, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		data, err := b.read(ctx, sessionID)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // Expired since it was listed
+		}
+		if err != nil {
+			return 0, err
+		}
+		if data, err = decompressRecord(data); err != nil {
+			log.Printf("Skipping session %s while warming the cache: %v", sessionID, err)
+			continue
+		}
+		var sessionData SessionData
+		if err := json.Unmarshal(data, &sessionData); err != nil {
+			log.Printf("Skipping session %s while warming the cache: %v", sessionID, err)
+			continue
+		}
+		candidates = append(candidates, candidate{data, sessionID, sessionData.UpdatedAt})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].updatedAt.After(candidates[j].updatedAt)
+	})
+
+	loaded := 0
+	for _, c := range candidates {
+		if loaded >= limit {
+			break
+		}
+		b.activeSessionMu.RLock()
+		_, active := b.activeSessions[c.sessionID]
+		b.activeSessionMu.RUnlock()
+		if active {
+			continue
+		}
+		transport, err := b.restore(ctx, c.sessionID, c.data)
+		if err != nil {
+			return loaded, err
+		}
+		if transport != nil {
+			loaded++
+		}
+	}
+	return loaded, nil
+}
+
+// Inspect returns the record stored for a session without connecting it to
+// the MCP server, caching it or refreshing its TTL. It returns ErrNotFound if
+// the session doesn't exist. Compressed records are returned decompressed, so
+// the result is always the record's JSON.
+func (b *BaseSessionStore) Inspect(ctx context.Context, sessionID string) ([]byte, error) {
+	data, err := b.InspectRaw(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return decompressRecord(data)
+}
+
+// InspectRaw is Inspect without decompression: it returns the bytes stored
+// for a session exactly as they are, with chunks reassembled
+func (b *BaseSessionStore) InspectRaw(ctx context.Context, sessionID string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ValidateSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	return b.read(ctx, sessionID)
+}
+
+// Import writes a stored session record as-is, e.g. one read back from a
+// snapshot, without connecting it to the MCP server or caching it. The record
+// must belong to sessionID. A ttl of 0 uses the store's TTL; a positive ttl
+// is used even if the store's sessions don't otherwise expire. data is the
+// record's JSON, compressed like any other write if it's large enough.
+func (b *BaseSessionStore) Import(ctx context.Context, sessionID string, data []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ValidateSessionID(sessionID); err != nil {
+		return err
+	}
+	if err := b.checkWritable(sessionID); err != nil {
+		return err
+	}
+
+	var sessionData SessionData
+	if err := json.Unmarshal(data, &sessionData); err != nil {
+		return fmt.Errorf("failed to unmarshal session data: %w: %w", ErrSerialization, err)
+	}
+	if sessionData.SessionID != sessionID {
+		return fmt.Errorf("record for session %q can't be imported as session %s: %w", sessionData.SessionID, sessionID, ErrSerialization)
+	}
+
+	if ttl <= 0 {
+		ttl = b.expiration()
+	}
+	data, err := b.compress(data)
+	if err != nil {
+		return fmt.Errorf("failed to compress data for session %s: %w: %w", sessionID, ErrSerialization, err)
+	}
+	if err := b.backend.setRaw(ctx, b.getKey(sessionID), data, ttl); err != nil {
+		return err
+	}
+	if b.writeBehind != nil {
+		b.writeBehind.enqueue(WriteEvent{
+			Time:      time.Now().UTC(),
+			SessionID: sessionID,
+			Bytes:     len(data),
+		})
+	}
+	return nil
+}
+
+// OnSessionClosed registers a function that's called after a session has been
+// deleted, e.g. when the client sends an HTTP DELETE for it. Subsystems that
+// keep in-memory state keyed by session ID use it to release that state.
+// Hooks run synchronously on the deleting goroutine, so they should be quick.
+func (b *BaseSessionStore) OnSessionClosed(hook func(sessionID string)) {
+	b.closedHooksMu.Lock()
+	defer b.closedHooksMu.Unlock()
+	b.closedHooks = append(b.closedHooks[:len(b.closedHooks):len(b.closedHooks)], hook)
+}
+
+// Range iterates over all active sessions
+func (b *BaseSessionStore) Range(f func(sessionID string, session *mcp.StreamableServerTransport)) {
+	b.activeSessionMu.RLock()
+	defer b.activeSessionMu.RUnlock()
+	for sessionID, session := range b.activeSessions {
+		f(sessionID, session)
+	}
+}
+
+// recordWrite counts a Set as a create or an update depending on whether the
+// session already exists, and logs re-creation of recently deleted IDs
+func (b *BaseSessionStore) recordWrite(ctx context.Context, sessionID string) {
+	_, err := b.read(ctx, sessionID)
+	switch {
+	case err == nil:
+		sessionWritesTotal.WithLabelValues("update").Inc()
+	case errors.Is(err, fs.ErrNotExist):
+		sessionWritesTotal.WithLabelValues("create").Inc()
+		if b.churn.wasDeleted(sessionID) {
+			log.Printf("Session %s was re-created after being deleted, possible session ID reuse", sessionID)
+		}
+	default:
+		log.Printf("Failed to check whether session %s exists: %v", sessionID, err)
+	}
+}
+
+// CacheStats returns the number of Get calls served from the active sessions
+// cache and the number that had to go to the backend
+func (b *BaseSessionStore) CacheStats() (hits, misses uint64) {
+	return b.cacheHits.Load(), b.cacheMisses.Load()
+}
+
+// CacheHitRatio returns the fraction of Get calls served from the active
+// sessions cache, or 0 if there haven't been any
+func (b *BaseSessionStore) CacheHitRatio() float64 {
+	hits, misses := b.CacheStats()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// marshal encodes a value for storage according to the store's JSON options.
+// With the defaults it produces the same compact, escaped output as json.Marshal.
+func (b *BaseSessionStore) marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(!b.options.DisableHTMLEscape)
+	if b.options.IndentJSON {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// expiration returns the TTL passed to the backend, where 0 means no expiry.
+// With TTLJitter set, each call returns a different TTL around the configured one.
+func (b *BaseSessionStore) expiration() time.Duration {
+	if b.ttl < 0 {
+		return 0
+	}
+	if b.options.TTLJitter == 0 {
+		return b.ttl
+	}
+
+	factor := 1 + b.options.TTLJitter*(2*rand.Float64()-1)
+	ttl := time.Duration(float64(b.ttl) * factor)
+	if ttl < minJitteredTTL {
+		ttl = minJitteredTTL
+	}
+	return ttl
+}
+
+// read returns the stored bytes for a session, falling back to its legacy
+// key when ReadLegacyKeys is set
+func (b *BaseSessionStore) read(ctx context.Context, sessionID string) ([]byte, error) {
+	data, err := b.backend.getRaw(ctx, b.getKey(sessionID))
+	if errors.Is(err, fs.ErrNotExist) && b.options.ReadLegacyKeys {
+		data, err = b.backend.getRaw(ctx, b.legacyKey(sessionID))
+	}
+	if err == nil && len(data) == 0 {
+		return nil, emptyRecordError(sessionID)
+	}
+	return data, err
+}
+
+// emptyRecordError reports a backend read that returned neither data nor an
+// error. Every stored record is a JSON object, so this is a backend bug, and
+// treating it as a missing session would hide it.
+func emptyRecordError(sessionID string) error {
+	return fmt.Errorf("session store returned an empty record for session %s: %w", sessionID, ErrSerialization)
+}
+
+// evict drops a session that's no longer in the backend from the active
+// sessions map. It only has work to do when DisableLocalCache is set, since
+// otherwise sessions in the map are never read from the backend.
+func (b *BaseSessionStore) evict(sessionID string) {
+	if !b.options.DisableLocalCache {
+		return
+	}
+	b.activeSessionMu.Lock()
+	defer b.activeSessionMu.Unlock()
+	delete(b.activeSessions, sessionID)
+}
+
+// closeBase stops the background work shared by every backend, delivering
+// any queued write-behind events. Stores call it from Close.
+func (b *BaseSessionStore) closeBase() {
+	if b.writeBehind != nil {
+		b.writeBehind.close()
+	}
+}
+
+// getKey generates a storage key for a session ID
+func (b *BaseSessionStore) getKey(sessionID string) string {
+	if b.options.KeyVersion != "" {
+		return b.prefix + b.options.KeyVersion + ":" + sessionID
+	}
+	return b.prefix + sessionID
+}
+
+// legacyKey generates the unversioned storage key for a session ID
+func (b *BaseSessionStore) legacyKey(sessionID string) string {
+	return b.prefix + sessionID
+}
+
+// sessionIDsFromKeys extracts the session IDs from keys listed under the
+// store's prefix. Keys in another key format version are skipped, and with
+// ReadLegacyKeys a session stored under both keys is only returned once.
+func (b *BaseSessionStore) sessionIDsFromKeys(keys []string) []string {
+	seen := make(map[string]bool, len(keys))
+	sessionIDs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		rest, ok := strings.CutPrefix(key, b.prefix)
+		if !ok {
+			continue
+		}
+
+		// Session IDs can't contain ':', so it only appears after a version
+		version, sessionID, versioned := strings.Cut(rest, ":")
+		switch {
+		case !versioned && (b.options.KeyVersion == "" || b.options.ReadLegacyKeys):
+			sessionID = rest
+		case versioned && b.options.KeyVersion != "" && version == b.options.KeyVersion:
+		default:
+			continue
+		}
+		// Keys derived from a session key, like Redis chunks, add ':'s
+		if strings.Contains(sessionID, ":") {
+			continue
+		}
+
+		if !seen[sessionID] {
+			seen[sessionID] = true
+			sessionIDs = append(sessionIDs, sessionID)
+		}
+	}
+	return sessionIDs
+}
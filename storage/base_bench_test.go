@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// Benchmarks for the session store hot path, run against miniredis so they
+// measure the store's own overhead rather than the network. Run them with
+//
+//	go test -run '^$' -bench . -benchmem ./storage/
+//
+// or make bench, and compare runs with benchstat.
+
+// uncache drops a session from the store's active sessions, so the next Get
+// reads it from the backend
+func uncache(store *BaseSessionStore, sessionID string) {
+	store.activeSessionMu.Lock()
+	defer store.activeSessionMu.Unlock()
+	delete(store.activeSessions, sessionID)
+}
+
+func BenchmarkGetCacheHit(b *testing.B) {
+	store, _ := newTestRedisStore(b, RedisSessionStoreConfig{})
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		b.Fatal(err)
+	}
+	ctx := b.Context()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := store.Get(ctx, "session-1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetCacheMiss(b *testing.B) {
+	store, _ := newTestRedisStore(b, RedisSessionStoreConfig{})
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		b.Fatal(err)
+	}
+	ctx := b.Context()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		uncache(store.BaseSessionStore, "session-1")
+		if transport, err := store.Get(ctx, "session-1"); err != nil || transport == nil {
+			b.Fatalf("Get() = %v, %v", transport, err)
+		}
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	store, _ := newTestRedisStore(b, RedisSessionStoreConfig{})
+	transport := newTestTransport("session-1")
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := store.Set("session-1", transport); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalSessionData(b *testing.B) {
+	store, _ := newTestRedisStore(b, RedisSessionStoreConfig{})
+	data := SessionData{SessionID: "3f2b9c1e-7d4a-4e8b-9a6f-1c2d3e4f5a6b", UpdatedAt: time.Now().UTC()}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := store.marshal(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
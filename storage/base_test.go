@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// memoryBackend is a sessionBackend keeping records in a map, for testing
+// BaseSessionStore without a real storage medium
+type memoryBackend struct {
+	mu     sync.Mutex
+	data   map[string][]byte
+	ttls   map[string]time.Duration
+	gets   int
+	setErr error // Returned by setRaw when set
+	getErr error // Returned by getRaw when set
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{
+		data: make(map[string][]byte),
+		ttls: make(map[string]time.Duration),
+	}
+}
+
+func (m *memoryBackend) getRaw(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gets++
+	if m.getErr != nil {
+		return nil, m.getErr
+	}
+	data, ok := m.data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return slices.Clone(data), nil
+}
+
+func (m *memoryBackend) setRaw(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.setErr != nil {
+		return m.setErr
+	}
+	m.data[key] = slices.Clone(data)
+	m.ttls[key] = ttl
+	return nil
+}
+
+func (m *memoryBackend) delRaw(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	delete(m.ttls, key)
+	return nil
+}
+
+// keys returns the stored keys in order
+func (m *memoryBackend) keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Sorted(maps.Keys(m.data))
+}
+
+func (m *memoryBackend) getCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gets
+}
+
+// newTestBaseStore returns a BaseSessionStore over backend with the given
+// options and the default prefix and TTL
+func newTestBaseStore(t *testing.T, backend sessionBackend, options StoreOptions) *BaseSessionStore {
+	t.Helper()
+	store, err := newBaseSessionStore(backend, baseSessionStoreConfig{
+		Server:  newTestServer(),
+		Options: options,
+	})
+	if err != nil {
+		t.Fatalf("newBaseSessionStore() error = %v", err)
+	}
+	t.Cleanup(store.closeBase)
+	return store
+}
+
+func TestBaseSessionStoreServesActiveSessionsFromCache(t *testing.T) {
+	backend := newMemoryBackend()
+	store := newTestBaseStore(t, backend, StoreOptions{})
+
+	transport := newTestTransport("session-1")
+	if err := store.Set("session-1", transport); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	for range 2 {
+		got, err := store.Get(t.Context(), "session-1")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if got != transport {
+			t.Fatal("Get() didn't return the active transport")
+		}
+	}
+
+	if hits, misses := store.CacheStats(); hits != 2 || misses != 0 {
+		t.Errorf("CacheStats() = %d, %d, want 2, 0", hits, misses)
+	}
+	if gets := backend.getCount(); gets != 0 {
+		t.Errorf("backend reads = %d, want 0", gets)
+	}
+}
+
+func TestBaseSessionStoreLoadsFromBackend(t *testing.T) {
+	backend := newMemoryBackend()
+	writer := newTestBaseStore(t, backend, StoreOptions{})
+	if err := writer.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	store := newTestBaseStore(t, backend, StoreOptions{})
+	first, err := store.Get(t.Context(), "session-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if first == nil || first.SessionID() != "session-1" {
+		t.Fatalf("Get() = %v, want session-1", first)
+	}
+	second, err := store.Get(t.Context(), "session-1")
+	if err != nil {
+		t.Fatalf("second Get() error = %v", err)
+	}
+	if second != first {
+		t.Error("second Get() connected a new transport instead of reusing the loaded one")
+	}
+
+	if hits, misses := store.CacheStats(); hits != 1 || misses != 1 {
+		t.Errorf("CacheStats() = %d, %d, want 1, 1", hits, misses)
+	}
+	if gets := backend.getCount(); gets != 1 {
+		t.Errorf("backend reads = %d, want 1", gets)
+	}
+}
+
+func TestBaseSessionStoreMissingSession(t *testing.T) {
+	store := newTestBaseStore(t, newMemoryBackend(), StoreOptions{})
+
+	if got, err := store.Get(t.Context(), "missing"); got != nil || err != nil {
+		t.Errorf("Get() = %v, %v, want nil, nil", got, err)
+	}
+	if _, err := store.Inspect(t.Context(), "missing"); !errors.Is(err, ErrNotFound) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Inspect() error = %v, want ErrNotFound wrapping fs.ErrNotExist", err)
+	}
+}
+
+func TestBaseSessionStoreBackendErrors(t *testing.T) {
+	backend := newMemoryBackend()
+	store := newTestBaseStore(t, backend, StoreOptions{})
+
+	backend.setErr = ErrUnavailable
+	if err := store.Set("session-1", newTestTransport("session-1")); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Set() error = %v, want ErrUnavailable", err)
+	}
+	store.Range(func(sessionID string, _ *mcp.StreamableServerTransport) {
+		t.Errorf("session %s is active after a failed write", sessionID)
+	})
+
+	backend.getErr = ErrUnavailable
+	if _, err := store.Get(t.Context(), "session-1"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Get() error = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestBaseSessionStoreDelete(t *testing.T) {
+	backend := newMemoryBackend()
+	store := newTestBaseStore(t, backend, StoreOptions{})
+
+	var closed []string
+	store.OnSessionClosed(func(sessionID string) {
+		closed = append(closed, sessionID)
+	})
+
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Delete("session-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if keys := backend.keys(); len(keys) != 0 {
+		t.Errorf("backend keys after Delete() = %v, want none", keys)
+	}
+	if !slices.Equal(closed, []string{"session-1"}) {
+		t.Errorf("closed hooks ran for %v, want [session-1]", closed)
+	}
+	if got, err := store.Get(t.Context(), "session-1"); got != nil || err != nil {
+		t.Errorf("Get() after Delete() = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestBaseSessionStoreKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  baseSessionStoreConfig
+		wantKey string
+	}{
+		{name: "default prefix", wantKey: "mcp:session:session-1"},
+		{name: "custom prefix", config: baseSessionStoreConfig{Prefix: "app:"}, wantKey: "app:session-1"},
+		{name: "key version", config: baseSessionStoreConfig{Options: StoreOptions{KeyVersion: "v1"}}, wantKey: "mcp:session:v1:session-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newMemoryBackend()
+			tt.config.Server = newTestServer()
+			store, err := newBaseSessionStore(backend, tt.config)
+			if err != nil {
+				t.Fatalf("newBaseSessionStore() error = %v", err)
+			}
+			if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if keys := backend.keys(); !slices.Equal(keys, []string{tt.wantKey}) {
+				t.Errorf("backend keys = %v, want [%s]", keys, tt.wantKey)
+			}
+			if ttl := backend.ttls[tt.wantKey]; ttl != time.Hour {
+				t.Errorf("TTL = %s, want the 1h default", ttl)
+			}
+		})
+	}
+}
+
+func TestNewBaseSessionStoreRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  baseSessionStoreConfig
+		wantErr error
+	}{
+		{name: "no server", wantErr: errNoServer},
+		{name: "negative jitter", config: baseSessionStoreConfig{Options: StoreOptions{TTLJitter: -0.1}}},
+		{name: "jitter of 1", config: baseSessionStoreConfig{Options: StoreOptions{TTLJitter: 1}}},
+		{name: "key version with separator", config: baseSessionStoreConfig{Options: StoreOptions{KeyVersion: "v1:x"}}},
+		{name: "lock key version", config: baseSessionStoreConfig{Options: StoreOptions{KeyVersion: lockKeyVersion}}},
+		{name: "self-test key version", config: baseSessionStoreConfig{Options: StoreOptions{KeyVersion: selfTestKeyVersion}}},
+		{name: "legacy keys without version", config: baseSessionStoreConfig{Options: StoreOptions{ReadLegacyKeys: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr == nil {
+				tt.config.Server = newTestServer()
+			}
+			_, err := newBaseSessionStore(newMemoryBackend(), tt.config)
+			if err == nil {
+				t.Fatal("newBaseSessionStore() succeeded, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("newBaseSessionStore() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBaseSessionStoreMaxStateBytes(t *testing.T) {
+	backend := newMemoryBackend()
+	store := newTestBaseStore(t, backend, StoreOptions{MaxStateBytes: 16})
+	rejected := testutil.ToFloat64(sessionStateTooLargeTotal)
+
+	err := store.Set("session-1", newTestTransport("session-1"))
+	if !errors.Is(err, ErrStateTooLarge) {
+		t.Fatalf("Set() error = %v, want ErrStateTooLarge", err)
+	}
+	if status := HTTPStatus(err); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("HTTPStatus() = %d, want 413", status)
+	}
+	if keys := backend.keys(); len(keys) != 0 {
+		t.Errorf("backend keys = %v, want none", keys)
+	}
+	store.Range(func(sessionID string, _ *mcp.StreamableServerTransport) {
+		t.Errorf("rejected session %s is active", sessionID)
+	})
+	if got := testutil.ToFloat64(sessionStateTooLargeTotal) - rejected; got != 1 {
+		t.Errorf("rejected writes counted = %v, want 1", got)
+	}
+
+	// Records within the limit, or with no limit, are written
+	for _, limit := range []int{1024, 0} {
+		store := newTestBaseStore(t, backend, StoreOptions{MaxStateBytes: limit})
+		if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+			t.Errorf("Set() with a limit of %d error = %v", limit, err)
+		}
+	}
+}
+
+func TestBaseSessionStoreServer(t *testing.T) {
+	t.Run("required", func(t *testing.T) {
+		_, err := NewRedisSessionStore(RedisSessionStoreConfig{Addr: "localhost:6379"})
+		if !errors.Is(err, errNoServer) {
+			t.Errorf("NewRedisSessionStore() without a server error = %v, want errNoServer", err)
+		}
+	})
+
+	backend := newMemoryBackend()
+	writer := newTestBaseStore(t, backend, StoreOptions{})
+	if err := writer.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("loaded sessions are connected", func(t *testing.T) {
+		server := newTestServer()
+		store, err := newBaseSessionStore(backend, baseSessionStoreConfig{Server: server})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(store.closeBase)
+		if _, err := store.Get(t.Context(), "session-1"); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if sessions := slices.Collect(server.Sessions()); len(sessions) != 1 {
+			t.Errorf("server has %d sessions, want the loaded session connected", len(sessions))
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		// A store built without its constructor fails the load rather than
+		// dereferencing a nil server
+		store := newTestBaseStore(t, backend, StoreOptions{})
+		store.server = nil
+		got, err := store.Get(t.Context(), "session-1")
+		if got != nil || !errors.Is(err, errNoServer) {
+			t.Errorf("Get() = %v, %v, want errNoServer", got, err)
+		}
+		store.Range(func(sessionID string, _ *mcp.StreamableServerTransport) {
+			t.Errorf("session %s is active without being connected", sessionID)
+		})
+	})
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisChunkManifestPrefix starts the value stored under a session's key when
+// its record is split into chunks, followed by the chunk count. Records are
+// JSON objects, so they can't start with it.
+const redisChunkManifestPrefix = "mcp-chunked:"
+
+// redisChunkedSetScript writes a record and its chunks, if it has any, and
+// deletes chunks the previous record had beyond the new count, all with the
+// same TTL. As a script it's atomic, so readers never see a manifest with
+// another write's chunks. With NX it writes nothing if the key exists.
+//
+// KEYS[1] is the session key. ARGV holds the TTL in milliseconds (0 for no
+// expiry), "NX" or "", the value for the session key and then the chunks.
+var redisChunkedSetScript = redis.NewScript(`
+local old = redis.call("GET", KEYS[1])
+if old and ARGV[2] == "NX" then
+	return 0
+end
+local oldCount = 0
+if old then
+	local n = string.match(old, "^mcp%-chunked:(%d+)$")
+	if n then
+		oldCount = tonumber(n)
+	end
+end
+local ttl = tonumber(ARGV[1])
+local function set(key, value)
+	if ttl > 0 then
+		redis.call("SET", key, value, "PX", ttl)
+	else
+		redis.call("SET", key, value)
+	end
+end
+set(KEYS[1], ARGV[3])
+local count = #ARGV - 3
+for i = 1, count do
+	set(KEYS[1] .. ":chunk:" .. (i - 1), ARGV[i + 3])
+end
+for i = count, oldCount - 1 do
+	redis.call("DEL", KEYS[1] .. ":chunk:" .. i)
+end
+return 1
+`)
+
+// redisChunkedGetScript reads a record, reassembling it from its chunks if
+// it was split, in one atomic step
+var redisChunkedGetScript = redis.NewScript(`
+local value = redis.call("GET", KEYS[1])
+if not value then
+	return false
+end
+local n = string.match(value, "^mcp%-chunked:(%d+)$")
+if not n then
+	return value
+end
+local chunks = {}
+for i = 0, tonumber(n) - 1 do
+	local chunk = redis.call("GET", KEYS[1] .. ":chunk:" .. i)
+	if not chunk then
+		return redis.error_reply("CHUNKMISSING chunk " .. i .. " of " .. n .. " is missing")
+	end
+	chunks[#chunks + 1] = chunk
+end
+return table.concat(chunks)
+`)
+
+// redisChunkedDelScript deletes a record along with its chunks
+var redisChunkedDelScript = redis.NewScript(`
+local value = redis.call("GET", KEYS[1])
+if not value then
+	return 0
+end
+local n = string.match(value, "^mcp%-chunked:(%d+)$")
+if n then
+	for i = 0, tonumber(n) - 1 do
+		redis.call("DEL", KEYS[1] .. ":chunk:" .. i)
+	end
+end
+return redis.call("DEL", KEYS[1])
+`)
+
+// redisChunkedExpireScript resets the TTL of a record and its chunks,
+// returning 0 if the record doesn't exist. ARGV[1] is the TTL in milliseconds.
+var redisChunkedExpireScript = redis.NewScript(`
+if redis.call("PEXPIRE", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+local n = string.match(redis.call("GET", KEYS[1]), "^mcp%-chunked:(%d+)$")
+if n then
+	for i = 0, tonumber(n) - 1 do
+		redis.call("PEXPIRE", KEYS[1] .. ":chunk:" .. i, ARGV[1])
+	end
+end
+return 1
+`)
+
+// isChunkManifest reports whether a value read from a session key is a
+// chunk manifest rather than a record
+func isChunkManifest(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(redisChunkManifestPrefix))
+}
+
+// getChunked reads a record that was split into chunks. A missing chunk
+// means the record can't be reassembled, so it's reported as corrupt.
+func (r *RedisSessionStore) getChunked(ctx context.Context, key string) ([]byte, error) {
+	data, err := redisChunkedGetScript.Run(ctx, r.redisClient(), []string{key}).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) && redis.HasErrorPrefix(redisErr, "CHUNKMISSING") {
+		return nil, fmt.Errorf("failed to reassemble session from Redis: %w: %w", ErrSerialization, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session from Redis: %w: %w", ErrUnavailable, redisClusterError(err))
+	}
+	return []byte(data), nil
+}
+
+// setChunked writes a record, split into chunks of at most r.chunkBytes if
+// it's larger than that, reporting whether it was written. With nx nothing
+// is written if the session key exists.
+func (r *RedisSessionStore) setChunked(ctx context.Context, key string, data []byte, ttl time.Duration, nx bool) (bool, error) {
+	flag := ""
+	if nx {
+		flag = "NX"
+	}
+	args := []any{ttl.Milliseconds(), flag}
+	if len(data) <= r.chunkBytes {
+		args = append(args, data)
+	} else {
+		count := (len(data) + r.chunkBytes - 1) / r.chunkBytes
+		args = append(args, redisChunkManifestPrefix+strconv.Itoa(count))
+		for start := 0; start < len(data); start += r.chunkBytes {
+			args = append(args, data[start:min(start+r.chunkBytes, len(data))])
+		}
+	}
+
+	written, err := redisChunkedSetScript.Run(ctx, r.redisClient(), []string{key}, args...).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to set session in Redis: %w: %w", ErrUnavailable, redisClusterError(err))
+	}
+	return written == 1, nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// chunkKeys returns the chunk keys stored for key
+func chunkKeys(mr *miniredis.Miniredis, key string) []string {
+	var chunks []string
+	for _, k := range mr.Keys() {
+		if strings.HasPrefix(k, key+":chunk:") {
+			chunks = append(chunks, k)
+		}
+	}
+	return chunks
+}
+
+func TestRedisSessionStoreChunking(t *testing.T) {
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{ChunkBytes: 16, TTL: 10 * time.Minute})
+	ctx := t.Context()
+	const key = "mcp:session:session-1"
+
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	record, err := store.Inspect(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	if !strings.Contains(string(record), `"session_id":"session-1"`) {
+		t.Fatalf("reassembled record = %s", record)
+	}
+
+	// The record is split into chunks of at most 16 bytes, all sharing the TTL
+	wantChunks := (len(record) + 15) / 16
+	manifest, err := mr.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest != redisChunkManifestPrefix+strconv.Itoa(wantChunks) {
+		t.Errorf("manifest = %q, want %d chunks", manifest, wantChunks)
+	}
+	chunks := chunkKeys(mr, key)
+	if len(chunks) != wantChunks {
+		t.Fatalf("chunk keys = %v, want %d", chunks, wantChunks)
+	}
+	for _, chunk := range chunks {
+		if value, _ := mr.Get(chunk); len(value) > 16 {
+			t.Errorf("chunk %s is %d bytes, want at most 16", chunk, len(value))
+		}
+	}
+	for _, chunk := range append(chunks, key) {
+		if ttl := mr.TTL(chunk); ttl != 10*time.Minute {
+			t.Errorf("TTL of %s = %s, want 10m", chunk, ttl)
+		}
+	}
+
+	// Another instance reassembles the record when it loads the session
+	other := newTestRedisStoreAt(t, mr, RedisSessionStoreConfig{ChunkBytes: 16})
+	if transport, err := other.Get(ctx, "session-1"); err != nil || transport == nil || transport.SessionID() != "session-1" {
+		t.Fatalf("Get() from another instance = %v, %v, want session-1", transport, err)
+	}
+	if sessions, err := other.ListSessions(ctx); err != nil || len(sessions) != 1 || sessions[0] != "session-1" {
+		t.Errorf("ListSessions() = %v, %v, want just session-1", sessions, err)
+	}
+
+	// Touch refreshes every chunk
+	mr.FastForward(5 * time.Minute)
+	if err := store.Touch(ctx, "session-1"); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	for _, chunk := range append(chunks, key) {
+		if ttl := mr.TTL(chunk); ttl != 10*time.Minute {
+			t.Errorf("TTL of %s after Touch() = %s, want 10m", chunk, ttl)
+		}
+	}
+
+	if err := store.Delete("session-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("keys after Delete() = %v, want none", keys)
+	}
+}
+
+func TestRedisSessionStoreChunkingThreshold(t *testing.T) {
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{ChunkBytes: 16})
+	const key = "mcp:session:session-1"
+
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if len(chunkKeys(mr, key)) == 0 {
+		t.Fatal("record wasn't split into chunks")
+	}
+
+	// Rewritten by a store with a threshold above the record's size, the
+	// record goes back to a single key and the old chunks are removed
+	large := newTestRedisStoreAt(t, mr, RedisSessionStoreConfig{ChunkBytes: 4096})
+	if err := large.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if chunks := chunkKeys(mr, key); len(chunks) != 0 {
+		t.Errorf("stale chunks %v left after a single-key write", chunks)
+	}
+	value, err := mr.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(value, "{") {
+		t.Errorf("small record stored as %q, want plain JSON", value)
+	}
+	if _, err := store.Inspect(t.Context(), "session-1"); err != nil {
+		t.Errorf("Inspect() of a single-key record by a chunking store error = %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package storage
+
+import "sync"
+
+// recentlyDeletedCapacity bounds how many deleted session IDs are remembered
+// for re-creation logging
+const recentlyDeletedCapacity = 1024
+
+// churnTracker remembers the most recently deleted session IDs, evicting the
+// oldest once it's full
+type churnTracker struct {
+	mu       sync.Mutex
+	capacity int
+	order    []string
+	ids      map[string]struct{}
+}
+
+func newChurnTracker(capacity int) *churnTracker {
+	return &churnTracker{
+		capacity: capacity,
+		ids:      make(map[string]struct{}, capacity),
+	}
+}
+
+// deleted records that sessionID was deleted
+func (c *churnTracker) deleted(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.ids[sessionID]; ok {
+		return
+	}
+	if len(c.order) >= c.capacity {
+		delete(c.ids, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.order = append(c.order, sessionID)
+	c.ids[sessionID] = struct{}{}
+}
+
+// wasDeleted reports whether sessionID was recently deleted
+func (c *churnTracker) wasDeleted(sessionID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.ids[sessionID]
+	return ok
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// gzipMagic starts every gzip stream. Records are JSON objects, so a stored
+// value starting with it can only be a compressed record.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// compress gzips a record larger than StoreOptions.CompressionMinBytes and
+// returns smaller ones as they are
+func (b *BaseSessionStore) compress(data []byte) ([]byte, error) {
+	if threshold := b.options.CompressionMinBytes; threshold <= 0 || len(data) <= threshold {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressRecord returns the JSON of a stored record, gunzipping it if it
+// was compressed. Whether a record was compressed is worked out from the
+// record itself, so records written with any threshold, or none, can be read.
+func decompressRecord(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress session data: %w: %w", ErrSerialization, err)
+	}
+	defer gz.Close()
+	decompressed, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress session data: %w: %w", ErrSerialization, err)
+	}
+	return decompressed, nil
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompressionThreshold(t *testing.T) {
+	tests := []struct {
+		name           string
+		minBytes       int
+		wantCompressed bool
+	}{
+		{name: "disabled", minBytes: 0, wantCompressed: false},
+		{name: "small record", minBytes: 4096, wantCompressed: false},
+		{name: "large record", minBytes: 16, wantCompressed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newMemoryBackend()
+			store := newTestBaseStore(t, backend, StoreOptions{CompressionMinBytes: tt.minBytes})
+			if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+
+			stored := backend.data["mcp:session:session-1"]
+			if compressed := bytes.HasPrefix(stored, gzipMagic); compressed != tt.wantCompressed {
+				t.Errorf("stored record compressed = %v, want %v", compressed, tt.wantCompressed)
+			}
+			raw, err := store.InspectRaw(t.Context(), "session-1")
+			if err != nil || !bytes.Equal(raw, stored) {
+				t.Errorf("InspectRaw() = %q, %v, want the stored bytes", raw, err)
+			}
+			record, err := store.Inspect(t.Context(), "session-1")
+			if err != nil {
+				t.Fatalf("Inspect() error = %v", err)
+			}
+			if !json.Valid(record) || !strings.Contains(string(record), `"session_id":"session-1"`) {
+				t.Errorf("Inspect() = %q, want the record's JSON", record)
+			}
+
+			// Whatever the threshold, any store can load the record
+			reader := newTestBaseStore(t, backend, StoreOptions{})
+			if transport, err := reader.Get(t.Context(), "session-1"); err != nil || transport == nil || transport.SessionID() != "session-1" {
+				t.Errorf("Get() = %v, %v, want session-1", transport, err)
+			}
+		})
+	}
+}
+
+func TestCompressionRedisRoundTrip(t *testing.T) {
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{Options: StoreOptions{CompressionMinBytes: 16}})
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	value, err := mr.Get("mcp:session:session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(value, string(gzipMagic)) {
+		t.Fatal("record wasn't stored compressed")
+	}
+
+	other := newTestRedisStoreAt(t, mr, RedisSessionStoreConfig{})
+	if transport, err := other.Get(t.Context(), "session-1"); err != nil || transport == nil || transport.SessionID() != "session-1" {
+		t.Errorf("Get() of a compressed record = %v, %v, want session-1", transport, err)
+	}
+}
+
+func TestDecompressRecord(t *testing.T) {
+	plain := []byte(`{"session_id":"session-1"}`)
+	if got, err := decompressRecord(plain); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("decompressRecord() of plain JSON = %q, %v, want it unchanged", got, err)
+	}
+
+	corrupt := append(append([]byte(nil), gzipMagic...), "not gzip"...)
+	if _, err := decompressRecord(corrupt); !errors.Is(err, ErrSerialization) {
+		t.Errorf("decompressRecord() of a corrupt record error = %v, want ErrSerialization", err)
+	}
+}
+
+func TestConsulEntryRoundTrip(t *testing.T) {
+	store := newTestBaseStore(t, newMemoryBackend(), StoreOptions{CompressionMinBytes: 16})
+	compressed, err := store.compress([]byte(`{"session_id":"session-1","updated_at":"2026-01-01T00:00:00Z"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		record   []byte
+		wantBlob bool
+	}{
+		{name: "JSON", record: []byte(`{"session_id":"session-1"}`), wantBlob: false},
+		{name: "compressed", record: compressed, wantBlob: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := json.Marshal(newConsulEntry(tt.record, time.Minute))
+			if err != nil {
+				t.Fatalf("failed to encode entry: %v", err)
+			}
+			// JSON records stay readable in the entry
+			if !tt.wantBlob && !strings.Contains(string(value), `"data":{"session_id":"session-1"}`) {
+				t.Errorf("entry = %s, want the record embedded as JSON", value)
+			}
+
+			var entry consulEntry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				t.Fatalf("failed to decode entry %s: %v", value, err)
+			}
+			if hasBlob := entry.Blob != nil; hasBlob != tt.wantBlob {
+				t.Errorf("entry has blob = %v, want %v", hasBlob, tt.wantBlob)
+			}
+			if !bytes.Equal(entry.record(), tt.record) {
+				t.Errorf("record() = %q, want %q", entry.record(), tt.record)
+			}
+			if entry.ExpiresAt == nil || entry.expired(time.Now(), 0) {
+				t.Error("entry with a TTL has no expiry or has already expired")
+			}
+		})
+	}
+
+	// Entries written before blobs were added only have data
+	var legacy consulEntry
+	if err := json.Unmarshal([]byte(`{"data":{"session_id":"session-1"}}`), &legacy); err != nil {
+		t.Fatal(err)
+	}
+	if string(legacy.record()) != `{"session_id":"session-1"}` || legacy.expired(time.Now(), 0) {
+		t.Errorf("legacy entry record() = %s", legacy.record())
+	}
+}
@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ConsulSessionStore implements StreamableHTTPSessionStore using the Consul KV
+// API as the backend. Consul KV has no per-key TTL, so each entry records when
+// it expires: expired entries are treated as missing when read and removed by
+// a background sweeper.
+type ConsulSessionStore struct {
+	*BaseSessionStore
+	client   *api.Client
+	skew     time.Duration // Clock skew tolerated when checking expiry
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// ConsulSessionStoreConfig holds configuration for the Consul session store
+type ConsulSessionStoreConfig struct {
+	Address       string        // Consul agent address (default: "localhost:8500")
+	Token         string        // Consul ACL token (default: "")
+	Prefix        string        // Key prefix for session storage (default: "mcp:session:")
+	TTL           time.Duration // Session TTL (default: 1 hour, NoExpiry to never expire)
+	SweepInterval time.Duration // How often expired sessions are deleted (default: 1 minute)
+	Server        *mcp.Server   // MCP server that loaded sessions are connected to (required)
+	Options       StoreOptions  // Optional behaviour shared by all stores
+
+	// ClockSkewTolerance keeps entries for this long past their expiry time.
+	// Expiry times are written with the writing instance's clock, so an
+	// instance whose clock runs ahead would otherwise expire sessions early.
+	ClockSkewTolerance time.Duration
+}
+
+// consulEntry is the value stored in Consul for each session. JSON records
+// are embedded as they are, so entries stay readable in the Consul UI. Other
+// records, e.g. compressed ones, can't be embedded and are base64-encoded in
+// Blob instead. Entries written before Blob was added only have Data.
+type consulEntry struct {
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"` // Nil if the session never expires
+	Data      json.RawMessage `json:"data,omitempty"`
+	Blob      []byte          `json:"blob,omitempty"`
+}
+
+// newConsulEntry builds the entry for a record, expiring after ttl
+func newConsulEntry(data []byte, ttl time.Duration) consulEntry {
+	var entry consulEntry
+	if json.Valid(data) {
+		entry.Data = data
+	} else {
+		entry.Blob = data
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		entry.ExpiresAt = &expiresAt
+	}
+	return entry
+}
+
+// record returns the record an entry holds
+func (e *consulEntry) record() []byte {
+	if e.Blob != nil {
+		return e.Blob
+	}
+	return e.Data
+}
+
+// expired reports whether the entry has expired at now, allowing for clocks
+// that differ by up to skew
+func (e *consulEntry) expired(now time.Time, skew time.Duration) bool {
+	return e.ExpiresAt != nil && !now.Before(e.ExpiresAt.Add(skew))
+}
+
+// NewConsulSessionStore creates a new Consul-backed session store
+func NewConsulSessionStore(config ConsulSessionStoreConfig) (*ConsulSessionStore, error) {
+	// Set defaults
+	if config.Address == "" {
+		config.Address = "localhost:8500"
+	}
+	if config.SweepInterval == 0 {
+		config.SweepInterval = time.Minute
+	}
+	if config.ClockSkewTolerance < 0 {
+		return nil, fmt.Errorf("clock skew tolerance must not be negative, got %s", config.ClockSkewTolerance)
+	}
+
+	clientConfig := api.DefaultConfig()
+	clientConfig.Address = config.Address
+	clientConfig.Token = config.Token
+	client, err := api.NewClient(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Consul client: %w", err)
+	}
+
+	store := &ConsulSessionStore{
+		client: client,
+		skew:   config.ClockSkewTolerance,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := store.Health(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to Consul: %w", err)
+	}
+
+	base, err := newBaseSessionStore(store, baseSessionStoreConfig{
+		Prefix:  config.Prefix,
+		TTL:     config.TTL,
+		Server:  config.Server,
+		Options: config.Options,
+	})
+	if err != nil {
+		return nil, err
+	}
+	store.BaseSessionStore = base
+
+	go store.sweep(config.SweepInterval)
+
+	return store, nil
+}
+
+// getRaw reads a session value from Consul, treating expired entries as missing
+func (c *ConsulSessionStore) getRaw(ctx context.Context, key string) ([]byte, error) {
+	pair, _, err := c.client.KV().Get(key, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session from Consul: %w: %w", ErrUnavailable, err)
+	}
+	if pair == nil {
+		return nil, ErrNotFound
+	}
+
+	var entry consulEntry
+	if err := json.Unmarshal(pair.Value, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Consul entry: %w: %w", ErrSerialization, err)
+	}
+	if entry.expired(time.Now(), c.skew) {
+		return nil, ErrNotFound
+	}
+	return entry.record(), nil
+}
+
+// setRaw writes a session value to Consul along with its expiry time
+func (c *ConsulSessionStore) setRaw(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	value, err := json.Marshal(newConsulEntry(data, ttl))
+	if err != nil {
+		return fmt.Errorf("failed to marshal Consul entry: %w: %w", ErrSerialization, err)
+	}
+
+	if _, err := c.client.KV().Put(&api.KVPair{Key: key, Value: value}, (&api.WriteOptions{}).WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to set session in Consul: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// createRaw writes a session value to Consul with a check-and-set, so it's
+// only written if the key doesn't exist. An expired entry that hasn't been
+// swept yet counts as missing and is replaced, as long as it isn't rewritten
+// in the meantime. An entry that can't be decoded is left alone.
+func (c *ConsulSessionStore) createRaw(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	pair, _, err := c.client.KV().Get(key, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to get session from Consul: %w: %w", ErrUnavailable, err)
+	}
+	var index uint64
+	if pair != nil {
+		var existing consulEntry
+		if err := json.Unmarshal(pair.Value, &existing); err != nil || !existing.expired(time.Now(), c.skew) {
+			return false, nil
+		}
+		index = pair.ModifyIndex
+	}
+
+	value, err := json.Marshal(newConsulEntry(data, ttl))
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal Consul entry: %w: %w", ErrSerialization, err)
+	}
+
+	created, _, err := c.client.KV().CAS(&api.KVPair{Key: key, Value: value, ModifyIndex: index}, (&api.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to create session in Consul: %w: %w", ErrUnavailable, err)
+	}
+	return created, nil
+}
+
+// delRaw removes a session value from Consul
+func (c *ConsulSessionStore) delRaw(ctx context.Context, key string) error {
+	if _, err := c.client.KV().Delete(key, (&api.WriteOptions{}).WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to delete session from Consul: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// ListSessions returns the IDs of all unexpired sessions stored in Consul
+// under the store's prefix
+func (c *ConsulSessionStore) ListSessions(ctx context.Context) ([]string, error) {
+	pairs, _, err := c.client.KV().List(c.prefix, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions in Consul: %w: %w", ErrUnavailable, err)
+	}
+
+	now := time.Now()
+	keys := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		var entry consulEntry
+		if err := json.Unmarshal(pair.Value, &entry); err == nil && entry.expired(now, c.skew) {
+			continue
+		}
+		keys = append(keys, pair.Key)
+	}
+	return c.sessionIDsFromKeys(keys), nil
+}
+
+// sweep deletes expired sessions every interval until Close is called
+func (c *ConsulSessionStore) sweep(interval time.Duration) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if err := c.deleteExpired(ctx); err != nil {
+				log.Printf("Failed to sweep expired Consul sessions: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// deleteExpired removes expired entries under the store's prefix. Deletes use
+// check-and-set, so an entry that's rewritten during the sweep is kept.
+func (c *ConsulSessionStore) deleteExpired(ctx context.Context) error {
+	pairs, _, err := c.client.KV().List(c.prefix, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, pair := range pairs {
+		var entry consulEntry
+		if err := json.Unmarshal(pair.Value, &entry); err != nil || !entry.expired(now, c.skew) {
+			continue
+		}
+		if _, _, err := c.client.KV().DeleteCAS(pair, (&api.WriteOptions{}).WithContext(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the expiry sweeper
+func (c *ConsulSessionStore) Close() error {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+	<-c.done
+	c.closeBase()
+	return nil
+}
+
+// Health checks the health of the Consul connection by asking the agent for
+// the cluster leader
+func (c *ConsulSessionStore) Health(ctx context.Context) error {
+	if _, err := c.client.Status().LeaderWithQueryOptions((&api.QueryOptions{}).WithContext(ctx)); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
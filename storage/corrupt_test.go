@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRedisSessionStoreCorruptRecord(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	mr.Set("mcp:session:session-1", "not json")
+	corrupt := testutil.ToFloat64(corruptSessionsTotal)
+
+	// Without DropCorruptSessions every load fails and the record stays
+	for range 2 {
+		if _, err := store.Get(t.Context(), "session-1"); !errors.Is(err, ErrSerialization) {
+			t.Errorf("Get() error = %v, want ErrSerialization", err)
+		}
+	}
+	if !mr.Exists("mcp:session:session-1") {
+		t.Error("corrupt record deleted without DropCorruptSessions")
+	}
+	if got := testutil.ToFloat64(corruptSessionsTotal) - corrupt; got != 2 {
+		t.Errorf("corrupt records counted = %v, want 2", got)
+	}
+}
+
+func TestRedisSessionStoreDropCorruptSessions(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{Options: StoreOptions{DropCorruptSessions: true}})
+	ctx := t.Context()
+	mr.Set("mcp:session:session-1", "not json")
+
+	if got, err := store.Get(ctx, "session-1"); got != nil || err != nil {
+		t.Errorf("Get() = %v, %v, want nil, nil so the session is reported as not found", got, err)
+	}
+	if mr.Exists("mcp:session:session-1") {
+		t.Error("corrupt record kept with DropCorruptSessions")
+	}
+	if _, err := store.Inspect(ctx, "session-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Inspect() after the drop error = %v, want ErrNotFound", err)
+	}
+
+	// The session can be started again under the same ID
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, err := store.Get(ctx, "session-1"); got == nil || err != nil {
+		t.Errorf("Get() after Set = %v, %v, want the session", got, err)
+	}
+}
+
+func TestRedisSessionStoreDropCorruptSessionsReadOnly(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{Options: StoreOptions{DropCorruptSessions: true, ReadOnly: true}})
+	mr.Set("mcp:session:session-1", "not json")
+
+	// The record can't be deleted, so the decode error is reported instead
+	if _, err := store.Get(t.Context(), "session-1"); !errors.Is(err, ErrSerialization) {
+		t.Errorf("Get() error = %v, want ErrSerialization", err)
+	}
+	if !mr.Exists("mcp:session:session-1") {
+		t.Error("corrupt record deleted in read-only mode")
+	}
+}
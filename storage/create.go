@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// rawCreator is implemented by backends that can write a key only if it
+// doesn't exist yet, atomically
+type rawCreator interface {
+	// createRaw stores data under key unless the key already holds a value,
+	// reporting whether it was written
+	createRaw(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error)
+}
+
+// errCreateUnsupported is returned by StoreIfAbsent for backends without an
+// atomic create
+var errCreateUnsupported = errors.New("session store doesn't support creating sessions atomically")
+
+// StoreIfAbsent writes a new session only if no record exists for it yet, so
+// when two instances race to create the same session the first write wins.
+// created reports whether this call wrote the record. If it didn't, the
+// existing record is read back and kept as-is, and session is served
+// against it rather than overwriting it.
+func (b *BaseSessionStore) StoreIfAbsent(ctx context.Context, sessionID string, session *mcp.StreamableServerTransport) (created bool, err error) {
+	creator, ok := b.backend.(rawCreator)
+	if !ok {
+		return false, errCreateUnsupported
+	}
+
+	sessionData, data, err := b.encodeSession(sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	// Only count writes that are actually attempted
+	if b.churn != nil {
+		b.recordWrite(ctx, sessionID)
+	}
+
+	created, err = creator.createRaw(ctx, b.getKey(sessionID), data, b.expiration())
+	if err != nil {
+		return false, err
+	}
+	if created {
+		b.written(sessionData, data)
+		b.activate(sessionID, session)
+		return true, nil
+	}
+
+	sessionCreateConflictsTotal.Inc()
+	if err := b.adoptExisting(ctx, sessionID, session); err != nil {
+		return false, err
+	}
+	log.Printf("Session %s was created by another instance first, keeping its stored record", sessionID)
+	return false, nil
+}
+
+// adoptExisting reloads the record another instance created for sessionID
+// and serves session against it. The session was counted as created by the
+// instance that won, so it isn't counted again here.
+func (b *BaseSessionStore) adoptExisting(ctx context.Context, sessionID string, session *mcp.StreamableServerTransport) error {
+	data, err := b.read(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to reload session %s created by another instance: %w", sessionID, err)
+	}
+	if data, err = decompressRecord(data); err != nil {
+		return err
+	}
+	var existing SessionData
+	if err := json.Unmarshal(data, &existing); err != nil {
+		return fmt.Errorf("failed to unmarshal session %s created by another instance: %w: %w", sessionID, ErrSerialization, err)
+	}
+	if existing.SessionID != sessionID {
+		return fmt.Errorf("record for session %q found in place of session %s: %w", existing.SessionID, sessionID, ErrSerialization)
+	}
+
+	b.activeSessionMu.Lock()
+	defer b.activeSessionMu.Unlock()
+	b.activeSessions[sessionID] = session
+	return nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"testing"
+)
+
+func TestRedisSessionStoreStoreIfAbsentRace(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	first, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	stores := []*RedisSessionStore{first, newTestRedisStoreAt(t, mr, RedisSessionStoreConfig{})}
+
+	for i := range 20 {
+		sessionID := fmt.Sprintf("session-%d", i)
+		start := make(chan struct{})
+		created := make([]bool, len(stores))
+		errs := make([]error, len(stores))
+		var wg sync.WaitGroup
+		for j, store := range stores {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				created[j], errs[j] = store.StoreIfAbsent(t.Context(), sessionID, newTestTransport(sessionID))
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		if errs[0] != nil || errs[1] != nil {
+			t.Fatalf("StoreIfAbsent(%s) errors = %v, %v", sessionID, errs[0], errs[1])
+		}
+		if created[0] == created[1] {
+			t.Fatalf("StoreIfAbsent(%s) created = %v, %v, want exactly one true", sessionID, created[0], created[1])
+		}
+		for j, store := range stores {
+			if !store.isActive(sessionID) {
+				t.Errorf("store %d doesn't serve %s after the race", j, sessionID)
+			}
+		}
+
+		// Further creates find the winner's record and leave it alone
+		stored, _ := mr.Get("mcp:session:" + sessionID)
+		loser := stores[0]
+		if created[0] {
+			loser = stores[1]
+		}
+		if again, err := loser.StoreIfAbsent(t.Context(), sessionID, newTestTransport(sessionID)); again || err != nil {
+			t.Errorf("StoreIfAbsent(%s) again = %v, %v, want false, nil", sessionID, again, err)
+		}
+		if got, _ := mr.Get("mcp:session:" + sessionID); got != stored {
+			t.Errorf("record for %s changed by the losing store:\n%s\nwant\n%s", sessionID, got, stored)
+		}
+	}
+}
+
+func TestRedisSessionStoreCreateIfAbsent(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	winner, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	loser := newTestRedisStoreAt(t, mr, RedisSessionStoreConfig{Options: StoreOptions{CreateIfAbsent: true}})
+	if err := winner.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := mr.Get("mcp:session:session-1")
+
+	// A session the loser hasn't seen is created, not overwritten
+	if err := loser.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, _ := mr.Get("mcp:session:session-1"); got != stored {
+		t.Errorf("record overwritten by Set with CreateIfAbsent:\n%s\nwant\n%s", got, stored)
+	}
+	if !loser.isActive("session-1") {
+		t.Error("session not active after adopting the existing record")
+	}
+}
+
+func TestBaseSessionStoreStoreIfAbsentUnsupported(t *testing.T) {
+	backend := newMemoryBackend()
+	store := newTestBaseStore(t, backend, StoreOptions{CreateIfAbsent: true})
+
+	if created, err := store.StoreIfAbsent(t.Context(), "session-1", newTestTransport("session-1")); created || !errors.Is(err, errCreateUnsupported) {
+		t.Errorf("StoreIfAbsent() = %v, %v, want false, errCreateUnsupported", created, err)
+	}
+	if err := store.Set("session-1", newTestTransport("session-1")); !errors.Is(err, errCreateUnsupported) {
+		t.Errorf("Set() with CreateIfAbsent error = %v, want errCreateUnsupported", err)
+	}
+	if keys := backend.keys(); len(keys) != 0 {
+		t.Errorf("keys written without an atomic create = %v, want none", keys)
+	}
+	if store.isActive("session-1") {
+		t.Error("session active although it was never written")
+	}
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// heldLocks tracks the session locks held on this instance, so shutdown can
+// wait for the work they guard to finish
+type heldLocks struct {
+	mu   sync.Mutex
+	keys map[string]int // Number of holders by lock key
+	idle chan struct{}  // Closed while no locks are held
+}
+
+// newHeldLocks creates a heldLocks with no locks held
+func newHeldLocks() *heldLocks {
+	idle := make(chan struct{})
+	close(idle)
+	return &heldLocks{
+		keys: make(map[string]int),
+		idle: idle,
+	}
+}
+
+// acquire records that a lock on key was taken, returning the function that
+// records its release
+func (h *heldLocks) acquire(key string) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.keys) == 0 {
+		h.idle = make(chan struct{})
+	}
+	h.keys[key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { h.release(key) })
+	}
+}
+
+func (h *heldLocks) release(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.keys[key]--; h.keys[key] <= 0 {
+		delete(h.keys, key)
+	}
+	if len(h.keys) == 0 {
+		close(h.idle)
+	}
+}
+
+// wait blocks until no locks are held. If ctx is done first it returns the
+// keys still locked, sorted, along with the context's error.
+func (h *heldLocks) wait(ctx context.Context) ([]string, error) {
+	h.mu.Lock()
+	idle := h.idle
+	h.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil, nil
+	case <-ctx.Done():
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.keys) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(h.keys))
+	for key := range h.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, ctx.Err()
+}
+
+// Drain prepares the store to be closed once the server has stopped taking
+// requests. New sessions are refused with ErrShuttingDown, then it waits for
+// the session locks held on this instance to be released, so in-flight
+// read-modify-write sequences complete, and for queued write-behind events to
+// be delivered. Sessions are written through to the backend as soon as
+// they're set, so no session data is held only in memory. If ctx is done
+// first, the returned error says what didn't complete, and Close won't wait
+// for it either.
+func (b *BaseSessionStore) Drain(ctx context.Context) error {
+	b.draining.Store(true)
+
+	var errs []error
+	if keys, err := b.heldLocks.wait(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("%d locks still held (%s): %w", len(keys), strings.Join(keys, ", "), err))
+	}
+	if b.writeBehind != nil {
+		if pending, err := b.writeBehind.drain(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%d write-behind events not delivered: %w", pending, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedisSessionStoreDrainRefusesNewSessions(t *testing.T) {
+	store, _ := newTestRedisStore(t, RedisSessionStoreConfig{})
+	existing := newTestTransport("existing")
+	if err := store.Set("existing", existing); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Drain(t.Context()); err != nil {
+		t.Fatalf("Drain() with nothing in flight error = %v", err)
+	}
+	if err := store.Set("new", newTestTransport("new")); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Set() of a new session while draining error = %v, want ErrShuttingDown", err)
+	}
+	if err := store.Set("existing", existing); err != nil {
+		t.Errorf("Set() of an active session while draining error = %v", err)
+	}
+}
+
+func TestRedisSessionStoreDrainWaitsForLocks(t *testing.T) {
+	store, _ := newTestRedisStore(t, RedisSessionStoreConfig{})
+	locked, release := make(chan struct{}), make(chan struct{})
+	lockDone := make(chan error, 1)
+	go func() {
+		lockDone <- store.WithSessionLock(t.Context(), "session-1", func(ctx context.Context) error {
+			close(locked)
+			<-release
+			return nil
+		})
+	}()
+	<-locked
+
+	drained := make(chan error, 1)
+	go func() { drained <- store.Drain(t.Context()) }()
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain() returned %v while a lock was held", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-lockDone; err != nil {
+		t.Fatalf("WithSessionLock() error = %v", err)
+	}
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Errorf("Drain() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Drain() didn't return once the lock was released")
+	}
+}
+
+func TestRedisSessionStoreDrainTimeout(t *testing.T) {
+	store, _ := newTestRedisStore(t, RedisSessionStoreConfig{})
+	locked, release := make(chan struct{}), make(chan struct{})
+	lockDone := make(chan error, 1)
+	go func() {
+		lockDone <- store.WithSessionLock(t.Context(), "session-1", func(ctx context.Context) error {
+			close(locked)
+			<-release
+			return nil
+		})
+	}()
+	<-locked
+	t.Cleanup(func() {
+		close(release)
+		<-lockDone
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	err := store.Drain(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "1 locks still held (") || !strings.Contains(err.Error(), "session-1") {
+		t.Errorf("Drain() error = %v, want the held lock reported", err)
+	}
+}
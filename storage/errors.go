@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+)
+
+var (
+	// ErrNotFound is returned when a session doesn't exist in the backend. It
+	// wraps fs.ErrNotExist, so errors.Is(err, fs.ErrNotExist) also matches.
+	ErrNotFound = fmt.Errorf("session not found: %w", fs.ErrNotExist)
+
+	// ErrUnavailable is returned when the backend can't be reached or fails
+	// to execute a command
+	ErrUnavailable = errors.New("session store unavailable")
+
+	// ErrSerialization is returned when session data can't be encoded or decoded
+	ErrSerialization = errors.New("session serialization failed")
+
+	// ErrStateTooLarge is returned when a session's serialized state exceeds
+	// StoreOptions.MaxStateBytes
+	ErrStateTooLarge = errors.New("session state too large")
+
+	// ErrShuttingDown is returned when a session is created after the store
+	// has started draining for shutdown
+	ErrShuttingDown = errors.New("session store shutting down")
+
+	// ErrReadOnly is returned by writes while the store is in read-only mode
+	ErrReadOnly = errors.New("session store is read-only")
+)
+
+// BatchError is returned by batched operations when some sessions failed and
+// others succeeded. The operation's result holds the successes, so callers
+// can retry only the sessions in Failed. errors.Is and errors.As match against
+// every per-session error.
+type BatchError struct {
+	Failed map[string]error // Error for each session that failed, by ID
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d sessions failed", len(e.Failed))
+}
+
+// Unwrap returns the per-session errors
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, err := range e.Failed {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// HTTPStatus maps a storage error to the HTTP status code that best describes
+// it, for handlers that surface store errors to clients
+func HTTPStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidSessionID):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrStateTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrUnavailable), errors.Is(err, ErrShuttingDown), errors.Is(err, ErrReadOnly):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
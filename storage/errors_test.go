@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"testing"
+)
+
+func TestRedisSessionStoreErrors(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		store, _ := newTestRedisStore(t, RedisSessionStoreConfig{})
+		_, err := store.getRaw(t.Context(), store.getKey("missing"))
+		if !errors.Is(err, ErrNotFound) || !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("getRaw() error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("serialization", func(t *testing.T) {
+		store, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+		mr.Set("mcp:session:session-1", "not json")
+		_, err := store.Get(t.Context(), "session-1")
+		if !errors.Is(err, ErrSerialization) {
+			t.Errorf("Get() of a corrupt record error = %v, want ErrSerialization", err)
+		}
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		store, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+		mr.Close()
+		if _, err := store.Get(t.Context(), "session-1"); !errors.Is(err, ErrUnavailable) {
+			t.Errorf("Get() error = %v, want ErrUnavailable", err)
+		}
+		if err := store.Set("session-1", newTestTransport("session-1")); !errors.Is(err, ErrUnavailable) {
+			t.Errorf("Set() error = %v, want ErrUnavailable", err)
+		}
+		if err := store.Delete("session-1"); !errors.Is(err, ErrUnavailable) {
+			t.Errorf("Delete() error = %v, want ErrUnavailable", err)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		_, err := NewRedisSessionStore(RedisSessionStoreConfig{Addr: "127.0.0.1:1", Server: newTestServer()})
+		if !errors.Is(err, ErrUnavailable) {
+			t.Errorf("NewRedisSessionStore() error = %v, want ErrUnavailable", err)
+		}
+	})
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: http.StatusOK},
+		{name: "not found", err: ErrNotFound, want: http.StatusNotFound},
+		{name: "fs not exist", err: fs.ErrNotExist, want: http.StatusNotFound},
+		{name: "invalid session ID", err: ValidateSessionID("*"), want: http.StatusBadRequest},
+		{name: "state too large", err: ErrStateTooLarge, want: http.StatusRequestEntityTooLarge},
+		{name: "unavailable", err: ErrUnavailable, want: http.StatusServiceUnavailable},
+		{name: "wrapped unavailable", err: fmt.Errorf("failed to get session: %w: %w", ErrUnavailable, errors.New("dial tcp: refused")), want: http.StatusServiceUnavailable},
+		{name: "shutting down", err: ErrShuttingDown, want: http.StatusServiceUnavailable},
+		{name: "read-only", err: ErrReadOnly, want: http.StatusServiceUnavailable},
+		{name: "serialization", err: ErrSerialization, want: http.StatusInternalServerError},
+		{name: "other", err: context.Canceled, want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPStatus(tt.err); got != tt.want {
+				t.Errorf("HTTPStatus(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
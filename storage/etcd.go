@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdSessionStore implements StreamableHTTPSessionStore using etcd as the
+// backend. Session TTLs are enforced with etcd leases.
+type EtcdSessionStore struct {
+	*BaseSessionStore
+	client *clientv3.Client
+}
+
+// EtcdSessionStoreConfig holds configuration for the etcd session store
+type EtcdSessionStoreConfig struct {
+	Endpoints   []string      // etcd endpoints (default: ["localhost:2379"])
+	Username    string        // etcd username, if auth is enabled
+	Password    string        // etcd password, if auth is enabled
+	DialTimeout time.Duration // Timeout for establishing the connection (default: 5s)
+	Prefix      string        // Key prefix for session storage (default: "mcp:session:")
+	TTL         time.Duration // Session TTL (default: 1 hour, NoExpiry to never expire)
+	Server      *mcp.Server   // MCP server that loaded sessions are connected to (required)
+	Options     StoreOptions  // Optional behaviour shared by all stores
+}
+
+// NewEtcdSessionStore creates a new etcd-backed session store
+func NewEtcdSessionStore(config EtcdSessionStoreConfig) (*EtcdSessionStore, error) {
+	// Set defaults
+	if len(config.Endpoints) == 0 {
+		config.Endpoints = []string{"localhost:2379"}
+	}
+	if config.DialTimeout == 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   config.Endpoints,
+		Username:    config.Username,
+		Password:    config.Password,
+		DialTimeout: config.DialTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to etcd: %w: %w", ErrUnavailable, err)
+	}
+
+	store := &EtcdSessionStore{
+		client: client,
+	}
+
+	base, err := newBaseSessionStore(store, baseSessionStoreConfig{
+		Prefix:  config.Prefix,
+		TTL:     config.TTL,
+		Server:  config.Server,
+		Options: config.Options,
+	})
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	store.BaseSessionStore = base
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), config.DialTimeout)
+	defer cancel()
+	if err := store.Health(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to etcd: %w", err)
+	}
+
+	return store, nil
+}
+
+// getRaw reads a session value from etcd
+func (e *EtcdSessionStore) getRaw(ctx context.Context, key string) ([]byte, error) {
+	resp, err := e.client.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session from etcd: %w: %w", ErrUnavailable, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, ErrNotFound
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+// ListSessions returns the IDs of all sessions stored in etcd under the
+// store's prefix
+func (e *EtcdSessionStore) ListSessions(ctx context.Context) ([]string, error) {
+	resp, err := e.client.Get(ctx, e.prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions in etcd: %w: %w", ErrUnavailable, err)
+	}
+	keys := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		keys = append(keys, string(kv.Key))
+	}
+	return e.sessionIDsFromKeys(keys), nil
+}
+
+// ListSessionsPage returns one page of session IDs in key order. The cursor
+// is the last key of the previous page.
+func (e *EtcdSessionStore) ListSessionsPage(ctx context.Context, cursor string, count int) ([]string, string, error) {
+	if count <= 0 {
+		count = DefaultPageSize
+	}
+	start := e.prefix
+	if cursor != "" {
+		if !strings.HasPrefix(cursor, e.prefix) {
+			return nil, "", fmt.Errorf("invalid etcd cursor %q", cursor)
+		}
+		start = cursor + "\x00" // The smallest key after the cursor
+	}
+
+	resp, err := e.client.Get(ctx, start,
+		clientv3.WithRange(clientv3.GetPrefixRangeEnd(e.prefix)),
+		clientv3.WithLimit(int64(count)),
+		clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list sessions in etcd: %w: %w", ErrUnavailable, err)
+	}
+
+	keys := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		keys = append(keys, string(kv.Key))
+	}
+	next := ""
+	if resp.More && len(keys) > 0 {
+		next = keys[len(keys)-1]
+	}
+	return e.sessionIDsFromKeys(keys), next, nil
+}
+
+// setRaw writes a session value to etcd. Each write is attached to a freshly
+// granted lease, which resets the session's TTL. A lease lives for its whole
+// TTL whether or not keys are attached to it, so the lease of the value
+// being replaced is revoked once the new value is written; each session
+// holds one lease at a time.
+func (e *EtcdSessionStore) setRaw(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	lease, err := e.grantLease(ctx, ttl)
+	if err != nil {
+		return err
+	}
+	opts := []clientv3.OpOption{clientv3.WithPrevKV()}
+	if lease != clientv3.NoLease {
+		opts = append(opts, clientv3.WithLease(lease))
+	}
+
+	resp, err := e.client.Put(ctx, key, string(data), opts...)
+	if err != nil {
+		e.revokeLease(ctx, lease)
+		return fmt.Errorf("failed to set session in etcd: %w: %w", ErrUnavailable, err)
+	}
+	if resp.PrevKv != nil && resp.PrevKv.Lease != int64(lease) {
+		e.revokeLease(ctx, clientv3.LeaseID(resp.PrevKv.Lease))
+	}
+	return nil
+}
+
+// createRaw writes a session value to etcd in a transaction that only puts
+// it if the key has never been created, or has been deleted since. The lease
+// granted for the value is revoked if it isn't written.
+func (e *EtcdSessionStore) createRaw(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	lease, err := e.grantLease(ctx, ttl)
+	if err != nil {
+		return false, err
+	}
+	var opts []clientv3.OpOption
+	if lease != clientv3.NoLease {
+		opts = append(opts, clientv3.WithLease(lease))
+	}
+
+	resp, err := e.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(data), opts...)).
+		Commit()
+	if err != nil {
+		e.revokeLease(ctx, lease)
+		return false, fmt.Errorf("failed to create session in etcd: %w: %w", ErrUnavailable, err)
+	}
+	if !resp.Succeeded {
+		e.revokeLease(ctx, lease)
+	}
+	return resp.Succeeded, nil
+}
+
+// grantLease grants a lease for a value expiring after ttl, or returns
+// NoLease for a value that never expires
+func (e *EtcdSessionStore) grantLease(ctx context.Context, ttl time.Duration) (clientv3.LeaseID, error) {
+	if ttl <= 0 {
+		return clientv3.NoLease, nil
+	}
+	lease, err := e.client.Grant(ctx, etcdLeaseSeconds(ttl))
+	if err != nil {
+		return clientv3.NoLease, fmt.Errorf("failed to grant etcd lease: %w: %w", ErrUnavailable, err)
+	}
+	return lease.ID, nil
+}
+
+// revokeLease revokes a lease no value is using any more, so it doesn't
+// linger until it expires. It runs even if ctx has been cancelled, since a
+// cancelled write is one reason a lease goes unused. Failures are logged: the
+// lease still expires at the end of its TTL.
+func (e *EtcdSessionStore) revokeLease(ctx context.Context, lease clientv3.LeaseID) {
+	if lease == clientv3.NoLease {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), etcdRevokeTimeout)
+	defer cancel()
+	if _, err := e.client.Revoke(ctx, lease); err != nil {
+		log.Printf("Failed to revoke etcd lease %x: %v", lease, err)
+	}
+}
+
+// delRaw removes a session value from etcd
+func (e *EtcdSessionStore) delRaw(ctx context.Context, key string) error {
+	if _, err := e.client.Delete(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete session from etcd: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// Close closes the etcd client
+func (e *EtcdSessionStore) Close() error {
+	e.closeBase()
+	return e.client.Close()
+}
+
+// Health checks the health of the etcd connection by reading a key under the
+// session prefix, which works with the same permissions the store needs
+func (e *EtcdSessionStore) Health(ctx context.Context) error {
+	if _, err := e.client.Get(ctx, e.prefix, clientv3.WithCountOnly()); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// etcdRevokeTimeout bounds revoking a lease that's no longer used
+const etcdRevokeTimeout = 5 * time.Second
+
+// etcdLeaseSeconds converts a TTL into a lease TTL, rounding up so sessions
+// never expire early
+func etcdLeaseSeconds(ttl time.Duration) int64 {
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
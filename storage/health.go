@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// connectionMonitor pings a backend on an interval and tracks whether it's
+// reachable, logging each transition
+type connectionMonitor struct {
+	name      string
+	ping      func(ctx context.Context) error
+	connected atomic.Bool
+	latency   atomic.Int64 // Duration of the most recent ping
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+}
+
+// newConnectionMonitor creates a monitor that starts out connected, since
+// stores only construct one after a successful initial ping
+func newConnectionMonitor(name string, ping func(ctx context.Context) error) *connectionMonitor {
+	m := &connectionMonitor{
+		name: name,
+		ping: ping,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	// Not through setConnected, which would log the initial state as the
+	// connection being restored
+	m.connected.Store(true)
+	storeConnected.WithLabelValues(name).Set(1)
+	return m
+}
+
+// start runs the ping loop in the background until close is called. An
+// interval of 0 or less disables the loop.
+func (m *connectionMonitor) start(interval time.Duration) {
+	if interval <= 0 {
+		close(m.done)
+		return
+	}
+
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				start := time.Now()
+				err := m.ping(ctx)
+				m.latency.Store(int64(time.Since(start)))
+				cancel()
+				m.setConnected(err == nil, err)
+			}
+		}
+	}()
+}
+
+// setConnected records the current state, logging and updating the gauge
+// when it changes
+func (m *connectionMonitor) setConnected(connected bool, err error) {
+	value := 0.0
+	if connected {
+		value = 1
+	}
+	storeConnected.WithLabelValues(m.name).Set(value)
+
+	if m.connected.Swap(connected) == connected {
+		return
+	}
+	if connected {
+		log.Printf("%s connection restored", m.name)
+	} else {
+		log.Printf("%s connection lost: %v", m.name, err)
+	}
+}
+
+// isConnected reports the state observed by the most recent ping
+func (m *connectionMonitor) isConnected() bool {
+	return m.connected.Load()
+}
+
+// lastLatency returns how long the most recent ping took, including pings
+// that failed or timed out. It's 0 until the first background ping.
+func (m *connectionMonitor) lastLatency() time.Duration {
+	return time.Duration(m.latency.Load())
+}
+
+// close stops the ping loop and waits for it to exit
+func (m *connectionMonitor) close() {
+	m.stopOnce.Do(func() { close(m.stop) })
+	<-m.done
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestConnectionMonitorLogsTransitions(t *testing.T) {
+	var logs bytes.Buffer
+	output := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(output) })
+
+	m := newConnectionMonitor("Test", nil)
+	if !m.isConnected() {
+		t.Error("new monitor isn't connected")
+	}
+	if logs.Len() != 0 {
+		t.Errorf("new monitor logged %q, want nothing", logs.String())
+	}
+
+	m.setConnected(false, errors.New("connection refused"))
+	m.setConnected(false, errors.New("connection refused"))
+	m.setConnected(true, nil)
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "Test connection lost: connection refused") || !strings.Contains(lines[1], "Test connection restored") {
+		t.Errorf("logged %q, want one lost and one restored line", lines)
+	}
+}
@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newTestServer returns an MCP server for stores under test to connect
+// loaded sessions to
+func newTestServer() *mcp.Server {
+	return mcp.NewServer(&mcp.Implementation{Name: "storage-test", Version: "0.0.0"}, nil)
+}
+
+// newTestTransport returns a transport for storing sessionID
+func newTestTransport(sessionID string) *mcp.StreamableServerTransport {
+	return mcp.NewStreamableServerTransport(sessionID, nil)
+}
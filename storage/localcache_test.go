@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestBaseSessionStoreDisableLocalCache(t *testing.T) {
+	backend := newMemoryBackend()
+	store := newTestBaseStore(t, backend, StoreOptions{DisableLocalCache: true})
+
+	transport := newTestTransport("session-1")
+	if err := store.Set("session-1", transport); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	got, err := store.Get(t.Context(), "session-1")
+	if err != nil || got == nil {
+		t.Fatalf("Get() = %v, %v, want the session", got, err)
+	}
+
+	// Deleted through another instance
+	backend.delRaw(t.Context(), "mcp:session:session-1")
+
+	if got, err := store.Get(t.Context(), "session-1"); got != nil || err != nil {
+		t.Errorf("Get() after the record was removed = %v, %v, want nil, nil", got, err)
+	}
+	if gets := backend.getCount(); gets != 2 {
+		t.Errorf("backend reads = %d, want 2", gets)
+	}
+}
+
+func TestRedisSessionStoreDisableLocalCache(t *testing.T) {
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{Options: StoreOptions{DisableLocalCache: true}})
+	other := newTestRedisStoreAt(t, mr, RedisSessionStoreConfig{})
+
+	transport := newTestTransport("session-1")
+	if err := store.Set("session-1", transport); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	got, err := store.Get(t.Context(), "session-1")
+	if err != nil || got != transport {
+		t.Fatalf("Get() = %v, %v, want the connected transport once Redis confirms the session exists", got, err)
+	}
+	if hits, _ := store.CacheStats(); hits != 0 {
+		t.Errorf("cache hits = %d, want 0", hits)
+	}
+
+	// Another instance deleting the session is seen straight away
+	if err := other.Delete("session-1"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Get(t.Context(), "session-1"); got != nil || err != nil {
+		t.Errorf("Get() after another instance deleted the session = %v, %v, want nil, nil", got, err)
+	}
+	store.Range(func(sessionID string, _ *mcp.StreamableServerTransport) {
+		t.Errorf("deleted session %s is still active", sessionID)
+	})
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// sessionLockStripes is the number of locks sessions are spread across. Two
+// sessions that hash to the same stripe serialize each other, which is
+// harmless as long as critical sections are short.
+const sessionLockStripes = 256
+
+// lockKeyVersion takes the place of the key version in the keys of stores
+// that keep session locks alongside sessions, so it's reserved
+const lockKeyVersion = "lock"
+
+// stripedLock serializes work per session ID within one process using a
+// fixed set of locks, so memory use doesn't grow with the number of sessions
+type stripedLock struct {
+	stripes [sessionLockStripes]chan struct{}
+}
+
+// newStripedLock creates a stripedLock with every stripe unlocked
+func newStripedLock() *stripedLock {
+	l := &stripedLock{}
+	for i := range l.stripes {
+		l.stripes[i] = make(chan struct{}, 1)
+	}
+	return l
+}
+
+// lock waits for the stripe for sessionID, returning the function that
+// releases it. It gives up with the context's error if ctx is done first.
+func (l *stripedLock) lock(ctx context.Context, sessionID string) (func(), error) {
+	h := fnv.New32a()
+	h.Write([]byte(sessionID))
+	stripe := l.stripes[h.Sum32()%sessionLockStripes]
+
+	select {
+	case stripe <- struct{}{}:
+		return func() { <-stripe }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WithSessionLock runs fn while holding this instance's lock for sessionID,
+// so read-modify-write sequences on the same session don't interleave. It
+// doesn't coordinate with other instances; stores that can, such as the
+// Redis store, override it.
+func (b *BaseSessionStore) WithSessionLock(ctx context.Context, sessionID string, fn func(ctx context.Context) error) error {
+	if err := ValidateSessionID(sessionID); err != nil {
+		return err
+	}
+	unlock, err := b.locks.lock(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer b.heldLocks.acquire(sessionID)()
+	return fn(ctx)
+}
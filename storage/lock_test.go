@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incrementers is how many concurrent read-modify-write sequences the lock
+// tests run
+const incrementers = 50
+
+func TestBaseSessionStoreWithSessionLockSerializesUpdates(t *testing.T) {
+	store := newTestBaseStore(t, newMemoryBackend(), StoreOptions{})
+
+	counter := 0
+	var wg sync.WaitGroup
+	for range incrementers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.WithSessionLock(t.Context(), "session-1", func(ctx context.Context) error {
+				value := counter
+				runtime.Gosched() // Give another increment the chance to interleave
+				counter = value + 1
+				return nil
+			})
+			if err != nil {
+				t.Errorf("WithSessionLock() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if counter != incrementers {
+		t.Errorf("counter = %d, want %d", counter, incrementers)
+	}
+}
+
+func TestRedisSessionStoreWithSessionLockSerializesUpdatesAcrossInstances(t *testing.T) {
+	first, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	second := newTestRedisStoreAt(t, mr, RedisSessionStoreConfig{})
+	client := first.redisClient()
+
+	var wg sync.WaitGroup
+	for i := range incrementers {
+		store := first
+		if i%2 == 1 {
+			store = second
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.WithSessionLock(t.Context(), "session-1", func(ctx context.Context) error {
+				value, err := client.Get(ctx, "counter").Int()
+				if err != nil && !errors.Is(err, redis.Nil) {
+					return err
+				}
+				runtime.Gosched()
+				return client.Set(ctx, "counter", value+1, 0).Err()
+			})
+			if err != nil {
+				t.Errorf("WithSessionLock() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := mr.Get("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != strconv.Itoa(incrementers) {
+		t.Errorf("counter = %s, want %d", value, incrementers)
+	}
+	if mr.Exists("mcp:session:lock:session-1") {
+		t.Error("lock key wasn't released")
+	}
+}
+
+func TestWithSessionLockGivesUpWhenContextIsDone(t *testing.T) {
+	store := newTestBaseStore(t, newMemoryBackend(), StoreOptions{})
+
+	held := make(chan struct{})
+	release := make(chan struct{})
+	go store.WithSessionLock(t.Context(), "session-1", func(ctx context.Context) error {
+		close(held)
+		<-release
+		return nil
+	})
+	<-held
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	err := store.WithSessionLock(ctx, "session-1", func(ctx context.Context) error {
+		t.Error("fn ran while the lock was held")
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WithSessionLock() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestRedisSessionStoreAcquireLock(t *testing.T) {
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	other := newTestRedisStoreAt(t, mr, RedisSessionStoreConfig{})
+	ctx := t.Context()
+
+	unlock, err := store.AcquireLock(ctx, "report", 10*time.Second)
+	if err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+	if ttl := mr.TTL("mcp:session:lock:report"); ttl != 10*time.Second {
+		t.Errorf("lock TTL = %s, want 10s", ttl)
+	}
+
+	// Another instance waits for the lock until its context is done
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := other.AcquireLock(waitCtx, "report", 10*time.Second); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("contended AcquireLock() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// Once released, it's acquired straight away
+	unlock()
+	unlock() // Releasing twice is harmless
+	if mr.Exists("mcp:session:lock:report") {
+		t.Error("lock key still exists after unlock")
+	}
+	unlockOther, err := other.AcquireLock(ctx, "report", 10*time.Second)
+	if err != nil {
+		t.Fatalf("AcquireLock() after release error = %v", err)
+	}
+	unlockOther()
+}
+
+func TestRedisSessionStoreAcquireLockWaitsForRelease(t *testing.T) {
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	other := newTestRedisStoreAt(t, mr, RedisSessionStoreConfig{})
+
+	unlock, err := store.AcquireLock(t.Context(), "report", 10*time.Second)
+	if err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+	time.AfterFunc(50*time.Millisecond, unlock)
+
+	start := time.Now()
+	unlockOther, err := other.AcquireLock(t.Context(), "report", 10*time.Second)
+	if err != nil {
+		t.Fatalf("AcquireLock() while held error = %v", err)
+	}
+	defer unlockOther()
+	if waited := time.Since(start); waited < 40*time.Millisecond {
+		t.Errorf("AcquireLock() returned after %s, before the holder released the lock", waited)
+	}
+}
+
+func TestRedisSessionStoreAcquireLockStaleUnlock(t *testing.T) {
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	ctx := t.Context()
+
+	unlockExpired, err := store.AcquireLock(ctx, "report", time.Second)
+	if err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+	mr.FastForward(2 * time.Second)
+
+	unlock, err := store.AcquireLock(ctx, "report", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireLock() after expiry error = %v", err)
+	}
+	defer unlock()
+
+	// Releasing the expired lock must not release the new holder's
+	unlockExpired()
+	if !mr.Exists("mcp:session:lock:report") {
+		t.Error("releasing an expired lock deleted the new holder's lock")
+	}
+}
+
+func TestRedisSessionStoreAcquireLockDefaults(t *testing.T) {
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{LockTTL: 5 * time.Second})
+
+	if _, err := store.AcquireLock(t.Context(), "", time.Second); err == nil {
+		t.Error("AcquireLock() with an empty key succeeded")
+	}
+
+	unlock, err := store.AcquireLock(t.Context(), "report", 0)
+	if err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+	defer unlock()
+	if ttl := mr.TTL("mcp:session:lock:report"); ttl != 5*time.Second {
+		t.Errorf("lock TTL = %s, want the 5s LockTTL", ttl)
+	}
+	if sessions, err := store.ListSessions(t.Context()); err != nil || len(sessions) != 0 {
+		t.Errorf("ListSessions() = %v, %v, want the lock left out", sessions, err)
+	}
+}
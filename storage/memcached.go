@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// memcachedMaxKeyLength is the longest key Memcached accepts
+const memcachedMaxKeyLength = 250
+
+// memcachedMaxRelativeExpiration is the largest expiration Memcached treats as
+// relative seconds; anything larger is interpreted as a Unix timestamp
+const memcachedMaxRelativeExpiration = 30 * 24 * time.Hour
+
+// MemcachedSessionStore implements StreamableHTTPSessionStore using Memcached as the backend
+type MemcachedSessionStore struct {
+	*BaseSessionStore
+	client *memcache.Client
+}
+
+// MemcachedSessionStoreConfig holds configuration for the Memcached session store
+type MemcachedSessionStoreConfig struct {
+	Addrs   []string      // Memcached server addresses (default: ["localhost:11211"])
+	Prefix  string        // Key prefix for session storage (default: "mcp:session:")
+	TTL     time.Duration // Session TTL (default: 1 hour, NoExpiry to never expire)
+	Server  *mcp.Server   // MCP server that loaded sessions are connected to (required)
+	Options StoreOptions  // Optional behaviour shared by all stores
+}
+
+// NewMemcachedSessionStore creates a new Memcached-backed session store
+func NewMemcachedSessionStore(config MemcachedSessionStoreConfig) (*MemcachedSessionStore, error) {
+	// Set defaults
+	if len(config.Addrs) == 0 {
+		config.Addrs = []string{"localhost:11211"}
+	}
+
+	client := memcache.New(config.Addrs...)
+
+	// Test connection
+	if err := client.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Memcached: %w: %w", ErrUnavailable, err)
+	}
+
+	store := &MemcachedSessionStore{
+		client: client,
+	}
+
+	base, err := newBaseSessionStore(store, baseSessionStoreConfig{
+		Prefix:  config.Prefix,
+		TTL:     config.TTL,
+		Server:  config.Server,
+		Options: config.Options,
+	})
+	if err != nil {
+		return nil, err
+	}
+	store.BaseSessionStore = base
+
+	return store, nil
+}
+
+// getRaw reads a session value from Memcached
+func (m *MemcachedSessionStore) getRaw(ctx context.Context, key string) ([]byte, error) {
+	item, err := m.client.Get(safeMemcachedKey(key))
+	if err != nil {
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get session from Memcached: %w: %w", ErrUnavailable, err)
+	}
+	return item.Value, nil
+}
+
+// setRaw writes a session value to Memcached
+func (m *MemcachedSessionStore) setRaw(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	if err := m.client.Set(&memcache.Item{
+		Key:        safeMemcachedKey(key),
+		Value:      data,
+		Expiration: memcachedExpiration(ttl),
+	}); err != nil {
+		return fmt.Errorf("failed to set session in Memcached: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// createRaw writes a session value to Memcached with add, so it's only
+// written if the key doesn't exist
+func (m *MemcachedSessionStore) createRaw(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	err := m.client.Add(&memcache.Item{
+		Key:        safeMemcachedKey(key),
+		Value:      data,
+		Expiration: memcachedExpiration(ttl),
+	})
+	if errors.Is(err, memcache.ErrNotStored) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create session in Memcached: %w: %w", ErrUnavailable, err)
+	}
+	return true, nil
+}
+
+// delRaw removes a session value from Memcached
+func (m *MemcachedSessionStore) delRaw(ctx context.Context, key string) error {
+	if err := m.client.Delete(safeMemcachedKey(key)); err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+		return fmt.Errorf("failed to delete session from Memcached: %w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// Close closes the Memcached connections
+func (m *MemcachedSessionStore) Close() error {
+	m.closeBase()
+	return m.client.Close()
+}
+
+// Health checks the health of the Memcached connection
+func (m *MemcachedSessionStore) Health(ctx context.Context) error {
+	if err := m.client.Ping(); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// safeMemcachedKey returns key if Memcached accepts it as-is. Keys are limited
+// to 250 bytes without whitespace or control characters, so anything that
+// doesn't fit is replaced by a hash. The original session ID is kept in the
+// stored payload.
+func safeMemcachedKey(key string) string {
+	if len(key) <= memcachedMaxKeyLength && isMemcachedSafe(key) {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// memcachedExpiration converts a TTL into a Memcached expiration value. An
+// expiration of 0 means never expire, so positive TTLs are rounded up to
+// whole seconds rather than down to 0.
+func memcachedExpiration(ttl time.Duration) int32 {
+	if ttl <= 0 {
+		return 0
+	}
+	if ttl > memcachedMaxRelativeExpiration {
+		return int32(time.Now().Add(ttl).Unix())
+	}
+	return int32((ttl + time.Second - 1) / time.Second)
+}
+
+// isMemcachedSafe reports whether a key only contains characters allowed by
+// the Memcached text protocol
+func isMemcachedSafe(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMemcached is a Memcached server speaking the subset of the text
+// protocol used by gomemcache for get, set, add, delete and ping
+type fakeMemcached struct {
+	listener net.Listener
+
+	mu          sync.Mutex
+	items       map[string][]byte
+	expirations map[string]int32
+}
+
+func newFakeMemcached(t *testing.T) *fakeMemcached {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	m := &fakeMemcached{
+		listener:    listener,
+		items:       make(map[string][]byte),
+		expirations: make(map[string]int32),
+	}
+	t.Cleanup(func() { listener.Close() })
+	go m.serve()
+	return m
+}
+
+func (m *fakeMemcached) addr() string {
+	return m.listener.Addr().String()
+}
+
+// keys returns the keys currently stored
+func (m *fakeMemcached) keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.items))
+	for key := range m.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (m *fakeMemcached) expiration(key string) int32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.expirations[key]
+}
+
+func (m *fakeMemcached) serve() {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			return
+		}
+		go m.handle(conn)
+	}
+}
+
+func (m *fakeMemcached) handle(conn net.Conn) {
+	defer conn.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "version":
+			fmt.Fprint(rw, "VERSION 1.6.0\r\n")
+		case "gets", "get":
+			m.mu.Lock()
+			for _, key := range fields[1:] {
+				if value, ok := m.items[key]; ok {
+					fmt.Fprintf(rw, "VALUE %s 0 %d 1\r\n%s\r\n", key, len(value), value)
+				}
+			}
+			m.mu.Unlock()
+			fmt.Fprint(rw, "END\r\n")
+		case "set", "add":
+			// <command> <key> <flags> <exptime> <bytes>
+			size, _ := strconv.Atoi(fields[4])
+			value := make([]byte, size+2)
+			if _, err := io.ReadFull(rw, value); err != nil {
+				return
+			}
+			expiration, _ := strconv.Atoi(fields[3])
+			m.mu.Lock()
+			if _, ok := m.items[fields[1]]; ok && fields[0] == "add" {
+				fmt.Fprint(rw, "NOT_STORED\r\n")
+			} else {
+				m.items[fields[1]] = value[:size]
+				m.expirations[fields[1]] = int32(expiration)
+				fmt.Fprint(rw, "STORED\r\n")
+			}
+			m.mu.Unlock()
+		case "delete":
+			m.mu.Lock()
+			if _, ok := m.items[fields[1]]; ok {
+				delete(m.items, fields[1])
+				fmt.Fprint(rw, "DELETED\r\n")
+			} else {
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+			}
+			m.mu.Unlock()
+		default:
+			fmt.Fprint(rw, "ERROR\r\n")
+		}
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func newTestMemcachedStore(t *testing.T, config MemcachedSessionStoreConfig) (*MemcachedSessionStore, *fakeMemcached) {
+	t.Helper()
+	server := newFakeMemcached(t)
+	config.Addrs = []string{server.addr()}
+	if config.Server == nil {
+		config.Server = newTestServer()
+	}
+	store, err := NewMemcachedSessionStore(config)
+	if err != nil {
+		t.Fatalf("NewMemcachedSessionStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, server
+}
+
+func TestMemcachedSessionStoreSetGetDelete(t *testing.T) {
+	store, server := newTestMemcachedStore(t, MemcachedSessionStoreConfig{TTL: time.Minute})
+	ctx := t.Context()
+
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := server.expiration("mcp:session:session-1"); got != 60 {
+		t.Errorf("expiration = %d, want 60", got)
+	}
+
+	// A second store has nothing cached, so it has to load from Memcached
+	other, err := NewMemcachedSessionStore(MemcachedSessionStoreConfig{
+		Addrs:  []string{server.addr()},
+		Server: newTestServer(),
+	})
+	if err != nil {
+		t.Fatalf("NewMemcachedSessionStore() error = %v", err)
+	}
+	defer other.Close()
+
+	transport, err := other.Get(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if transport == nil || transport.SessionID() != "session-1" {
+		t.Fatalf("Get() = %v, want the stored session", transport)
+	}
+
+	if err := store.Delete("session-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if keys := server.keys(); len(keys) != 0 {
+		t.Errorf("keys after Delete() = %v, want none", keys)
+	}
+	if got, err := store.Get(ctx, "session-1"); got != nil || err != nil {
+		t.Errorf("Get() after Delete() = %v, %v, want nil, nil", got, err)
+	}
+	if err := store.Delete("session-1"); err != nil {
+		t.Errorf("Delete() of a missing session error = %v", err)
+	}
+}
+
+func TestMemcachedSessionStoreLongKey(t *testing.T) {
+	prefix := strings.Repeat("p", memcachedMaxKeyLength) + ":"
+	store, server := newTestMemcachedStore(t, MemcachedSessionStoreConfig{Prefix: prefix})
+
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	keys := server.keys()
+	if len(keys) != 1 || keys[0] != safeMemcachedKey(prefix+"session-1") {
+		t.Fatalf("keys = %v, want the hashed key", keys)
+	}
+
+	// The payload holds the original session ID, so another store can load it
+	other, err := NewMemcachedSessionStore(MemcachedSessionStoreConfig{
+		Addrs:  []string{server.addr()},
+		Prefix: prefix,
+		Server: newTestServer(),
+	})
+	if err != nil {
+		t.Fatalf("NewMemcachedSessionStore() error = %v", err)
+	}
+	defer other.Close()
+	if transport, err := other.Get(t.Context(), "session-1"); err != nil || transport == nil {
+		t.Errorf("Get() = %v, %v, want the stored session", transport, err)
+	}
+}
+
+func TestSafeMemcachedKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		hashed bool
+	}{
+		{name: "short", key: "mcp:session:abc", hashed: false},
+		{name: "at limit", key: strings.Repeat("k", memcachedMaxKeyLength), hashed: false},
+		{name: "too long", key: strings.Repeat("k", memcachedMaxKeyLength+1), hashed: true},
+		{name: "space", key: "mcp session", hashed: true},
+		{name: "control character", key: "mcp:\x01", hashed: true},
+		{name: "delete", key: "mcp:\x7f", hashed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := safeMemcachedKey(tt.key)
+			if !tt.hashed {
+				if got != tt.key {
+					t.Errorf("safeMemcachedKey() = %q, want the key unchanged", got)
+				}
+				return
+			}
+			if !strings.HasPrefix(got, "sha256:") || len(got) > memcachedMaxKeyLength || !isMemcachedSafe(got) {
+				t.Errorf("safeMemcachedKey() = %q, want a safe hashed key", got)
+			}
+			if got != safeMemcachedKey(tt.key) {
+				t.Error("safeMemcachedKey() isn't stable")
+			}
+		})
+	}
+}
+
+func TestMemcachedExpiration(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		want int32
+	}{
+		{name: "no expiry", ttl: NoExpiry, want: 0},
+		{name: "zero", ttl: 0, want: 0},
+		{name: "under a second", ttl: 500 * time.Millisecond, want: 1},
+		{name: "whole seconds", ttl: time.Minute, want: 60},
+		{name: "partial second", ttl: 1500 * time.Millisecond, want: 2},
+		{name: "30 days", ttl: memcachedMaxRelativeExpiration, want: int32(memcachedMaxRelativeExpiration / time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := memcachedExpiration(tt.ttl); got != tt.want {
+				t.Errorf("memcachedExpiration(%s) = %d, want %d", tt.ttl, got, tt.want)
+			}
+		})
+	}
+
+	// Longer TTLs are sent as a Unix timestamp
+	ttl := memcachedMaxRelativeExpiration + time.Hour
+	want := time.Now().Add(ttl).Unix()
+	if got := int64(memcachedExpiration(ttl)); got < want-5 || got > want+5 {
+		t.Errorf("memcachedExpiration(%s) = %d, want about %d", ttl, got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// sessionWritesTotal counts session writes by whether they created a new
+	// session or updated an existing one. Only recorded when churn tracking is on.
+	sessionWritesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_session_writes_total",
+		Help: "Session writes by kind (create or update), recorded when session churn tracking is enabled.",
+	}, []string{"kind"})
+
+	// sessionStateTooLargeTotal counts writes rejected for exceeding
+	// StoreOptions.MaxStateBytes
+	sessionStateTooLargeTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mcp_session_state_too_large_total",
+		Help: "Session writes rejected because the serialized state exceeded the configured maximum size.",
+	})
+
+	// sessionStateBytes records the serialized size of every session write,
+	// including writes rejected for their size
+	sessionStateBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "mcp_session_state_bytes",
+		Help:    "Serialized session state size in bytes, per session write.",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+	})
+
+	// sessionCreateConflictsTotal counts StoreIfAbsent calls that found the
+	// session already created by another instance
+	sessionCreateConflictsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mcp_session_create_conflicts_total",
+		Help: "New sessions found to have been created by another instance first, and reloaded rather than overwritten.",
+	})
+
+	// corruptSessionsTotal counts session records that couldn't be decoded
+	// when loaded, whether or not they were dropped
+	corruptSessionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mcp_corrupt_sessions_total",
+		Help: "Session records loaded from the backend that couldn't be decoded.",
+	})
+
+	// sessionsActive tracks the number of sessions in the store. It's seeded
+	// from the backend at startup and then adjusted as sessions are created
+	// and deleted through this instance.
+	sessionsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mcp_sessions_active",
+		Help: "Sessions in the session store, counted at startup and adjusted as this instance creates and deletes sessions.",
+	})
+
+	// sessionLifecycleTotal counts session lifecycle events by type
+	sessionLifecycleTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_session_lifecycle_events_total",
+		Help: "Session lifecycle events handled by this instance, by event (created or deleted).",
+	}, []string{"event"})
+
+	// writeBehindDroppedTotal counts write events dropped because the
+	// write-behind queue was full
+	writeBehindDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mcp_session_write_behind_dropped_total",
+		Help: "Session write events dropped because the write-behind sink fell behind.",
+	})
+
+	// snapshotsTotal counts session snapshot exports by result
+	snapshotsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_session_snapshots_total",
+		Help: "Session snapshot exports by result (success or failure).",
+	}, []string{"result"})
+
+	// snapshotLastSuccess records when a snapshot export last succeeded, so
+	// stale backups can be alerted on
+	snapshotLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mcp_session_snapshot_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful session snapshot export.",
+	})
+
+	// storeConnected reports whether the background health check last reached
+	// the backend
+	storeConnected = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mcp_session_store_connected",
+		Help: "Whether the session store backend was reachable at the last health check (1) or not (0).",
+	}, []string{"backend"})
+
+	// redisClientResetsTotal counts Redis clients recreated after repeated
+	// failed health checks
+	redisClientResetsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mcp_redis_client_resets_total",
+		Help: "Redis clients closed and recreated after consecutive failed health checks.",
+	})
+)
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+)
+
+// SetReadOnly switches read-only mode on or off. While it's on, writes to the
+// backend (Set, Delete, Import and Touch) fail with ErrReadOnly, while sessions
+// are still loaded from the cache and the backend, e.g. so an instance keeps
+// serving existing sessions during backend maintenance.
+func (b *BaseSessionStore) SetReadOnly(readOnly bool) {
+	if b.readOnly.Swap(readOnly) == readOnly {
+		return
+	}
+	if readOnly {
+		log.Printf("Session store is now read-only, writes are rejected")
+	} else {
+		log.Printf("Session store is writable again")
+	}
+}
+
+// ReadOnly reports whether read-only mode is on
+func (b *BaseSessionStore) ReadOnly() bool {
+	return b.readOnly.Load()
+}
+
+// checkWritable returns ErrReadOnly for a write to sessionID in read-only mode
+func (b *BaseSessionStore) checkWritable(sessionID string) error {
+	if b.readOnly.Load() {
+		return fmt.Errorf("session %s not written: %w", sessionID, ErrReadOnly)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+)
+
+func TestRedisSessionStoreReadOnly(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := mr.Get("mcp:session:session-1")
+
+	store.SetReadOnly(true)
+	if !store.ReadOnly() {
+		t.Fatal("ReadOnly() = false after SetReadOnly(true)")
+	}
+	ctx := t.Context()
+	writes := map[string]error{
+		"Set":    store.Set("session-2", newTestTransport("session-2")),
+		"Delete": store.Delete("session-1"),
+		"Touch":  store.Touch(ctx, "session-1"),
+		"Import": store.Import(ctx, "session-3", []byte(stored), 0),
+	}
+	for name, err := range writes {
+		if !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s() in read-only mode error = %v, want ErrReadOnly", name, err)
+		}
+		if status := HTTPStatus(err); status != http.StatusServiceUnavailable {
+			t.Errorf("HTTPStatus(%s() error) = %d, want 503", name, status)
+		}
+	}
+	if mr.Exists("mcp:session:session-2") || mr.Exists("mcp:session:session-3") || !mr.Exists("mcp:session:session-1") {
+		t.Errorf("keys = %v, want only session-1, unchanged", mr.Keys())
+	}
+
+	// Reads still work, from the cache and from Redis
+	if got, err := store.Get(ctx, "session-1"); err != nil || got == nil {
+		t.Errorf("Get() in read-only mode = %v, %v, want the session", got, err)
+	}
+	other := newTestRedisStoreAt(t, mr, RedisSessionStoreConfig{})
+	other.SetReadOnly(true)
+	if got, err := other.Get(ctx, "session-1"); err != nil || got == nil {
+		t.Errorf("Get() from Redis in read-only mode = %v, %v, want the session", got, err)
+	}
+
+	store.SetReadOnly(false)
+	if err := store.Set("session-2", newTestTransport("session-2")); err != nil {
+		t.Errorf("Set() after leaving read-only mode error = %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRedisSessionStoreClusterNode(t *testing.T) {
+	for _, reply := range []string{"MOVED 3999 127.0.0.1:6381", "ASK 3999 127.0.0.1:6381"} {
+		t.Run(strings.Fields(reply)[0], func(t *testing.T) {
+			store, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+			mr.SetError(reply)
+			t.Cleanup(func() { mr.SetError("") })
+
+			_, err := store.Get(t.Context(), "session-1")
+			if !errors.Is(err, ErrUnavailable) {
+				t.Errorf("Get() error = %v, want ErrUnavailable", err)
+			}
+			if err == nil || !strings.Contains(err.Error(), reply) || !strings.Contains(err.Error(), "Redis Cluster node, which isn't supported") {
+				t.Errorf("Get() error = %v, want the reply explained as coming from a Redis Cluster node", err)
+			}
+
+			err = store.Set("session-1", newTestTransport("session-1"))
+			if err == nil || !strings.Contains(err.Error(), "Redis Cluster node") {
+				t.Errorf("Set() error = %v, want the reply explained as coming from a Redis Cluster node", err)
+			}
+		})
+	}
+
+	t.Run("other errors", func(t *testing.T) {
+		err := redisClusterError(errors.New("ERR unknown command"))
+		if strings.Contains(err.Error(), "Redis Cluster") {
+			t.Errorf("redisClusterError() = %v, want other errors left alone", err)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+//go:build integration
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// The tests in this file run against a real Redis started in a container for
+// each test, so they need Docker. Run them with make test-integration.
+
+// redisTestImage is the Redis image the integration tests run against
+const redisTestImage = "redis:7-alpine"
+
+// startRedisContainer starts a Redis container, removed when the test ends,
+// and returns its address
+func startRedisContainer(t *testing.T) string {
+	t.Helper()
+	container, err := testcontainers.GenericContainer(t.Context(), testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        redisTestImage,
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections"),
+		},
+		Started: true,
+	})
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatalf("failed to start Redis container: %v", err)
+	}
+	addr, err := container.Endpoint(t.Context(), "")
+	if err != nil {
+		t.Fatalf("failed to get Redis container address: %v", err)
+	}
+	return addr
+}
+
+// newIntegrationRedisStore returns a store using the Redis at addr
+func newIntegrationRedisStore(t *testing.T, addr string, config RedisSessionStoreConfig) *RedisSessionStore {
+	t.Helper()
+	config.Addr = addr
+	config.Server = newTestServer()
+	store, err := NewRedisSessionStore(config)
+	if err != nil {
+		t.Fatalf("NewRedisSessionStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestRedisIntegrationSetGetDelete(t *testing.T) {
+	addr := startRedisContainer(t)
+	store := newIntegrationRedisStore(t, addr, RedisSessionStoreConfig{})
+	ctx := t.Context()
+
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	other := newIntegrationRedisStore(t, addr, RedisSessionStoreConfig{})
+	loaded, err := other.Get(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("Get() from another instance error = %v", err)
+	}
+	if loaded == nil || loaded.SessionID() != "session-1" {
+		t.Fatalf("Get() from another instance = %v, want session-1", loaded)
+	}
+
+	if err := store.Delete("session-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, err := store.Get(ctx, "session-1"); got != nil || err != nil {
+		t.Errorf("Get() after Delete() = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestRedisIntegrationTTL(t *testing.T) {
+	addr := startRedisContainer(t)
+	store := newIntegrationRedisStore(t, addr, RedisSessionStoreConfig{TTL: time.Second})
+	ctx := t.Context()
+
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+
+	// Another instance has nothing cached, so it sees the key has expired
+	other := newIntegrationRedisStore(t, addr, RedisSessionStoreConfig{})
+	if got, err := other.Get(ctx, "session-1"); got != nil || err != nil {
+		t.Errorf("Get() after expiry = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestRedisIntegrationHealth(t *testing.T) {
+	store := newIntegrationRedisStore(t, startRedisContainer(t), RedisSessionStoreConfig{})
+	if err := store.Health(t.Context()); err != nil {
+		t.Errorf("Health() error = %v", err)
+	}
+}
+
+func TestRedisIntegrationMissingSession(t *testing.T) {
+	store := newIntegrationRedisStore(t, startRedisContainer(t), RedisSessionStoreConfig{})
+	ctx := t.Context()
+
+	if got, err := store.Get(ctx, "missing"); got != nil || err != nil {
+		t.Errorf("Get() = %v, %v, want nil, nil", got, err)
+	}
+	if err := store.Delete("missing"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+}
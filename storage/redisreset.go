@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisClient returns the client commands should be sent with. It can be
+// swapped by resetClient at any time, so callers fetch it for each command
+// rather than holding on to it.
+func (r *RedisSessionStore) redisClient() *redis.Client {
+	return r.client.Load()
+}
+
+// supervisedPing is the background health check. It counts consecutive
+// failures and, once there have been r.resetAfter of them, recreates the
+// client, since a pool that's got wedged stays unhealthy until it's replaced.
+// The count starts again after a reset, so while Redis itself is down the
+// client is only recreated every r.resetAfter checks.
+func (r *RedisSessionStore) supervisedPing(ctx context.Context) error {
+	err := r.Health(ctx)
+	if err == nil {
+		r.failures = 0
+		return nil
+	}
+
+	r.failures++
+	if r.resetAfter > 0 && r.failures >= r.resetAfter {
+		log.Printf("Redis health check failed %d times in a row, recreating the Redis client: %v", r.failures, err)
+		r.failures = 0
+		r.resetClient()
+	}
+	return err
+}
+
+// resetClient replaces the client with a new one built from the same
+// options, then closes the old one. Commands already running on the old
+// client fail with its connections closed, and ones started on it after that
+// fail with redis.ErrClosed, rather than waiting on the wedged pool. Session
+// operations report both as ErrUnavailable. Commands started after the swap
+// use the new client.
+func (r *RedisSessionStore) resetClient() {
+	old := r.redisClient()
+	options := *old.Options()
+	r.client.Store(redis.NewClient(&options))
+	redisClientResetsTotal.Inc()
+
+	if err := old.Close(); err != nil {
+		log.Printf("Failed to close the replaced Redis client: %v", err)
+	}
+}
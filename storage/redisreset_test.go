@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisSessionStoreResetClient(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	// The monitor never ticks, so the test drives the health checks itself
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{HealthCheckInterval: time.Hour, ResetAfterFailures: 3})
+	ctx := t.Context()
+	resets := testutil.ToFloat64(redisClientResetsTotal)
+	old := store.redisClient()
+
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	for i := range 2 {
+		if err := store.supervisedPing(ctx); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("health check %d error = %v, want ErrUnavailable", i+1, err)
+		}
+	}
+	// A passing check starts the count again
+	mr.SetError("")
+	if err := store.supervisedPing(ctx); err != nil {
+		t.Fatalf("health check error = %v", err)
+	}
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	for range 2 {
+		store.supervisedPing(ctx)
+	}
+	if store.redisClient() != old {
+		t.Fatal("client recreated before 3 consecutive failed health checks")
+	}
+
+	store.supervisedPing(ctx)
+	if store.redisClient() == old {
+		t.Fatal("client not recreated after 3 consecutive failed health checks")
+	}
+	if got := testutil.ToFloat64(redisClientResetsTotal) - resets; got != 1 {
+		t.Errorf("client resets counted = %v, want 1", got)
+	}
+	if err := old.Ping(ctx).Err(); !errors.Is(err, redis.ErrClosed) {
+		t.Errorf("replaced client Ping() error = %v, want redis.ErrClosed", err)
+	}
+
+	// The new client has the old one's options and serves sessions once Redis
+	// recovers
+	if store.redisClient().Options().Addr != mr.Addr() {
+		t.Errorf("new client address = %s, want %s", store.redisClient().Options().Addr, mr.Addr())
+	}
+	mr.SetError("")
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Errorf("Set() after the reset error = %v", err)
+	}
+	if !mr.Exists("mcp:session:session-1") {
+		t.Error("session not written through the new client")
+	}
+}
+
+func TestRedisSessionStoreResetClientMonitor(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{HealthCheckInterval: 10 * time.Millisecond, ResetAfterFailures: 2})
+	old := store.redisClient()
+
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	deadline := time.Now().Add(2 * time.Second)
+	for store.redisClient() == old {
+		if time.Now().After(deadline) {
+			t.Fatal("background health checks didn't recreate the client")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewRedisSessionStoreRejectsInvalidReset(t *testing.T) {
+	mr := miniredis.RunT(t)
+	for _, config := range []RedisSessionStoreConfig{
+		{ResetAfterFailures: -1, HealthCheckInterval: time.Second},
+		{ResetAfterFailures: 3},
+	} {
+		config.Addr = mr.Addr()
+		config.Server = newTestServer()
+		if store, err := NewRedisSessionStore(config); err == nil {
+			store.Close()
+			t.Errorf("NewRedisSessionStore(reset after %d, interval %s) succeeded, want an error", config.ResetAfterFailures, config.HealthCheckInterval)
+		}
+	}
+}
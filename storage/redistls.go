@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// redisTLSConfig adds the PEM-encoded CA certificate and client certificate
+// in config to base, e.g. the TLS settings parsed from a rediss:// URL. Any
+// of them turns TLS on. base is returned as-is if none are set. Malformed
+// PEM is rejected here, before anything connects.
+func redisTLSConfig(base *tls.Config, config RedisSessionStoreConfig) (*tls.Config, error) {
+	if config.TLSCACertPEM == "" && config.TLSCertPEM == "" && config.TLSKeyPEM == "" {
+		return base, nil
+	}
+	if (config.TLSCertPEM == "") != (config.TLSKeyPEM == "") {
+		return nil, errors.New("Redis TLS client certificate and key must be set together")
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		tlsConfig = base.Clone()
+	}
+
+	if config.TLSCACertPEM != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(config.TLSCACertPEM)) {
+			return nil, errors.New("invalid Redis TLS CA certificate: no PEM-encoded certificates found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.TLSCertPEM != "" {
+		cert, err := tls.X509KeyPair([]byte(config.TLSCertPEM), []byte(config.TLSKeyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis TLS client certificate or key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,359 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// sessionIDHeader is the header Streamable HTTP clients use to identify an
+// existing session
+const sessionIDHeader = "Mcp-Session-Id"
+
+// RouteSelector picks the key of the store a session belongs in. sessionID is
+// empty for the request that creates a session, whose ID isn't known yet. r
+// is nil when the store is used outside a request, e.g. by a background job.
+// A selector should return the same key for every request in a session; the
+// first key chosen for a session is kept either way.
+type RouteSelector func(sessionID string, r *http.Request) string
+
+// RoutingSessionStore spreads sessions across several stores, e.g. keeping
+// anonymous sessions in a short-lived store and authenticated ones in Redis.
+// Its Handler must wrap the Streamable HTTP handler so requests can be
+// routed: the SDK calls Set and Delete without the request, so the store a
+// session belongs in is resolved from each request as it arrives. A route is
+// only kept once the store has the session, and is dropped when the session
+// is deleted or found missing, so session IDs a client makes up aren't
+// remembered.
+type RoutingSessionStore struct {
+	stores       map[string]SessionStore
+	defaultStore string
+	selector     RouteSelector
+
+	mu        sync.Mutex
+	routes    map[string]string                         // Store key by session ID, for sessions the store has
+	requested map[string]*requestedRoute                // Keys selected by in-flight requests for other sessions
+	pending   map[string]*mcp.StreamableServerTransport // New sessions waiting for their request's route
+	creating  int                                       // Session-creating requests in flight
+}
+
+// requestedRoute is the store selected for a session by the requests using
+// it that are in flight, before the store has been found to have the session
+type requestedRoute struct {
+	key      string
+	requests int
+}
+
+// RoutingSessionStoreConfig holds configuration for the routing session store
+type RoutingSessionStoreConfig struct {
+	Stores       map[string]SessionStore // Stores to route between, by key (required)
+	DefaultStore string                  // Key used when Select returns an unknown key (required)
+	Select       RouteSelector           // Picks the store for each session (required)
+}
+
+// NewRoutingSessionStore creates a session store that routes each session to
+// one of config.Stores
+func NewRoutingSessionStore(config RoutingSessionStoreConfig) (*RoutingSessionStore, error) {
+	if _, ok := config.Stores[config.DefaultStore]; !ok {
+		return nil, fmt.Errorf("default store %q is not one of the routed stores", config.DefaultStore)
+	}
+	if config.Select == nil {
+		return nil, errors.New("a route selector is required")
+	}
+
+	return &RoutingSessionStore{
+		stores:       config.Stores,
+		defaultStore: config.DefaultStore,
+		selector:     config.Select,
+		routes:       make(map[string]string),
+		requested:    make(map[string]*requestedRoute),
+		pending:      make(map[string]*mcp.StreamableServerTransport),
+	}, nil
+}
+
+// selectKey runs the selector, falling back to the default store for keys
+// that don't name a store
+func (s *RoutingSessionStore) selectKey(sessionID string, r *http.Request) string {
+	key := s.selector(sessionID, r)
+	if _, ok := s.stores[key]; !ok {
+		return s.defaultStore
+	}
+	return key
+}
+
+// keyLocked returns the key of the store sessionID belongs in: its route if
+// it has one, else the store selected by a request in flight for it, else
+// the selector's choice without a request. s.mu must be held.
+func (s *RoutingSessionStore) keyLocked(sessionID string) string {
+	if key, ok := s.routes[sessionID]; ok {
+		return key
+	}
+	if requested, ok := s.requested[sessionID]; ok {
+		return requested.key
+	}
+	return s.selectKey(sessionID, nil)
+}
+
+// beginRequest selects the store for a request naming sessionID, so the
+// SDK's calls while serving it reach that store. It returns a function that
+// ends the request.
+func (s *RoutingSessionStore) beginRequest(sessionID string, r *http.Request) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.routes[sessionID]; ok {
+		return func() {}
+	}
+	requested, ok := s.requested[sessionID]
+	if !ok {
+		requested = &requestedRoute{key: s.selectKey(sessionID, r)}
+		s.requested[sessionID] = requested
+	}
+	requested.requests++
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if requested.requests--; requested.requests == 0 && s.requested[sessionID] == requested {
+			delete(s.requested, sessionID)
+		}
+	}
+}
+
+// Handler wraps the Streamable HTTP handler so sessions are routed by the
+// requests that use them. Requests for an existing session resolve its store
+// before they're served. The store for a new session is chosen from the
+// request that creates it; the SDK stores the session before that request's
+// response carries the new ID, so the write is held back until then.
+func (s *RoutingSessionStore) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sessionID := r.Header.Get(sessionIDHeader); sessionID != "" {
+			defer s.beginRequest(sessionID, r)()
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		s.mu.Lock()
+		s.creating++
+		s.mu.Unlock()
+		defer s.doneCreating()
+
+		rw := &routingResponseWriter{ResponseWriter: w, store: s, key: s.selectKey("", r)}
+		next.ServeHTTP(rw, r)
+		rw.claim()
+	})
+}
+
+// claimSession routes a session created by a request to key and writes it to
+// that store if it was held back. A session that wasn't held back is routed
+// straight away, since the server created it and is about to store it.
+func (s *RoutingSessionStore) claimSession(sessionID, key string) {
+	s.mu.Lock()
+	if existing, ok := s.routes[sessionID]; ok {
+		key = existing
+	}
+	session, ok := s.pending[sessionID]
+	delete(s.pending, sessionID)
+	if !ok {
+		s.routes[sessionID] = key
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	s.store(sessionID, key, session, "Failed to store new session %s in the %q store: %v")
+}
+
+// store writes a session to the store key and routes the session there if
+// the write succeeds, logging a failure with format
+func (s *RoutingSessionStore) store(sessionID, key string, session *mcp.StreamableServerTransport, format string) {
+	if err := s.stores[key].Set(sessionID, session); err != nil {
+		log.Printf(format, sessionID, key, err)
+		return
+	}
+	s.confirm(sessionID, key)
+}
+
+// confirm routes sessionID to key now that the store has the session
+func (s *RoutingSessionStore) confirm(sessionID, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[sessionID] = key
+}
+
+// doneCreating ends a session-creating request. Once none are left, sessions
+// still held back weren't announced by any response, so they're routed
+// without a request rather than kept in memory indefinitely.
+func (s *RoutingSessionStore) doneCreating() {
+	s.mu.Lock()
+	s.creating--
+	if s.creating > 0 || len(s.pending) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	orphaned := s.pending
+	s.pending = make(map[string]*mcp.StreamableServerTransport)
+	keys := make(map[string]string, len(orphaned))
+	for sessionID := range orphaned {
+		keys[sessionID] = s.keyLocked(sessionID)
+	}
+	s.mu.Unlock()
+
+	for sessionID, session := range orphaned {
+		s.store(sessionID, keys[sessionID], session, "Failed to store session %s in the %q store: %v")
+	}
+}
+
+// Get loads a session from the store it's routed to. The session is routed
+// there if the store has it, and its route is dropped if the store doesn't,
+// e.g. because it expired.
+func (s *RoutingSessionStore) Get(ctx context.Context, sessionID string) (*mcp.StreamableServerTransport, error) {
+	s.mu.Lock()
+	if session, ok := s.pending[sessionID]; ok {
+		s.mu.Unlock()
+		return session, nil
+	}
+	key := s.keyLocked(sessionID)
+	s.mu.Unlock()
+
+	session, err := s.stores[key].Get(ctx, sessionID)
+	switch {
+	case err == nil && session != nil:
+		s.confirm(sessionID, key)
+	case err == nil, errors.Is(err, ErrNotFound):
+		s.mu.Lock()
+		if s.routes[sessionID] == key {
+			delete(s.routes, sessionID)
+		}
+		s.mu.Unlock()
+	}
+	return session, err
+}
+
+// Set writes a session to the store it's routed to. A session created while
+// a session-creating request is in flight is held back until that request's
+// response names it, so it lands in the store the request was routed to.
+func (s *RoutingSessionStore) Set(sessionID string, session *mcp.StreamableServerTransport) error {
+	s.mu.Lock()
+	key, ok := s.routes[sessionID]
+	if !ok && s.creating > 0 {
+		s.pending[sessionID] = session
+		s.mu.Unlock()
+		return nil
+	}
+	if !ok {
+		key = s.keyLocked(sessionID)
+	}
+	s.mu.Unlock()
+
+	if err := s.stores[key].Set(sessionID, session); err != nil {
+		return err
+	}
+	s.confirm(sessionID, key)
+	return nil
+}
+
+// Delete removes a session from the store it's routed to and forgets its route
+func (s *RoutingSessionStore) Delete(sessionID string) error {
+	s.mu.Lock()
+	delete(s.pending, sessionID)
+	key := s.keyLocked(sessionID)
+	delete(s.routes, sessionID)
+	s.mu.Unlock()
+
+	return s.stores[key].Delete(sessionID)
+}
+
+// Range calls f for the sessions held back and for every routed store's sessions
+func (s *RoutingSessionStore) Range(f func(sessionID string, session *mcp.StreamableServerTransport)) {
+	s.mu.Lock()
+	pending := make(map[string]*mcp.StreamableServerTransport, len(s.pending))
+	for sessionID, session := range s.pending {
+		pending[sessionID] = session
+	}
+	s.mu.Unlock()
+
+	for sessionID, session := range pending {
+		f(sessionID, session)
+	}
+	for _, store := range s.stores {
+		store.Range(f)
+	}
+}
+
+// OnSessionClosed registers hook with every routed store
+func (s *RoutingSessionStore) OnSessionClosed(hook func(sessionID string)) {
+	for _, store := range s.stores {
+		store.OnSessionClosed(hook)
+	}
+}
+
+// Health checks every routed store
+func (s *RoutingSessionStore) Health(ctx context.Context) error {
+	var errs []error
+	for key, store := range s.stores {
+		if err := store.Health(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%q store: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every routed store
+func (s *RoutingSessionStore) Close() error {
+	var errs []error
+	for key, store := range s.stores {
+		if err := store.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %q store: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// routingResponseWriter claims the session a creating request's response
+// names before anything is sent to the client
+type routingResponseWriter struct {
+	http.ResponseWriter
+	store   *RoutingSessionStore
+	key     string
+	claimed bool
+}
+
+// claim routes the session named by the response's session ID header, if any
+func (w *routingResponseWriter) claim() {
+	if w.claimed {
+		return
+	}
+	w.claimed = true
+	if sessionID := w.Header().Get(sessionIDHeader); sessionID != "" {
+		w.store.claimSession(sessionID, w.key)
+	}
+}
+
+func (w *routingResponseWriter) WriteHeader(status int) {
+	w.claim()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *routingResponseWriter) Write(p []byte) (int, error) {
+	w.claim()
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends buffered data to the client, so event streams aren't held up
+func (w *routingResponseWriter) Flush() {
+	w.claim()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *routingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// fakeMCPHandler stands in for the SDK's Streamable HTTP handler: requests
+// without a session ID create and store a session, DELETE deletes one, and
+// other requests load theirs, responding 404 if it's missing
+func fakeMCPHandler(store SessionStore) http.Handler {
+	var nextID atomic.Int64
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.Header.Get(sessionIDHeader)
+		if sessionID == "" {
+			sessionID = fmt.Sprintf("session-%d", nextID.Add(1))
+			if err := store.Set(sessionID, newTestTransport(sessionID)); err != nil {
+				http.Error(w, err.Error(), HTTPStatus(err))
+				return
+			}
+			w.Header().Set(sessionIDHeader, sessionID)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		session, err := store.Get(r.Context(), sessionID)
+		if err != nil {
+			http.Error(w, err.Error(), HTTPStatus(err))
+			return
+		}
+		if session == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			if err := store.Delete(sessionID); err != nil {
+				http.Error(w, err.Error(), HTTPStatus(err))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// routeByAuthorization sends requests with an Authorization header to the
+// "authenticated" store and everything else to "anonymous"
+func routeByAuthorization(sessionID string, r *http.Request) string {
+	if r != nil && r.Header.Get("Authorization") != "" {
+		return "authenticated"
+	}
+	return "anonymous"
+}
+
+type routingTest struct {
+	store         *RoutingSessionStore
+	handler       http.Handler
+	anonymous     *miniredis.Miniredis
+	authenticated *miniredis.Miniredis
+}
+
+func newRoutingTest(t *testing.T) *routingTest {
+	t.Helper()
+	anonymous, anonymousRedis := newTestRedisStore(t, RedisSessionStoreConfig{})
+	authenticated, authenticatedRedis := newTestRedisStore(t, RedisSessionStoreConfig{})
+	store, err := NewRoutingSessionStore(RoutingSessionStoreConfig{
+		Stores: map[string]SessionStore{
+			"anonymous":     anonymous,
+			"authenticated": authenticated,
+		},
+		DefaultStore: "anonymous",
+		Select:       routeByAuthorization,
+	})
+	if err != nil {
+		t.Fatalf("NewRoutingSessionStore() error = %v", err)
+	}
+	return &routingTest{
+		store:         store,
+		handler:       store.Handler(fakeMCPHandler(store)),
+		anonymous:     anonymousRedis,
+		authenticated: authenticatedRedis,
+	}
+}
+
+// do sends a request for sessionID, an empty one creating a session, and
+// returns the response
+func (rt *routingTest) do(method, sessionID string, authenticated bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/mcp", nil)
+	if sessionID != "" {
+		req.Header.Set(sessionIDHeader, sessionID)
+	}
+	if authenticated {
+		req.Header.Set("Authorization", "Bearer token")
+	}
+	rec := httptest.NewRecorder()
+	rt.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRoutingSessionStoreRoutesConsistently(t *testing.T) {
+	rt := newRoutingTest(t)
+
+	authenticated := rt.do(http.MethodPost, "", true).Header().Get(sessionIDHeader)
+	anonymous := rt.do(http.MethodPost, "", false).Header().Get(sessionIDHeader)
+	if authenticated == "" || anonymous == "" {
+		t.Fatal("session-creating requests didn't return session IDs")
+	}
+
+	if !rt.authenticated.Exists("mcp:session:"+authenticated) || rt.anonymous.Exists("mcp:session:"+authenticated) {
+		t.Errorf("authenticated session %s wasn't stored only in the authenticated store", authenticated)
+	}
+	if !rt.anonymous.Exists("mcp:session:"+anonymous) || rt.authenticated.Exists("mcp:session:"+anonymous) {
+		t.Errorf("anonymous session %s wasn't stored only in the anonymous store", anonymous)
+	}
+
+	// Later requests reach the session's store, even if they'd select
+	// another one, e.g. after the client dropped its Authorization header
+	for _, withAuth := range []bool{true, false, true} {
+		if rec := rt.do(http.MethodPost, authenticated, withAuth); rec.Code != http.StatusOK {
+			t.Errorf("request for %s (authorized: %v) status = %d, want 200", authenticated, withAuth, rec.Code)
+		}
+		if rec := rt.do(http.MethodPost, anonymous, false); rec.Code != http.StatusOK {
+			t.Errorf("request for %s status = %d, want 200", anonymous, rec.Code)
+		}
+	}
+
+	if rec := rt.do(http.MethodDelete, authenticated, false); rec.Code != http.StatusOK {
+		t.Fatalf("DELETE status = %d, want 200", rec.Code)
+	}
+	if rt.authenticated.Exists("mcp:session:" + authenticated) {
+		t.Error("deleted session is still in the authenticated store")
+	}
+	if !rt.anonymous.Exists("mcp:session:" + anonymous) {
+		t.Error("deleting one session removed another")
+	}
+}
+
+func TestRoutingSessionStoreRoutesAfterRestart(t *testing.T) {
+	rt := newRoutingTest(t)
+	sessionID := rt.do(http.MethodPost, "", true).Header().Get(sessionIDHeader)
+
+	// A new instance has no routes, so the selector has to pick the same store
+	restarted, err := NewRoutingSessionStore(RoutingSessionStoreConfig{
+		Stores:       rt.store.stores,
+		DefaultStore: "anonymous",
+		Select:       routeByAuthorization,
+	})
+	if err != nil {
+		t.Fatalf("NewRoutingSessionStore() error = %v", err)
+	}
+	rt.store, rt.handler = restarted, restarted.Handler(fakeMCPHandler(restarted))
+
+	if rec := rt.do(http.MethodPost, sessionID, true); rec.Code != http.StatusOK {
+		t.Errorf("request after restart status = %d, want 200", rec.Code)
+	}
+	if key := restarted.routes[sessionID]; key != "authenticated" {
+		t.Errorf("session routed to %q after restart, want authenticated", key)
+	}
+}
+
+func TestRoutingSessionStoreDoesNotRememberUnknownSessions(t *testing.T) {
+	rt := newRoutingTest(t)
+
+	for i := range 100 {
+		sessionID := fmt.Sprintf("made-up-%d", i)
+		if rec := rt.do(http.MethodPost, sessionID, i%2 == 0); rec.Code != http.StatusNotFound {
+			t.Fatalf("request for made-up session status = %d, want 404", rec.Code)
+		}
+	}
+
+	rt.store.mu.Lock()
+	defer rt.store.mu.Unlock()
+	if len(rt.store.routes) != 0 || len(rt.store.requested) != 0 || len(rt.store.pending) != 0 {
+		t.Errorf("routing state kept for made-up sessions: %d routes, %d requested, %d pending",
+			len(rt.store.routes), len(rt.store.requested), len(rt.store.pending))
+	}
+}
+
+func TestRoutingSessionStoreOutsideRequests(t *testing.T) {
+	rt := newRoutingTest(t)
+
+	// Without a request the selector picks the default store
+	if err := rt.store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if !rt.anonymous.Exists("mcp:session:session-1") {
+		t.Error("session written outside a request isn't in the default store")
+	}
+	if session, err := rt.store.Get(t.Context(), "session-1"); err != nil || session == nil {
+		t.Errorf("Get() = %v, %v, want the session", session, err)
+	}
+	if err := rt.store.Delete("session-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if rt.anonymous.Exists("mcp:session:session-1") {
+		t.Error("session still stored after Delete()")
+	}
+}
+
+func TestNewRoutingSessionStoreRejectsInvalidConfig(t *testing.T) {
+	store, _ := newTestRedisStore(t, RedisSessionStoreConfig{})
+	stores := map[string]SessionStore{"redis": store}
+
+	if _, err := NewRoutingSessionStore(RoutingSessionStoreConfig{Stores: stores, DefaultStore: "memory", Select: routeByAuthorization}); err == nil {
+		t.Error("NewRoutingSessionStore() with an unknown default store succeeded")
+	}
+	if _, err := NewRoutingSessionStore(RoutingSessionStoreConfig{Stores: stores, DefaultStore: "redis"}); err == nil {
+		t.Error("NewRoutingSessionStore() without a selector succeeded")
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// selfTestKeyVersion takes the place of the key version in the keys written
+// by SelfTest, so listings skip them and they can't collide with sessions
+const selfTestKeyVersion = "selftest"
+
+// selfTestTTL bounds how long a self-test record outlives a failed delete
+const selfTestTTL = time.Minute
+
+// SelfTest checks the whole path a session takes through the store before any
+// traffic is accepted: it encodes a synthetic session the way Set does,
+// writes it under a reserved key, reads it back, checks the bytes and the
+// decoded session match what was written, then deletes it. A pinging health
+// check can pass while this fails, e.g. when the backend truncates values or
+// the encoding options produce records that can't be read back. Mismatches
+// are reported as ErrSerialization. The record expires on its own if it
+// can't be deleted. In read-only mode nothing is written, so it's skipped.
+func (b *BaseSessionStore) SelfTest(ctx context.Context) error {
+	if b.readOnly.Load() {
+		log.Printf("Skipping session store self-test, the store is read-only")
+		return nil
+	}
+
+	written := SessionData{
+		SessionID: "selftest-" + rand.Text(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	data, err := b.marshal(written)
+	if err != nil {
+		return fmt.Errorf("failed to marshal self-test session: %w: %w", ErrSerialization, err)
+	}
+
+	key := b.prefix + selfTestKeyVersion + ":" + written.SessionID
+	if err := b.backend.setRaw(ctx, key, data, selfTestTTL); err != nil {
+		return fmt.Errorf("failed to write self-test session: %w", err)
+	}
+	defer func() {
+		if err := b.backend.delRaw(ctx, key); err != nil {
+			log.Printf("Failed to delete self-test session %s, it will expire within %s: %v", written.SessionID, selfTestTTL, err)
+		}
+	}()
+
+	read, err := b.backend.getRaw(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to read back self-test session: %w", err)
+	}
+	if !bytes.Equal(read, data) {
+		return fmt.Errorf("self-test session read back differs from what was written (%d bytes written, %d read): %w", len(data), len(read), ErrSerialization)
+	}
+
+	var loaded SessionData
+	if err := json.Unmarshal(read, &loaded); err != nil {
+		return fmt.Errorf("failed to unmarshal self-test session: %w: %w", ErrSerialization, err)
+	}
+	if loaded.SessionID != written.SessionID || !loaded.UpdatedAt.Equal(written.UpdatedAt) {
+		return fmt.Errorf("self-test session decoded as %+v, wrote %+v: %w", loaded, written, ErrSerialization)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+// truncatingBackend drops the last byte of every value it returns, like a
+// backend that silently truncates values
+type truncatingBackend struct {
+	*memoryBackend
+}
+
+func (t truncatingBackend) getRaw(ctx context.Context, key string) ([]byte, error) {
+	data, err := t.memoryBackend.getRaw(ctx, key)
+	if len(data) > 0 {
+		data = data[:len(data)-1]
+	}
+	return data, err
+}
+
+func TestBaseSessionStoreSelfTest(t *testing.T) {
+	backend := newMemoryBackend()
+	store := newTestBaseStore(t, backend, StoreOptions{})
+
+	if err := store.SelfTest(t.Context()); err != nil {
+		t.Fatalf("SelfTest() error = %v", err)
+	}
+	if keys := backend.keys(); len(keys) != 0 {
+		t.Errorf("keys left after SelfTest() = %v, want none", keys)
+	}
+	if backend.getCount() != 1 {
+		t.Errorf("SelfTest() read %d records, want 1", backend.getCount())
+	}
+}
+
+func TestBaseSessionStoreSelfTestMismatch(t *testing.T) {
+	backend := newMemoryBackend()
+	store := newTestBaseStore(t, truncatingBackend{backend}, StoreOptions{})
+
+	err := store.SelfTest(t.Context())
+	if !errors.Is(err, ErrSerialization) || !strings.Contains(err.Error(), "differs from what was written") {
+		t.Errorf("SelfTest() error = %v, want a mismatch reported as ErrSerialization", err)
+	}
+	if keys := backend.keys(); len(keys) != 0 {
+		t.Errorf("keys left after a failed SelfTest() = %v, want none", keys)
+	}
+}
+
+func TestBaseSessionStoreSelfTestWriteError(t *testing.T) {
+	backend := newMemoryBackend()
+	backend.setErr = errors.New("connection refused")
+	store := newTestBaseStore(t, backend, StoreOptions{})
+
+	if err := store.SelfTest(t.Context()); !errors.Is(err, backend.setErr) {
+		t.Errorf("SelfTest() error = %v, want the write error", err)
+	}
+}
+
+func TestBaseSessionStoreSelfTestReadOnly(t *testing.T) {
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	backend := newMemoryBackend()
+	backend.setErr = errors.New("write attempted")
+	store := newTestBaseStore(t, backend, StoreOptions{ReadOnly: true})
+
+	if err := store.SelfTest(t.Context()); err != nil {
+		t.Errorf("SelfTest() in read-only mode error = %v, want it skipped", err)
+	}
+	if backend.getCount() != 0 {
+		t.Errorf("SelfTest() in read-only mode read %d records, want none", backend.getCount())
+	}
+}
+
+func TestRedisSessionStoreSelfTest(t *testing.T) {
+	store, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.SelfTest(t.Context()); err != nil {
+		t.Fatalf("SelfTest() error = %v", err)
+	}
+	if keys := mr.Keys(); len(keys) != 1 || keys[0] != "mcp:session:session-1" {
+		t.Errorf("keys after SelfTest() = %v, want only the real session", keys)
+	}
+}
@@ -0,0 +1,417 @@
+package storage
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SnapshotFormat identifies session snapshot archives in their header
+const SnapshotFormat = "mcp-session-snapshot"
+
+// SnapshotVersion is the version of the snapshot archive format written by
+// SnapshotExporter
+const SnapshotVersion = 1
+
+// Snapshot exporter defaults
+const (
+	defaultSnapshotPageDelay = 50 * time.Millisecond
+	snapshotTimeFormat       = "20060102T150405Z"
+)
+
+// A snapshot archive is gzip-compressed JSON lines: a SnapshotHeader followed
+// by one SnapshotRecord per session.
+
+// SnapshotHeader is the first line of a snapshot archive
+type SnapshotHeader struct {
+	Format    string    `json:"format"` // Always SnapshotFormat
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SnapshotRecord is a single session in a snapshot archive
+type SnapshotRecord struct {
+	SessionID string          `json:"session_id"`
+	Data      json.RawMessage `json:"data"`                // The stored record, as returned by Inspect
+	ExpiresAt time.Time       `json:"expires_at,omitzero"` // Zero if the session never expires or the store can't tell
+}
+
+// SnapshotSink stores snapshot archives, e.g. in a local directory or an
+// S3-compatible bucket. WriteSnapshot reads the archive from r until EOF and
+// must not leave a partial archive under name if it fails.
+type SnapshotSink interface {
+	WriteSnapshot(ctx context.Context, name string, r io.Reader) error
+}
+
+// DirSnapshotSink writes snapshot archives as files in a directory
+type DirSnapshotSink struct {
+	Dir string
+}
+
+// WriteSnapshot implements SnapshotSink. The archive is written to a
+// temporary file that's renamed into place once complete.
+func (s DirSnapshotSink) WriteSnapshot(ctx context.Context, name string, r io.Reader) error {
+	tmp, err := os.CreateTemp(s.Dir, "."+name+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.Dir, name))
+}
+
+// SnapshotExporterConfig holds configuration for a SnapshotExporter
+type SnapshotExporterConfig struct {
+	// Store is the store to export. It must implement SessionInspector and
+	// either SessionPager or SessionLister.
+	Store SessionStore
+
+	// Sink receives each archive (required)
+	Sink SnapshotSink
+
+	// Interval between exports when running in the background (required
+	// for Run)
+	Interval time.Duration
+
+	// PageSize is how many sessions are listed and read between pauses
+	// (default: DefaultPageSize)
+	PageSize int
+
+	// PageDelay is the pause between pages, which spreads the export's load
+	// on the backend (default: 50ms, negative for no pause)
+	PageDelay time.Duration
+}
+
+// SnapshotExporter writes every session in a store to a SnapshotSink, for
+// disaster recovery. Sessions are read with Inspect, so exporting doesn't
+// load them into the cache or refresh their TTLs.
+type SnapshotExporter struct {
+	store     SessionStore
+	inspector SessionInspector
+	sink      SnapshotSink
+	interval  time.Duration
+	pageSize  int
+	pageDelay time.Duration
+}
+
+// NewSnapshotExporter creates a SnapshotExporter
+func NewSnapshotExporter(config SnapshotExporterConfig) (*SnapshotExporter, error) {
+	if config.Sink == nil {
+		return nil, fmt.Errorf("a snapshot sink is required")
+	}
+	inspector, ok := config.Store.(SessionInspector)
+	if !ok {
+		return nil, fmt.Errorf("the session store doesn't support reading stored sessions")
+	}
+	_, canPage := config.Store.(SessionPager)
+	_, canList := config.Store.(SessionLister)
+	if !canPage && !canList {
+		return nil, fmt.Errorf("the session store doesn't support listing sessions")
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = DefaultPageSize
+	}
+	if config.PageDelay == 0 {
+		config.PageDelay = defaultSnapshotPageDelay
+	}
+
+	return &SnapshotExporter{
+		store:     config.Store,
+		inspector: inspector,
+		sink:      config.Sink,
+		interval:  config.Interval,
+		pageSize:  config.PageSize,
+		pageDelay: config.PageDelay,
+	}, nil
+}
+
+// Run exports a snapshot every Interval until ctx is done. Failures are
+// logged and counted in mcp_session_snapshots_total, and the next export is
+// attempted at the next interval.
+func (e *SnapshotExporter) Run(ctx context.Context) {
+	if e.interval <= 0 {
+		log.Printf("Session snapshots disabled, no interval configured")
+		return
+	}
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		name, count, err := e.Export(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Session snapshot failed: %v", err)
+			}
+			continue
+		}
+		log.Printf("Exported %d sessions to %s in %s", count, name, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// Export writes one snapshot of every session to the sink, returning the
+// archive's name and the number of sessions in it. Sessions that expire
+// between being listed and being read are left out.
+func (e *SnapshotExporter) Export(ctx context.Context) (string, int, error) {
+	createdAt := time.Now().UTC()
+	name := "sessions-" + createdAt.Format(snapshotTimeFormat) + ".jsonl.gz"
+
+	// The archive is streamed to the sink as it's built, so large stores
+	// never have to fit in memory
+	pr, pw := io.Pipe()
+	var count int
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		var err error
+		count, err = e.writeArchive(ctx, pw, createdAt)
+		pw.CloseWithError(err)
+	}()
+
+	err := e.sink.WriteSnapshot(ctx, name, pr)
+	// Unblock the writer if the sink stopped reading early
+	pr.Close()
+	<-written
+	if err != nil {
+		snapshotsTotal.WithLabelValues("failure").Inc()
+		return "", 0, fmt.Errorf("failed to export session snapshot: %w", err)
+	}
+
+	snapshotsTotal.WithLabelValues("success").Inc()
+	snapshotLastSuccess.SetToCurrentTime()
+	return name, count, nil
+}
+
+// writeArchive writes the archive for a snapshot to w
+func (e *SnapshotExporter) writeArchive(ctx context.Context, w io.Writer, createdAt time.Time) (int, error) {
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+	if err := enc.Encode(SnapshotHeader{
+		Format:    SnapshotFormat,
+		Version:   SnapshotVersion,
+		CreatedAt: createdAt,
+	}); err != nil {
+		return 0, err
+	}
+
+	expiry, _ := e.store.(SessionExpiryReader)
+	seen := make(map[string]bool) // Paged listings may repeat IDs
+	var count int
+	err := e.eachPage(ctx, func(sessionIDs []string) error {
+		for _, sessionID := range sessionIDs {
+			if seen[sessionID] {
+				continue
+			}
+			seen[sessionID] = true
+
+			record, err := e.record(ctx, expiry, sessionID)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, gz.Close()
+}
+
+// record reads a single session for the archive
+func (e *SnapshotExporter) record(ctx context.Context, expiry SessionExpiryReader, sessionID string) (SnapshotRecord, error) {
+	data, err := e.inspector.Inspect(ctx, sessionID)
+	if err != nil {
+		return SnapshotRecord{}, fmt.Errorf("failed to read session %s: %w", sessionID, err)
+	}
+	record := SnapshotRecord{SessionID: sessionID, Data: data}
+
+	if expiry != nil {
+		ttl, err := expiry.SessionTTL(ctx, sessionID)
+		if err != nil {
+			return SnapshotRecord{}, fmt.Errorf("failed to read TTL of session %s: %w", sessionID, err)
+		}
+		if ttl > 0 {
+			record.ExpiresAt = time.Now().Add(ttl).UTC()
+		}
+	}
+	return record, nil
+}
+
+// eachPage calls fn with the store's session IDs a page at a time, pausing
+// for PageDelay between pages. Stores that can't page are listed in one go
+// and then split into pages, so reads are still spread out.
+func (e *SnapshotExporter) eachPage(ctx context.Context, fn func(sessionIDs []string) error) error {
+	pause := func() error {
+		if e.pageDelay <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(e.pageDelay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if pager, ok := e.store.(SessionPager); ok {
+		cursor := ""
+		for {
+			sessionIDs, next, err := pager.ListSessionsPage(ctx, cursor, e.pageSize)
+			if err != nil {
+				return fmt.Errorf("failed to list sessions: %w", err)
+			}
+			if err := fn(sessionIDs); err != nil {
+				return err
+			}
+			if next == "" {
+				return nil
+			}
+			cursor = next
+			if err := pause(); err != nil {
+				return err
+			}
+		}
+	}
+
+	sessionIDs, err := e.store.(SessionLister).ListSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for start := 0; start < len(sessionIDs); start += e.pageSize {
+		if start > 0 {
+			if err := pause(); err != nil {
+				return err
+			}
+		}
+		if err := fn(sessionIDs[start:min(start+e.pageSize, len(sessionIDs))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadSnapshot reads a snapshot archive from r, calling fn for each record in
+// order. It fails if the archive isn't a snapshot, was written by a newer
+// version, or holds a malformed record. An error from fn stops the read and
+// is returned as-is.
+func ReadSnapshot(r io.Reader, fn func(record SnapshotRecord) error) (SnapshotHeader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return SnapshotHeader{}, fmt.Errorf("not a session snapshot: %w", err)
+	}
+	defer gz.Close()
+
+	dec := json.NewDecoder(gz)
+	var header SnapshotHeader
+	if err := dec.Decode(&header); err != nil || header.Format != SnapshotFormat {
+		return SnapshotHeader{}, fmt.Errorf("not a session snapshot: missing %s header", SnapshotFormat)
+	}
+	if header.Version < 1 || header.Version > SnapshotVersion {
+		return header, fmt.Errorf("unsupported session snapshot version %d, expected at most %d", header.Version, SnapshotVersion)
+	}
+
+	for n := 1; ; n++ {
+		var record SnapshotRecord
+		if err := dec.Decode(&record); err == io.EOF {
+			return header, nil
+		} else if err != nil {
+			return header, fmt.Errorf("malformed session snapshot at record %d: %w", n, err)
+		}
+		if err := ValidateSessionID(record.SessionID); err != nil {
+			return header, fmt.Errorf("malformed session snapshot at record %d: %w", n, err)
+		}
+		if len(record.Data) == 0 || string(record.Data) == "null" {
+			return header, fmt.Errorf("malformed session snapshot at record %d: session %s has no data", n, record.SessionID)
+		}
+		if err := fn(record); err != nil {
+			return header, err
+		}
+	}
+}
+
+// RestoreResult counts what RestoreSnapshot did with each record
+type RestoreResult struct {
+	Restored int // Records written to the store
+	Skipped  int // Records left out because the session already existed
+	Expired  int // Records left out because the session had expired
+}
+
+// RestoreSnapshot writes every session in a snapshot archive to store, which
+// must implement SessionImporter. Sessions are restored with the time they
+// had left when the snapshot was taken, or the store's TTL if the snapshot
+// didn't record one; sessions that have expired since are left out. With
+// skipExisting, sessions the store already holds are left as they are, which
+// needs a store that also implements SessionInspector. On error the result
+// counts the records handled before it.
+func RestoreSnapshot(ctx context.Context, store SessionStore, r io.Reader, skipExisting bool) (RestoreResult, error) {
+	var result RestoreResult
+	importer, ok := store.(SessionImporter)
+	if !ok {
+		return result, fmt.Errorf("the session store doesn't support importing sessions")
+	}
+	inspector, canInspect := store.(SessionInspector)
+	if skipExisting && !canInspect {
+		return result, fmt.Errorf("skipping existing sessions needs a store that can read stored sessions")
+	}
+
+	_, err := ReadSnapshot(r, func(record SnapshotRecord) error {
+		var ttl time.Duration
+		if !record.ExpiresAt.IsZero() {
+			ttl = time.Until(record.ExpiresAt)
+			if ttl <= 0 {
+				result.Expired++
+				return nil
+			}
+		}
+
+		if skipExisting {
+			_, err := inspector.Inspect(ctx, record.SessionID)
+			if err == nil {
+				result.Skipped++
+				return nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to check for session %s: %w", record.SessionID, err)
+			}
+		}
+
+		if err := importer.Import(ctx, record.SessionID, record.Data, ttl); err != nil {
+			return fmt.Errorf("failed to restore session %s: %w", record.SessionID, err)
+		}
+		result.Restored++
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// seedSessions writes a session for each ID to store
+func seedSessions(t *testing.T, store SessionStore, sessionIDs ...string) {
+	t.Helper()
+	for _, sessionID := range sessionIDs {
+		if err := store.Set(sessionID, newTestTransport(sessionID)); err != nil {
+			t.Fatalf("Set(%s) error = %v", sessionID, err)
+		}
+	}
+}
+
+// exportSnapshot exports store to a temporary directory and returns the
+// archive's path
+func exportSnapshot(t *testing.T, store SessionStore) string {
+	t.Helper()
+	dir := t.TempDir()
+	exporter, err := NewSnapshotExporter(SnapshotExporterConfig{
+		Store:     store,
+		Sink:      DirSnapshotSink{Dir: dir},
+		PageSize:  2,
+		PageDelay: -1,
+	})
+	if err != nil {
+		t.Fatalf("NewSnapshotExporter() error = %v", err)
+	}
+	name, _, err := exporter.Export(t.Context())
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	return filepath.Join(dir, name)
+}
+
+// readSnapshotFile returns the header and records of the archive at path
+func readSnapshotFile(t *testing.T, path string) (SnapshotHeader, []SnapshotRecord) {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var records []SnapshotRecord
+	header, err := ReadSnapshot(file, func(record SnapshotRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSnapshot() error = %v", err)
+	}
+	return header, records
+}
+
+var snapshotSessionIDs = []string{"session-1", "session-2", "session-3", "session-4", "session-5"}
+
+func TestSnapshotExport(t *testing.T) {
+	store, _ := newTestRedisStore(t, RedisSessionStoreConfig{})
+	seedSessions(t, store, snapshotSessionIDs...)
+
+	dir := t.TempDir()
+	exporter, err := NewSnapshotExporter(SnapshotExporterConfig{
+		Store:     store,
+		Sink:      DirSnapshotSink{Dir: dir},
+		PageSize:  2,
+		PageDelay: -1,
+	})
+	if err != nil {
+		t.Fatalf("NewSnapshotExporter() error = %v", err)
+	}
+	name, count, err := exporter.Export(t.Context())
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if count != len(snapshotSessionIDs) {
+		t.Errorf("Export() count = %d, want %d", count, len(snapshotSessionIDs))
+	}
+	if !strings.HasPrefix(name, "sessions-") || !strings.HasSuffix(name, ".jsonl.gz") {
+		t.Errorf("Export() name = %q", name)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("snapshot directory holds %d files, want just the archive", len(entries))
+	}
+
+	header, records := readSnapshotFile(t, filepath.Join(dir, name))
+	if header.Format != SnapshotFormat || header.Version != SnapshotVersion || header.CreatedAt.IsZero() {
+		t.Errorf("header = %+v", header)
+	}
+	var sessionIDs []string
+	for _, record := range records {
+		sessionIDs = append(sessionIDs, record.SessionID)
+		stored, err := store.Inspect(t.Context(), record.SessionID)
+		if err != nil {
+			t.Fatalf("Inspect(%s) error = %v", record.SessionID, err)
+		}
+		if string(record.Data) != string(stored) {
+			t.Errorf("record for %s = %s, want the stored %s", record.SessionID, record.Data, stored)
+		}
+		if until := time.Until(record.ExpiresAt); until <= 59*time.Minute || until > time.Hour {
+			t.Errorf("record for %s expires in %s, want about 1h", record.SessionID, until)
+		}
+	}
+	slices.Sort(sessionIDs)
+	if !slices.Equal(sessionIDs, snapshotSessionIDs) {
+		t.Errorf("archive holds %v, want %v", sessionIDs, snapshotSessionIDs)
+	}
+}
+
+// failingSnapshotSink reads part of each archive and then fails
+type failingSnapshotSink struct{}
+
+func (failingSnapshotSink) WriteSnapshot(ctx context.Context, name string, r io.Reader) error {
+	r.Read(make([]byte, 16))
+	return errors.New("bucket unavailable")
+}
+
+func TestSnapshotExportSinkFailure(t *testing.T) {
+	store, _ := newTestRedisStore(t, RedisSessionStoreConfig{})
+	seedSessions(t, store, snapshotSessionIDs...)
+
+	exporter, err := NewSnapshotExporter(SnapshotExporterConfig{Store: store, Sink: failingSnapshotSink{}, PageDelay: -1})
+	if err != nil {
+		t.Fatalf("NewSnapshotExporter() error = %v", err)
+	}
+	if _, _, err := exporter.Export(t.Context()); err == nil {
+		t.Error("Export() to a failing sink succeeded")
+	}
+}
+
+func TestNewSnapshotExporterRequiresSink(t *testing.T) {
+	store, _ := newTestRedisStore(t, RedisSessionStoreConfig{})
+	if _, err := NewSnapshotExporter(SnapshotExporterConfig{Store: store}); err == nil {
+		t.Error("NewSnapshotExporter() without a sink succeeded")
+	}
+}
+
+func TestSnapshotRestoreParity(t *testing.T) {
+	source, _ := newTestRedisStore(t, RedisSessionStoreConfig{})
+	seedSessions(t, source, snapshotSessionIDs...)
+	path := exportSnapshot(t, source)
+
+	dest, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	result, err := RestoreSnapshot(t.Context(), dest, file, false)
+	if err != nil {
+		t.Fatalf("RestoreSnapshot() error = %v", err)
+	}
+	if result != (RestoreResult{Restored: len(snapshotSessionIDs)}) {
+		t.Errorf("RestoreSnapshot() = %+v, want all %d restored", result, len(snapshotSessionIDs))
+	}
+
+	restored, err := dest.ListSessions(t.Context())
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	slices.Sort(restored)
+	if !slices.Equal(restored, snapshotSessionIDs) {
+		t.Errorf("restored sessions = %v, want %v", restored, snapshotSessionIDs)
+	}
+	for _, sessionID := range snapshotSessionIDs {
+		want, err := source.Inspect(t.Context(), sessionID)
+		if err != nil {
+			t.Fatalf("source Inspect(%s) error = %v", sessionID, err)
+		}
+		got, err := dest.Inspect(t.Context(), sessionID)
+		if err != nil {
+			t.Fatalf("restored Inspect(%s) error = %v", sessionID, err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("restored record for %s = %s, want %s", sessionID, got, want)
+		}
+		// Restored with the time the session had left, not a fresh TTL
+		if ttl := mr.TTL("mcp:session:" + sessionID); ttl <= 59*time.Minute || ttl > time.Hour {
+			t.Errorf("restored TTL for %s = %s, want about 1h", sessionID, ttl)
+		}
+
+		// Restored sessions load like any other
+		transport, err := dest.Get(t.Context(), sessionID)
+		if err != nil || transport == nil || transport.SessionID() != sessionID {
+			t.Errorf("Get(%s) after restore = %v, %v", sessionID, transport, err)
+		}
+	}
+}
+
+func TestSnapshotRestoreSkipExisting(t *testing.T) {
+	source, _ := newTestRedisStore(t, RedisSessionStoreConfig{})
+	seedSessions(t, source, snapshotSessionIDs...)
+	path := exportSnapshot(t, source)
+
+	dest, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	mr.Set("mcp:session:session-1", `{"session_id":"session-1","updated_at":"2030-01-01T00:00:00Z"}`)
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	result, err := RestoreSnapshot(t.Context(), dest, file, true)
+	if err != nil {
+		t.Fatalf("RestoreSnapshot() error = %v", err)
+	}
+	if result != (RestoreResult{Restored: len(snapshotSessionIDs) - 1, Skipped: 1}) {
+		t.Errorf("RestoreSnapshot() = %+v, want session-1 skipped and the rest restored", result)
+	}
+	if value, _ := mr.Get("mcp:session:session-1"); !strings.Contains(value, "2030-01-01") {
+		t.Errorf("existing session was overwritten with %s", value)
+	}
+}
+
+// writeSnapshot returns an archive holding header and records
+func writeSnapshot(t *testing.T, header SnapshotHeader, records ...SnapshotRecord) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	if err := enc.Encode(header); err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestSnapshotRestoreTTL(t *testing.T) {
+	dest, mr := newTestRedisStore(t, RedisSessionStoreConfig{TTL: 10 * time.Minute})
+	header := SnapshotHeader{Format: SnapshotFormat, Version: SnapshotVersion, CreatedAt: time.Now()}
+	archive := writeSnapshot(t, header,
+		SnapshotRecord{SessionID: "expired", Data: []byte(`{"session_id":"expired"}`), ExpiresAt: time.Now().Add(-time.Minute)},
+		SnapshotRecord{SessionID: "remaining", Data: []byte(`{"session_id":"remaining"}`), ExpiresAt: time.Now().Add(time.Hour)},
+		SnapshotRecord{SessionID: "unknown", Data: []byte(`{"session_id":"unknown"}`)},
+	)
+
+	result, err := RestoreSnapshot(t.Context(), dest, archive, false)
+	if err != nil {
+		t.Fatalf("RestoreSnapshot() error = %v", err)
+	}
+	if result != (RestoreResult{Restored: 2, Expired: 1}) {
+		t.Errorf("RestoreSnapshot() = %+v, want 2 restored and 1 expired", result)
+	}
+	if mr.Exists("mcp:session:expired") {
+		t.Error("expired session was restored")
+	}
+	if ttl := mr.TTL("mcp:session:remaining"); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("TTL of a session with a recorded expiry = %s, want about 1h", ttl)
+	}
+	if ttl := mr.TTL("mcp:session:unknown"); ttl != 10*time.Minute {
+		t.Errorf("TTL of a session without a recorded expiry = %s, want the store's 10m", ttl)
+	}
+}
+
+func TestReadSnapshotRejectsInvalidArchives(t *testing.T) {
+	header := SnapshotHeader{Format: SnapshotFormat, Version: SnapshotVersion}
+	tests := []struct {
+		name    string
+		archive *bytes.Buffer
+	}{
+		{name: "not gzip", archive: bytes.NewBufferString(`{"format":"mcp-session-snapshot","version":1}`)},
+		{name: "wrong format", archive: writeSnapshot(t, SnapshotHeader{Format: "something-else", Version: 1})},
+		{name: "newer version", archive: writeSnapshot(t, SnapshotHeader{Format: SnapshotFormat, Version: SnapshotVersion + 1})},
+		{name: "invalid session ID", archive: writeSnapshot(t, header, SnapshotRecord{SessionID: "a:b", Data: []byte(`{}`)})},
+		{name: "no data", archive: writeSnapshot(t, header, SnapshotRecord{SessionID: "session-1"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadSnapshot(tt.archive, func(SnapshotRecord) error { return nil })
+			if err == nil {
+				t.Error("ReadSnapshot() succeeded, want an error")
+			}
+		})
+	}
+}
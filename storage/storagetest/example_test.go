@@ -0,0 +1,109 @@
+package storagetest_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+	"github.com/omgitsads/mcp-go-session-example/storage/storagetest"
+)
+
+// endSessionHandler is the kind of handler under test: it deletes the session
+// named by the Mcp-Session-Id header from the store it was given
+func endSessionHandler(store storage.SessionStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.Header.Get("Mcp-Session-Id")
+		session, err := store.Get(r.Context(), sessionID)
+		if err != nil {
+			http.Error(w, err.Error(), storage.HTTPStatus(err))
+			return
+		}
+		if session == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if err := store.Delete(sessionID); err != nil {
+			http.Error(w, err.Error(), storage.HTTPStatus(err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// startSession is the kind of tool under test: it writes a new session to
+// the store
+func startSession(store storage.SessionStore, sessionID string) error {
+	return store.Set(sessionID, mcp.NewStreamableServerTransport(sessionID, nil))
+}
+
+// Inject the mock into a handler, then assert on the calls it made and the
+// sessions left in the store
+func Example() {
+	store := storagetest.NewInMemoryForTest()
+	store.Seed("session-1")
+
+	req := httptest.NewRequest(http.MethodDelete, "/mcp", nil)
+	req.Header.Set("Mcp-Session-Id", "session-1")
+	rec := httptest.NewRecorder()
+	endSessionHandler(store).ServeHTTP(rec, req)
+
+	fmt.Println("status:", rec.Code)
+	fmt.Println("stored:", store.Has("session-1"))
+	for _, call := range store.Calls() {
+		fmt.Println(call.Method, call.SessionID)
+	}
+	// Output:
+	// status: 204
+	// stored: false
+	// Get session-1
+	// Delete session-1
+}
+
+func ExampleNewInMemoryForTest() {
+	store := storagetest.NewInMemoryForTest()
+
+	if err := startSession(store, "session-1"); err != nil {
+		fmt.Println("error:", err)
+	}
+
+	fmt.Println("stored:", store.Has("session-1"))
+	fmt.Println("calls:", store.Calls())
+	// Output:
+	// stored: true
+	// calls: [{Set session-1}]
+}
+
+func ExampleMockSessionStore_SetError() {
+	store := storagetest.NewInMemoryForTest()
+	store.Seed("session-1")
+	store.SetError(storagetest.MethodDelete, storage.ErrUnavailable)
+
+	req := httptest.NewRequest(http.MethodDelete, "/mcp", nil)
+	req.Header.Set("Mcp-Session-Id", "session-1")
+	rec := httptest.NewRecorder()
+	endSessionHandler(store).ServeHTTP(rec, req)
+
+	fmt.Println("status:", rec.Code)
+	fmt.Println("stored:", store.Has("session-1"))
+	// Output:
+	// status: 503
+	// stored: true
+}
+
+func ExampleMockSessionStore_Seed() {
+	store := storagetest.NewInMemoryForTest()
+	seeded := store.Seed("session-1")
+
+	req := httptest.NewRequest(http.MethodDelete, "/mcp", nil)
+	req.Header.Set("Mcp-Session-Id", "session-2")
+	rec := httptest.NewRecorder()
+	endSessionHandler(store).ServeHTTP(rec, req)
+
+	fmt.Println("status:", rec.Code)
+	fmt.Println("seeded:", seeded.SessionID(), store.Has("session-1"))
+	// Output:
+	// status: 404
+	// seeded: session-1 true
+}
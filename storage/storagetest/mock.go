@@ -0,0 +1,191 @@
+// Package storagetest provides an in-memory session store for testing code that
+// depends on a storage.SessionStore, without running Redis or Memcached.
+package storagetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/omgitsads/mcp-go-session-example/storage"
+)
+
+// Method names recorded in Call and accepted by SetError
+const (
+	MethodGet    = "Get"
+	MethodSet    = "Set"
+	MethodDelete = "Delete"
+	MethodHealth = "Health"
+	MethodClose  = "Close"
+)
+
+// Call records a single operation made against a MockSessionStore
+type Call struct {
+	Method    string
+	SessionID string // Empty for Health and Close
+}
+
+// MockSessionStore is an in-memory storage.SessionStore that records every call
+// and can be made to fail on demand
+type MockSessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*mcp.StreamableServerTransport
+	errors   map[string]error
+	calls    []Call
+	hooks    []func(sessionID string)
+}
+
+var _ storage.SessionStore = (*MockSessionStore)(nil)
+
+// NewInMemoryForTest creates an empty MockSessionStore that succeeds on every call
+func NewInMemoryForTest() *MockSessionStore {
+	return &MockSessionStore{
+		sessions: make(map[string]*mcp.StreamableServerTransport),
+		errors:   make(map[string]error),
+	}
+}
+
+// SetError makes every subsequent call to method return err. Passing a nil
+// err restores normal behaviour.
+func (m *MockSessionStore) SetError(method string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err == nil {
+		delete(m.errors, method)
+		return
+	}
+	m.errors[method] = err
+}
+
+// Seed adds a session to the store without recording a call, and returns the
+// transport that Get will return for it
+func (m *MockSessionStore) Seed(sessionID string) *mcp.StreamableServerTransport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	transport := mcp.NewStreamableServerTransport(sessionID, nil)
+	m.sessions[sessionID] = transport
+	return transport
+}
+
+// Calls returns a copy of the calls made so far, in order
+func (m *MockSessionStore) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// Has reports whether a session is currently stored
+func (m *MockSessionStore) Has(sessionID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.sessions[sessionID]
+	return ok
+}
+
+// AssertHasSession fails the test if sessionID isn't stored
+func (m *MockSessionStore) AssertHasSession(t testing.TB, sessionID string) {
+	t.Helper()
+	if !m.Has(sessionID) {
+		t.Errorf("expected session %q to be stored", sessionID)
+	}
+}
+
+// AssertNoSession fails the test if sessionID is stored
+func (m *MockSessionStore) AssertNoSession(t testing.TB, sessionID string) {
+	t.Helper()
+	if m.Has(sessionID) {
+		t.Errorf("expected session %q not to be stored", sessionID)
+	}
+}
+
+// AssertCalled fails the test if method was never called for sessionID
+func (m *MockSessionStore) AssertCalled(t testing.TB, method, sessionID string) {
+	t.Helper()
+	for _, call := range m.Calls() {
+		if call.Method == method && call.SessionID == sessionID {
+			return
+		}
+	}
+	t.Errorf("expected %s to be called for session %q", method, sessionID)
+}
+
+// record appends a call and returns the configured error for method, if any.
+// The caller must hold m.mu.
+func (m *MockSessionStore) record(method, sessionID string) error {
+	m.calls = append(m.calls, Call{Method: method, SessionID: sessionID})
+	return m.errors[method]
+}
+
+// Get returns a stored session, or nil if it doesn't exist
+func (m *MockSessionStore) Get(ctx context.Context, sessionID string) (*mcp.StreamableServerTransport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.record(MethodGet, sessionID); err != nil {
+		return nil, err
+	}
+	return m.sessions[sessionID], nil
+}
+
+// Set stores a session
+func (m *MockSessionStore) Set(sessionID string, session *mcp.StreamableServerTransport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.record(MethodSet, sessionID); err != nil {
+		return err
+	}
+	m.sessions[sessionID] = session
+	return nil
+}
+
+// Delete removes a session and runs the OnSessionClosed hooks
+func (m *MockSessionStore) Delete(sessionID string) error {
+	m.mu.Lock()
+	if err := m.record(MethodDelete, sessionID); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	delete(m.sessions, sessionID)
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(sessionID)
+	}
+	return nil
+}
+
+// OnSessionClosed registers a function that's called after a successful Delete
+func (m *MockSessionStore) OnSessionClosed(hook func(sessionID string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks[:len(m.hooks):len(m.hooks)], hook)
+}
+
+// Range iterates over all stored sessions
+func (m *MockSessionStore) Range(f func(sessionID string, session *mcp.StreamableServerTransport)) {
+	m.mu.Lock()
+	sessions := make(map[string]*mcp.StreamableServerTransport, len(m.sessions))
+	for id, session := range m.sessions {
+		sessions[id] = session
+	}
+	m.mu.Unlock()
+
+	for sessionID, session := range sessions {
+		f(sessionID, session)
+	}
+}
+
+// Health returns the configured Health error, if any
+func (m *MockSessionStore) Health(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.record(MethodHealth, "")
+}
+
+// Close returns the configured Close error, if any
+func (m *MockSessionStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.record(MethodClose, "")
+}
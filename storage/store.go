@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/omgitsads/mcp-go-session-example/health"
+)
+
+// SessionStore is implemented by every session storage backend in this package.
+// It covers the methods the Streamable HTTP handler needs, plus lifecycle helpers
+// used by the server command.
+type SessionStore interface {
+	Get(ctx context.Context, sessionID string) (*mcp.StreamableServerTransport, error)
+	Set(sessionID string, session *mcp.StreamableServerTransport) error
+	Delete(sessionID string) error
+	Range(f func(sessionID string, session *mcp.StreamableServerTransport))
+	OnSessionClosed(hook func(sessionID string))
+	Health(ctx context.Context) error
+	Close() error
+}
+
+// CacheStatsProvider is implemented by stores that keep a local cache of
+// active sessions, such as those built on BaseSessionStore
+type CacheStatsProvider interface {
+	CacheStats() (hits, misses uint64)
+	CacheHitRatio() float64
+}
+
+// ConnectionStateProvider is implemented by stores that monitor their backend
+// connection in the background
+type ConnectionStateProvider interface {
+	IsConnected() bool
+}
+
+// HealthCheckRegistrar is implemented by stores that register their own
+// checks in the server's health registry, in place of a single check
+// calling Health
+type HealthCheckRegistrar interface {
+	RegisterHealthChecks(registry *health.Registry)
+}
+
+// ReadOnlySwitch is implemented by stores that can reject writes while still
+// serving reads, e.g. during backend maintenance. Every store built on
+// BaseSessionStore implements it.
+type ReadOnlySwitch interface {
+	SetReadOnly(readOnly bool)
+	ReadOnly() bool
+}
+
+// SelfTester is implemented by stores that can check a synthetic session
+// survives the round trip through encoding and the backend. Every store
+// built on BaseSessionStore implements it.
+type SelfTester interface {
+	SelfTest(ctx context.Context) error
+}
+
+// SessionCreator is implemented by stores that can write a new session only
+// if no other instance has created it already. Every store built on
+// BaseSessionStore implements it, but backends without an atomic create
+// return an error.
+type SessionCreator interface {
+	StoreIfAbsent(ctx context.Context, sessionID string, session *mcp.StreamableServerTransport) (created bool, err error)
+}
+
+// SessionDrainer is implemented by stores that can finish their in-flight
+// work before being closed at shutdown. Every store built on
+// BaseSessionStore implements it.
+type SessionDrainer interface {
+	Drain(ctx context.Context) error
+}
+
+// LatencyProvider is implemented by stores that measure their backend's
+// latency in the background
+type LatencyProvider interface {
+	Latency() time.Duration
+}
+
+// PoolStatsProvider is implemented by stores that keep a pool of backend
+// connections
+type PoolStatsProvider interface {
+	PoolStats() PoolStats
+}
+
+// PoolStats describes a store's backend connection pool
+type PoolStats struct {
+	Hits       uint32 // Times a free connection was found in the pool
+	Misses     uint32 // Times a new connection had to be opened
+	Timeouts   uint32 // Times waiting for a connection timed out
+	TotalConns uint32 // Connections in the pool
+	IdleConns  uint32 // Idle connections in the pool
+	StaleConns uint32 // Connections removed from the pool as stale
+}
+
+// SessionLister is implemented by stores that can enumerate the sessions held
+// in the backend, not just the ones active on this instance
+type SessionLister interface {
+	ListSessions(ctx context.Context) ([]string, error)
+}
+
+// DefaultPageSize is the page size used by SessionPager implementations when
+// none is given
+const DefaultPageSize = 100
+
+// SessionPager is implemented by stores that can enumerate their sessions a
+// page at a time, so very large stores can be listed without holding every ID
+// in memory. Pass an empty cursor for the first page; an empty next cursor
+// means there are no more pages. Cursors are opaque and only valid for the
+// store that returned them. A count of 0 or less uses DefaultPageSize, and
+// stores may return more or fewer IDs than asked for.
+type SessionPager interface {
+	ListSessionsPage(ctx context.Context, cursor string, count int) (sessionIDs []string, next string, err error)
+}
+
+// CountSessions returns the number of sessions held in the backend
+func CountSessions(ctx context.Context, store SessionLister) (int, error) {
+	sessionIDs, err := store.ListSessions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return len(sessionIDs), nil
+}
+
+// InitSessionMetrics seeds the active sessions gauge from the backend, so it
+// reflects sessions created before a restart. Stores that can't list their
+// sessions start from zero.
+func InitSessionMetrics(ctx context.Context, store SessionStore) error {
+	lister, ok := store.(SessionLister)
+	if !ok {
+		return nil
+	}
+	count, err := CountSessions(ctx, lister)
+	if err != nil {
+		return fmt.Errorf("failed to count sessions: %w", err)
+	}
+	sessionsActive.Set(float64(count))
+	return nil
+}
+
+// SessionLocker is implemented by stores that can serialize work on a single
+// session, e.g. a tool handler's read-modify-write of stored state. fn runs
+// while the lock is held and gets a context that's done when ctx is.
+type SessionLocker interface {
+	WithSessionLock(ctx context.Context, sessionID string, fn func(ctx context.Context) error) error
+}
+
+// DistributedLocker is implemented by stores that can hold locks shared by
+// every instance using the same backend, so tool handlers can coordinate work
+// on a session or any other resource across instances. The returned function
+// releases the lock. Locks expire after ttl if they aren't released.
+type DistributedLocker interface {
+	AcquireLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), err error)
+}
+
+// CacheWarmer is implemented by stores that can preload sessions into their
+// local cache
+type CacheWarmer interface {
+	WarmCache(ctx context.Context, limit int) (int, error)
+}
+
+// SessionToucher is implemented by stores that can reset a session's TTL
+// without rewriting it
+type SessionToucher interface {
+	Touch(ctx context.Context, sessionID string) error
+}
+
+// SessionInspector is implemented by stores that can read a session's stored
+// record without side effects, for debugging tools
+type SessionInspector interface {
+	Inspect(ctx context.Context, sessionID string) ([]byte, error)
+}
+
+// RawSessionInspector is implemented by stores that can read a session's
+// stored bytes exactly as the backend holds them, e.g. still compressed
+type RawSessionInspector interface {
+	InspectRaw(ctx context.Context, sessionID string) ([]byte, error)
+}
+
+// SessionExpiryReader is implemented by stores that can report how long a
+// session has left before it expires, without refreshing it. A TTL of 0
+// means the session never expires.
+type SessionExpiryReader interface {
+	SessionTTL(ctx context.Context, sessionID string) (time.Duration, error)
+}
+
+// SessionImporter is implemented by stores that can write a stored session
+// record as-is, e.g. to restore a backup. A TTL of 0 uses the store's TTL.
+type SessionImporter interface {
+	Import(ctx context.Context, sessionID string, data []byte, ttl time.Duration) error
+}
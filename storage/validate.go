@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxSessionIDLength is the longest session ID accepted by the stores
+const MaxSessionIDLength = 128
+
+// ErrInvalidSessionID is returned when a session ID can't safely be used as
+// part of a storage key
+var ErrInvalidSessionID = errors.New("invalid session ID")
+
+// ValidateSessionID checks that a session ID only contains ASCII letters,
+// digits, '-' and '_', and is at most MaxSessionIDLength long. This covers the
+// SDK's generated IDs as well as UUIDs, while keeping glob characters and key
+// separators such as '*', '?', '[' and ':' out of storage keys.
+func ValidateSessionID(sessionID string) error {
+	if sessionID == "" {
+		return fmt.Errorf("%w: empty", ErrInvalidSessionID)
+	}
+	if len(sessionID) > MaxSessionIDLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidSessionID, MaxSessionIDLength)
+	}
+	for i := 0; i < len(sessionID); i++ {
+		c := sessionID[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return fmt.Errorf("%w: unexpected character %q at position %d", ErrInvalidSessionID, c, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateSessionID(t *testing.T) {
+	tests := []struct {
+		name      string
+		sessionID string
+		valid     bool
+	}{
+		{name: "generated", sessionID: "ABCDEF1234567890", valid: true},
+		{name: "uuid", sessionID: "9b2f1c4e-6d7a-4f3b-8e21-0c5d9a7b3e10", valid: true},
+		{name: "underscore", sessionID: "session_1", valid: true},
+		{name: "max length", sessionID: strings.Repeat("a", MaxSessionIDLength), valid: true},
+		{name: "empty", sessionID: ""},
+		{name: "too long", sessionID: strings.Repeat("a", MaxSessionIDLength+1)},
+		{name: "glob star", sessionID: "*"},
+		{name: "glob question mark", sessionID: "session-?"},
+		{name: "glob class", sessionID: "session-[0-9]"},
+		{name: "key separator", sessionID: "v1:session-1"},
+		{name: "path traversal", sessionID: "../session-1"},
+		{name: "space", sessionID: "session 1"},
+		{name: "newline", sessionID: "session-1\n"},
+		{name: "non-ASCII", sessionID: "sessión"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSessionID(tt.sessionID)
+			if tt.valid && err != nil {
+				t.Errorf("ValidateSessionID(%q) error = %v, want nil", tt.sessionID, err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidSessionID) {
+				t.Errorf("ValidateSessionID(%q) error = %v, want ErrInvalidSessionID", tt.sessionID, err)
+			}
+		})
+	}
+}
+
+func TestStoreRejectsInvalidSessionIDs(t *testing.T) {
+	backend := newMemoryBackend()
+	store := newTestBaseStore(t, backend, StoreOptions{})
+
+	for _, sessionID := range []string{"", "*", "a:b", strings.Repeat("a", MaxSessionIDLength+1)} {
+		if _, err := store.Get(t.Context(), sessionID); !errors.Is(err, ErrInvalidSessionID) {
+			t.Errorf("Get(%q) error = %v, want ErrInvalidSessionID", sessionID, err)
+		}
+		if err := store.Set(sessionID, newTestTransport(sessionID)); !errors.Is(err, ErrInvalidSessionID) {
+			t.Errorf("Set(%q) error = %v, want ErrInvalidSessionID", sessionID, err)
+		}
+		if err := store.Delete(sessionID); !errors.Is(err, ErrInvalidSessionID) {
+			t.Errorf("Delete(%q) error = %v, want ErrInvalidSessionID", sessionID, err)
+		}
+	}
+
+	if keys := backend.keys(); len(keys) != 0 {
+		t.Errorf("backend keys = %v, want none", keys)
+	}
+	if gets := backend.getCount(); gets != 0 {
+		t.Errorf("backend reads = %d, want 0", gets)
+	}
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestBaseSessionStoreWarmCache(t *testing.T) {
+	writer, mr := newTestRedisStore(t, RedisSessionStoreConfig{})
+	for _, sessionID := range []string{"oldest", "older", "newer", "newest"} {
+		if err := writer.Set(sessionID, newTestTransport(sessionID)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond) // Distinct write times
+	}
+	mr.Set("mcp:session:corrupt", "not json")
+
+	logger := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(logger) })
+
+	store := newTestRedisStoreAt(t, mr, RedisSessionStoreConfig{})
+	loaded, err := store.WarmCache(t.Context(), 2)
+	if err != nil {
+		t.Fatalf("WarmCache() error = %v", err)
+	}
+	if loaded != 2 {
+		t.Errorf("WarmCache() loaded %d sessions, want 2", loaded)
+	}
+	for sessionID, want := range map[string]bool{"newest": true, "newer": true, "older": false, "oldest": false, "corrupt": false} {
+		store.activeSessionMu.RLock()
+		_, cached := store.activeSessions[sessionID]
+		store.activeSessionMu.RUnlock()
+		if cached != want {
+			t.Errorf("session %s cached = %v, want %v", sessionID, cached, want)
+		}
+	}
+
+	// Sessions already active aren't loaded again or counted
+	loaded, err = store.WarmCache(t.Context(), 3)
+	if err != nil {
+		t.Fatalf("WarmCache() error = %v", err)
+	}
+	if loaded != 2 {
+		t.Errorf("second WarmCache() loaded %d sessions, want the 2 that weren't cached", loaded)
+	}
+}
+
+func TestBaseSessionStoreWarmCacheNeedsLister(t *testing.T) {
+	store := newTestBaseStore(t, newMemoryBackend(), StoreOptions{})
+	if _, err := store.WarmCache(t.Context(), 10); err == nil {
+		t.Error("WarmCache() with a backend that can't list sessions succeeded")
+	}
+}
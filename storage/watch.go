@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// SessionEventType is the kind of change a SessionEvent reports
+type SessionEventType string
+
+// Session event types. Backends report a write without knowing whether the
+// session existed before, so creates and updates are both SessionWritten.
+const (
+	SessionWritten SessionEventType = "write"
+	SessionTouched SessionEventType = "touch" // TTL reset without a write
+	SessionDeleted SessionEventType = "delete"
+	SessionExpired SessionEventType = "expire"
+)
+
+// SessionEvent is a change to a stored session, as reported by the backend
+type SessionEvent struct {
+	Time      time.Time // When the event was received
+	Type      SessionEventType
+	SessionID string
+}
+
+// SessionWatcher is implemented by stores that can stream changes made to
+// stored sessions by any instance. WatchSessions calls fn for each event, in
+// order, until ctx is done, and then returns nil.
+type SessionWatcher interface {
+	WatchSessions(ctx context.Context, fn func(event SessionEvent)) error
+}
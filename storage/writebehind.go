@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// writeBehindBufferSize is how many events are queued for a write-behind sink
+// before new ones are dropped
+const writeBehindBufferSize = 1024
+
+// WriteEvent describes a session write that reached the backend
+type WriteEvent struct {
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"session_id"`
+	Bytes     int       `json:"bytes"` // Size of the stored record
+}
+
+// WriteBehindSink receives an event for every successful session write, e.g.
+// to mirror writes to an append-only log for analytics. Events are delivered
+// in order from a single background goroutine, so a sink may block, but
+// events that arrive while the queue is full are dropped. Errors are logged
+// and never affect the write itself.
+type WriteBehindSink interface {
+	WriteBehind(event WriteEvent) error
+}
+
+// NopWriteBehindSink discards every event
+type NopWriteBehindSink struct{}
+
+// WriteBehind implements WriteBehindSink
+func (NopWriteBehindSink) WriteBehind(WriteEvent) error {
+	return nil
+}
+
+// FileWriteBehindSink appends events to a file as JSON lines
+type FileWriteBehindSink struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// NewFileWriteBehindSink opens path for appending, creating it if needed
+func NewFileWriteBehindSink(path string) (*FileWriteBehindSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-behind file: %w", err)
+	}
+	return &FileWriteBehindSink{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+// WriteBehind implements WriteBehindSink
+func (s *FileWriteBehindSink) WriteBehind(event WriteEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.encoder.Encode(event)
+}
+
+// Close closes the file. The store delivering events to the sink must be
+// closed first, so queued events are written.
+func (s *FileWriteBehindSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// writeBehind delivers write events to a sink in the background
+type writeBehind struct {
+	sink   WriteBehindSink
+	events chan WriteEvent
+	mu     sync.RWMutex
+	closed bool // Set once events is closed
+	done   chan struct{}
+
+	abandoned atomic.Bool // Set when a drain timed out with events undelivered
+}
+
+// newWriteBehind starts delivering events to sink
+func newWriteBehind(sink WriteBehindSink) *writeBehind {
+	w := &writeBehind{
+		sink:   sink,
+		events: make(chan WriteEvent, writeBehindBufferSize),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// enqueue queues an event without blocking, dropping it if the queue is full
+// or the store has been closed
+func (w *writeBehind) enqueue(event WriteEvent) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.events <- event:
+	default:
+		writeBehindDroppedTotal.Inc()
+	}
+}
+
+// run delivers queued events until close is called
+func (w *writeBehind) run() {
+	defer close(w.done)
+	for event := range w.events {
+		if err := w.sink.WriteBehind(event); err != nil {
+			log.Printf("Write-behind sink failed for session %s: %v", event.SessionID, err)
+		}
+	}
+}
+
+// close stops accepting events and waits for queued ones to be delivered,
+// unless a drain already gave up on them
+func (w *writeBehind) close() {
+	w.stop()
+	if !w.abandoned.Load() {
+		<-w.done
+	}
+}
+
+// drain stops accepting events and waits for queued ones to be delivered or
+// for ctx to be done. If ctx is done first, it returns how many events were
+// still queued, and close no longer waits for them.
+func (w *writeBehind) drain(ctx context.Context) (int, error) {
+	w.stop()
+	select {
+	case <-w.done:
+		return 0, nil
+	case <-ctx.Done():
+		w.abandoned.Store(true)
+		return len(w.events), ctx.Err()
+	}
+}
+
+// stop closes the queue to new events
+func (w *writeBehind) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.closed = true
+		close(w.events)
+	}
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink is a WriteBehindSink that keeps the events it receives
+type recordingSink struct {
+	mu     sync.Mutex
+	events []WriteEvent
+	err    error // Returned for every event when set
+}
+
+func (s *recordingSink) WriteBehind(event WriteEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return s.err
+}
+
+func (s *recordingSink) received() []WriteEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]WriteEvent(nil), s.events...)
+}
+
+func TestWriteBehindAfterSuccessfulSet(t *testing.T) {
+	backend := newMemoryBackend()
+	sink := &recordingSink{}
+	store := newTestBaseStore(t, backend, StoreOptions{WriteBehind: sink})
+
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	backend.setErr = ErrUnavailable
+	if err := store.Set("session-2", newTestTransport("session-2")); err == nil {
+		t.Fatal("Set() with the backend failing succeeded")
+	}
+	store.closeBase() // Delivers queued events
+
+	events := sink.received()
+	if len(events) != 1 {
+		t.Fatalf("sink received %d events, want 1 for the successful write", len(events))
+	}
+	event := events[0]
+	if event.SessionID != "session-1" {
+		t.Errorf("event session ID = %q, want session-1", event.SessionID)
+	}
+	if want := len(backend.data["mcp:session:session-1"]); event.Bytes != want {
+		t.Errorf("event bytes = %d, want the %d byte record", event.Bytes, want)
+	}
+	if event.Time.IsZero() {
+		t.Error("event time isn't set")
+	}
+}
+
+func TestWriteBehindSinkFailureDoesNotAffectSet(t *testing.T) {
+	sink := &recordingSink{err: errors.New("sink unavailable")}
+	store := newTestBaseStore(t, newMemoryBackend(), StoreOptions{WriteBehind: sink})
+
+	if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+		t.Errorf("Set() error = %v, want the sink's failure ignored", err)
+	}
+	store.closeBase()
+	if events := sink.received(); len(events) != 1 {
+		t.Errorf("sink received %d events, want 1", len(events))
+	}
+}
+
+// blockingSink blocks every delivery until release is closed
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (s *blockingSink) WriteBehind(WriteEvent) error {
+	<-s.release
+	return nil
+}
+
+func TestWriteBehindDoesNotBlockSet(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	store := newTestBaseStore(t, newMemoryBackend(), StoreOptions{WriteBehind: sink})
+	defer close(sink.release)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// More writes than the queue holds, so some events are dropped
+		for range writeBehindBufferSize + 10 {
+			if err := store.Set("session-1", newTestTransport("session-1")); err != nil {
+				t.Errorf("Set() error = %v", err)
+				return
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Set() blocked on a slow write-behind sink")
+	}
+}
+
+func TestFileWriteBehindSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "writes.jsonl")
+	sink, err := NewFileWriteBehindSink(path)
+	if err != nil {
+		t.Fatalf("NewFileWriteBehindSink() error = %v", err)
+	}
+	store := newTestBaseStore(t, newMemoryBackend(), StoreOptions{WriteBehind: sink})
+
+	for _, sessionID := range []string{"session-1", "session-2"} {
+		if err := store.Set(sessionID, newTestTransport(sessionID)); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+	store.closeBase()
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var sessionIDs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event WriteEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line %q isn't a JSON event: %v", scanner.Text(), err)
+		}
+		sessionIDs = append(sessionIDs, event.SessionID)
+	}
+	if len(sessionIDs) != 2 || sessionIDs[0] != "session-1" || sessionIDs[1] != "session-2" {
+		t.Errorf("file holds events for %v, want [session-1 session-2]", sessionIDs)
+	}
+}